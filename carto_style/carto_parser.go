@@ -0,0 +1,402 @@
+package carto
+
+import (
+	mapnik "Imposm_Optimizer/mapnik_style"
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+//symbolizerKinds maps the property prefixes of CartoCSS to the symbolizer kinds of the sld package
+var symbolizerKinds = map[string][]string{
+	"point":           {sld.PointKind},
+	"marker":          {sld.PointKind},
+	"dot":             {sld.PointKind},
+	"line":            {sld.LineKind},
+	"line-pattern":    {sld.LineKind},
+	"polygon":         {sld.PolygonKind},
+	"polygon-pattern": {sld.PolygonKind},
+	"building":        {sld.PolygonKind},
+	"text":            {sld.TextKind},
+	"shield":          {sld.PointKind, sld.TextKind},
+	"raster":          {sld.RasterKind}}
+
+//labelProperties contain the text of a label
+var labelProperties = []string{"text-name", "shield-name"}
+
+//Parser class
+type Parser struct {
+	filePath          string
+	successfullPasing bool
+	project           Project
+	tableNames        []string
+	tablePrefix       string
+	usedLayers        []string
+}
+
+//cartoRule is a ruleset of a stylesheet with all selectors of its parent rulesets
+type cartoRule struct {
+	selector     selector
+	declarations []declaration
+}
+
+//New CartoCSS parser instance for a project.mml or a single .mss file
+func New(filePath string) Parser {
+	s := Parser{filePath, false, Project{}, nil, "", make([]string, 0)}
+	return s
+}
+
+func (s *Parser) loadProject() error {
+
+	if s.successfullPasing {
+		return nil
+	}
+
+	switch filepath.Ext(s.filePath) {
+	case ".mml":
+		projectFile, err := ioutil.ReadFile(s.filePath)
+
+		if err != nil {
+			return err
+		}
+
+		//project files are YAML or JSON, which is a subset of YAML
+		err = yaml.Unmarshal(projectFile, &s.project)
+
+		if err != nil {
+			return errors.New(`"` + s.filePath + `" is no valid project file: ` + err.Error())
+		}
+
+		//the stylesheets are relative to the project file
+		for i, stylesheet := range s.project.Stylesheets {
+			if !filepath.IsAbs(stylesheet) {
+				s.project.Stylesheets[i] = filepath.Join(filepath.Dir(s.filePath), stylesheet)
+			}
+		}
+	case ".mss":
+		s.project = Project{[]string{s.filePath}, []Layer{}}
+	default:
+		return errors.New(`"` + s.filePath + `" must be a .mml or .mss file`)
+	}
+
+	s.successfullPasing = true
+
+	return nil
+}
+
+//SetTableNames restricts the extracted requirements to the layers, which match one of the tables by their id or the table
+//of their datasource query. The first table matching at least one layer is used. A project with a single layer is used
+//completely, if no layer matches. Otherwise no layer is used
+func (s *Parser) SetTableNames(tableNames []string, tablePrefix string) {
+	s.tableNames = tableNames
+	s.tablePrefix = tablePrefix
+}
+
+//LayerNames returns the ids of all layers and the tables of their datasource queries
+func (s *Parser) LayerNames() ([]string, error) {
+
+	err := s.loadProject()
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+
+	for _, layer := range s.project.Layers {
+		for _, name := range layerTableNames(layer) {
+			if !functions.StringInSlice(name, names) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names, nil
+}
+
+//UsedLayers returns the ids of the layers, which were used for the requirements of the table.
+//The list is empty, if the whole file was used
+func (s *Parser) UsedLayers() []string {
+	return s.usedLayers
+}
+
+//GetFilePath returns the path to the project or stylesheet file
+func (s *Parser) GetFilePath() string {
+	return s.filePath
+}
+
+//ExtractRequirements ( mappingColumns ) will return all required values of the CartoCSS rulesets in form of a ParsedSLD structure.
+//Every ruleset with properties is handled like a SLD rule, the zoom filters are converted into scale denominators
+func (s *Parser) ExtractRequirements(mappingColumns sld.MappingColumnNames) (sld.ParsedSLD, error) {
+
+	err := s.loadProject()
+
+	if err != nil {
+		return sld.ParsedSLD{}, err
+	}
+
+	cartoRules, variables, err := s.readStylesheets()
+
+	if err != nil {
+		return sld.ParsedSLD{}, err
+	}
+
+	requirements := sld.NewTableRequirements(mappingColumns)
+	ruleList := make([]sld.Rule, 0)
+
+	for layerIndex, layer := range s.selectedLayers() {
+		query := mapnik.ParseDatasourceSQL(layer.Datasource.Table)
+
+		for column, usages := range query.Columns {
+			for _, usage := range usages {
+				sld.AddRequiredColumn(&requirements, column, []string{}, layerZooms(layer).scaleDenominator(), usage)
+			}
+		}
+
+		for _, cartoRule := range cartoRules {
+			if !cartoRule.selector.matchesLayer(layer) {
+				continue
+			}
+
+			if rule, ok := convertRule(cartoRule, layerZooms(layer), layerIndex, variables, query, &requirements); ok {
+				ruleList = append(ruleList, rule)
+			}
+		}
+	}
+
+	scaleDenominator, useAllMappingTypes := sld.CompleteRequirements(ruleList, &requirements)
+
+	return sld.ParsedSLD{
+		FileName:           s.filePath,
+		Requirements:       requirements,
+		Scale:              scaleDenominator,
+		UseAllMappingTypes: useAllMappingTypes,
+		Version:            "CartoCSS"}, nil
+}
+
+//readStylesheets parses all stylesheets of the project and returns their rulesets with properties and the variables
+func (s *Parser) readStylesheets() ([]cartoRule, map[string]string, error) {
+
+	cartoRules := make([]cartoRule, 0)
+	variables := make(map[string]string)
+
+	for _, stylesheetPath := range s.project.Stylesheets {
+		text, err := ioutil.ReadFile(stylesheetPath)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		blocks, err := parseStylesheet(string(text), variables)
+
+		if err != nil {
+			return nil, nil, errors.New(`"` + stylesheetPath + `": ` + err.Error())
+		}
+
+		cartoRules = append(cartoRules, flattenBlocks(blocks, []selector{{}})...)
+	}
+
+	return cartoRules, variables, nil
+}
+
+//flattenBlocks combines the selectors of nested rulesets with the selectors of their parents
+func flattenBlocks(blocks []block, parents []selector) []cartoRule {
+
+	cartoRules := make([]cartoRule, 0)
+
+	for _, current := range blocks {
+		combined := make([]selector, 0)
+
+		for _, parent := range parents {
+			for _, child := range current.selectors {
+				combined = append(combined, parent.combine(child))
+			}
+		}
+
+		if len(current.declarations) > 0 {
+			for _, combinedSelector := range combined {
+				cartoRules = append(cartoRules, cartoRule{combinedSelector, current.declarations})
+			}
+		}
+
+		cartoRules = append(cartoRules, flattenBlocks(current.children, combined)...)
+	}
+
+	return cartoRules
+}
+
+//selectedLayers returns the layers of the first table, which matches the id or the datasource table of a layer.
+//Without matching table only a project with a single layer is used
+func (s *Parser) selectedLayers() []Layer {
+
+	s.usedLayers = make([]string, 0)
+
+	for _, tableName := range s.tableNames {
+		layers := make([]Layer, 0)
+
+		for _, layer := range s.project.Layers {
+			for _, name := range layerTableNames(layer) {
+				if sld.MatchesTable(name, tableName, s.tablePrefix) {
+					layers = append(layers, layer)
+					s.usedLayers = append(s.usedLayers, layer.ID)
+					break
+				}
+			}
+		}
+
+		if len(layers) > 0 {
+			return layers
+		}
+	}
+
+	if len(s.project.Layers) <= 1 {
+		return s.project.Layers
+	}
+
+	fmt.Println(`WARNING: no layer of the project "`+s.filePath+`" matches the tables`, s.tableNames)
+
+	return []Layer{}
+}
+
+//convertRule converts a ruleset into a SLD rule and adds the columns of its properties to the requirements.
+//False is returned, if the ruleset is not drawn in the zoom levels of the layer
+func convertRule(cartoRule cartoRule, zooms zoomRange, layerIndex int, variables map[string]string, query mapnik.SQLColumns, requirements *sld.TableRequirements) (sld.Rule, bool) {
+
+	rule := sld.Rule{FeatureTypeStyle: layerIndex}
+	filterParts := make([]string, 0)
+
+	for _, filter := range cartoRule.selector.conditions {
+		value := filter.value
+
+		if variable, isVariable := variables[value]; isVariable {
+			value = variable
+		}
+
+		switch {
+		case filter.field == "zoom":
+			zooms = zooms.restrict(filter.operator, value)
+		case filter.operator == "=~":
+			filterParts = append(filterParts, "["+filter.field+"].match("+value+")")
+		default:
+			filterParts = append(filterParts, "["+filter.field+"] "+filter.operator+" "+value)
+		}
+	}
+
+	if zooms.isEmpty() {
+		return sld.Rule{}, false
+	}
+
+	rule.MinScale, rule.MaxScale = zooms.scale()
+	scale := zooms.scaleDenominator()
+
+	//the conditions are translated into the Mapnik filter, which is created by the carto compiler
+	if len(filterParts) > 0 {
+		filterText := strings.Join(filterParts, " and ")
+		rule.Filter = sld.Filter{XMLContent: []byte(filterText)}
+		filterTree, err := mapnik.ParseFilter(filterText)
+
+		if err != nil {
+			fmt.Println("Parsing Error: " + err.Error())
+			filterTree = sld.UnknownFilter{Name: "Filter"}
+		}
+
+		rule.FilterTree = query.ResolveFilter(filterTree)
+
+		for _, column := range query.FilterColumns(rule.FilterTree, mapnik.AttributeNames(filterText)) {
+			sld.AddRequiredColumn(requirements, column, []string{}, scale, sld.FilterUsage)
+		}
+	}
+
+	for _, property := range cartoRule.declarations {
+		name := property.property
+
+		//instances like "casing/line-width" draw an additional symbolizer
+		if index := strings.LastIndex(name, "/"); index != -1 {
+			name = name[index+1:]
+		}
+
+		symbolizer := []sld.Symbolizer{{XMLContent: []byte(property.value), UnitOfMeasure: sld.PixelUnit}}
+
+		for _, kind := range propertyKinds(name) {
+			switch kind {
+			case sld.PointKind:
+				rule.PointSymbolizer = symbolizer
+			case sld.LineKind:
+				rule.LineSymbolizer = symbolizer
+			case sld.PolygonKind:
+				rule.PolygonSymbolizer = symbolizer
+			case sld.TextKind:
+				rule.TextSymbolizer = symbolizer
+			case sld.RasterKind:
+				rule.RasterSymbolizer = symbolizer
+			}
+		}
+
+		usage := sld.StyleUsage
+
+		if functions.StringInSlice(name, labelProperties) {
+			usage = sld.LabelUsage
+		}
+
+		for _, attribute := range mapnik.AttributeNames(unquote(property.value)) {
+			for _, column := range query.SourceColumns(attribute) {
+				sld.AddRequiredColumn(requirements, column, []string{}, scale, usage)
+			}
+		}
+	}
+
+	return rule, true
+}
+
+//propertyKinds returns the symbolizer kinds of a property like "line-width"
+func propertyKinds(property string) []string {
+
+	for _, prefix := range []string{"line-pattern", "polygon-pattern"} {
+		if strings.HasPrefix(property, prefix+"-") {
+			return symbolizerKinds[prefix]
+		}
+	}
+
+	if index := strings.Index(property, "-"); index != -1 {
+		return symbolizerKinds[property[:index]]
+	}
+
+	return nil
+}
+
+//unquote removes the quotes of a value, which is a single string like "[name]"
+func unquote(value string) string {
+
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && strings.IndexByte(value[1:], value[0]) == len(value)-2 {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
+
+//layerZooms returns the zoom levels of a layer
+func layerZooms(layer Layer) zoomRange {
+
+	zooms := allZooms()
+
+	if layer.Properties.MinZoom != nil {
+		zooms.start = *layer.Properties.MinZoom
+	}
+
+	if layer.Properties.MaxZoom != nil {
+		zooms.end = *layer.Properties.MaxZoom
+	}
+
+	return zooms
+}
+
+//layerTableNames returns the id of a layer and the tables of its datasource query
+func layerTableNames(layer Layer) []string {
+	return append([]string{layer.ID}, mapnik.ParseDatasourceSQL(layer.Datasource.Table).Tables...)
+}
@@ -0,0 +1,103 @@
+package carto
+
+import (
+	functions "Imposm_Optimizer/std_functions"
+	"strings"
+)
+
+//########### CartoCSS project structures ###########//
+
+//Project contains the stylesheets and layers of a project.mml file, which can be written in YAML or JSON
+type Project struct {
+	Stylesheets []string `yaml:"Stylesheet"`
+	Layers      []Layer  `yaml:"Layer"`
+}
+
+//Layer references the features of a datasource, its styles are selected by the id and the classes of the layer
+type Layer struct {
+	ID         string          `yaml:"id"`
+	Class      string          `yaml:"class"`
+	Geometry   string          `yaml:"geometry"`
+	Datasource Datasource      `yaml:"Datasource"`
+	Properties LayerProperties `yaml:"properties"`
+}
+
+//Datasource contains the type and the table or SQL query of a layer
+type Datasource struct {
+	Type  string `yaml:"type"`
+	Table string `yaml:"table"`
+}
+
+//LayerProperties contain the zoom levels, in which a layer is drawn
+type LayerProperties struct {
+	MinZoom *int `yaml:"minzoom"`
+	MaxZoom *int `yaml:"maxzoom"`
+}
+
+//########### CartoCSS stylesheet structures ###########//
+
+//block is a ruleset of a stylesheet with its selectors, declarations and nested rulesets
+type block struct {
+	selectors    []selector
+	declarations []declaration
+	children     []block
+}
+
+//selector of a ruleset like "#roads::casing[feature = 'highway_primary'][zoom >= 12]"
+type selector struct {
+	ids        []string
+	classes    []string
+	attachment string
+	elements   []string
+	conditions []condition
+}
+
+//condition is a filter of a selector like "[zoom >= 12]"
+type condition struct {
+	field    string
+	operator string
+	value    string
+}
+
+//declaration is a property of a ruleset like "line-width: 2"
+type declaration struct {
+	property string
+	value    string
+}
+
+//combine returns the selector of a nested ruleset, which contains the conditions of its parent
+func (s selector) combine(child selector) selector {
+
+	combined := selector{
+		append(append([]string{}, s.ids...), child.ids...),
+		append(append([]string{}, s.classes...), child.classes...),
+		s.attachment + child.attachment,
+		append(append([]string{}, s.elements...), child.elements...),
+		append(append([]condition{}, s.conditions...), child.conditions...)}
+
+	return combined
+}
+
+//matchesLayer checks if the layer is selected by the ids and classes of the selector
+func (s selector) matchesLayer(layer Layer) bool {
+
+	if len(s.ids) == 0 && len(s.classes) == 0 {
+		return false
+	}
+
+	for _, id := range s.ids {
+		if id != layer.ID {
+			return false
+		}
+	}
+
+	layerClasses := strings.Fields(layer.Class)
+
+	for _, class := range s.classes {
+		if !functions.StringInSlice(class, layerClasses) {
+			return false
+		}
+	}
+
+	return true
+}
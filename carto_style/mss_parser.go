@@ -0,0 +1,181 @@
+package carto
+
+import (
+	scanner "Imposm_Optimizer/stylesheet_scanner"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+//conditionOperators of the selector filters, longer operators have to be tested first
+var conditionOperators = []string{"=~", "!=", ">=", "<=", "=", ">", "<", "%"}
+
+//parseStylesheet returns the rulesets of a stylesheet. Variables like "@water: #aad3df;" are added to the variables map
+func parseStylesheet(text string, variables map[string]string) ([]block, error) {
+
+	scannedBlocks, err := scanner.Parse(text, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return convertBlocks(scannedBlocks, variables)
+}
+
+//convertBlocks parses the selectors and declarations of the scanned blocks
+func convertBlocks(scannedBlocks []scanner.Block, variables map[string]string) ([]block, error) {
+
+	blocks := make([]block, 0, len(scannedBlocks))
+
+	for _, scannedBlock := range scannedBlocks {
+		selectors, err := parseSelectors(scannedBlock.Selectors)
+
+		if err != nil {
+			return nil, err
+		}
+
+		current := block{selectors, make([]declaration, 0), nil}
+
+		for _, statement := range scannedBlock.Statements {
+			addStatement(statement, &current, variables)
+		}
+
+		current.children, err = convertBlocks(scannedBlock.Children, variables)
+
+		if err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, current)
+	}
+
+	return blocks, nil
+}
+
+//addStatement adds a declaration to the ruleset or a variable to the variables map
+func addStatement(statement string, current *block, variables map[string]string) {
+
+	index := strings.Index(statement, ":")
+
+	if index == -1 {
+		return
+	}
+
+	property := strings.TrimSpace(statement[:index])
+	value := strings.TrimSpace(statement[index+1:])
+
+	if strings.HasPrefix(property, "@") {
+		variables[property] = value
+		return
+	}
+
+	current.declarations = append(current.declarations, declaration{property, value})
+}
+
+//parseSelectors parses a comma separated list of selectors
+func parseSelectors(text string) ([]selector, error) {
+
+	selectors := make([]selector, 0)
+
+	for _, part := range scanner.SplitOutside(text, ',') {
+		parsed, err := parseSelector(strings.TrimSpace(part))
+
+		if err != nil {
+			return nil, err
+		}
+
+		selectors = append(selectors, parsed)
+	}
+
+	return selectors, nil
+}
+
+//parseSelector parses the ids, classes, attachments and conditions of a selector
+func parseSelector(text string) (selector, error) {
+
+	parsed := selector{}
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '#' || r == '.':
+			name, end := scanner.ReadName(runes, i+1)
+
+			if r == '#' {
+				parsed.ids = append(parsed.ids, name)
+			} else {
+				parsed.classes = append(parsed.classes, name)
+			}
+			i = end
+
+		case r == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			name, end := scanner.ReadName(runes, i+2)
+			parsed.attachment += "::" + name
+			i = end
+
+		case r == '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				if runes[end] == '"' || runes[end] == '\'' {
+					end = scanner.ClosingQuote(runes, end)
+					continue
+				}
+				end++
+			}
+
+			if end == len(runes) {
+				return selector{}, errors.New(`missing "]" in selector "` + text + `"`)
+			}
+
+			parsedCondition, err := parseCondition(string(runes[i+1 : end]))
+
+			if err != nil {
+				return selector{}, err
+			}
+
+			parsed.conditions = append(parsed.conditions, parsedCondition)
+			i = end + 1
+
+		default:
+			name, end := scanner.ReadName(runes, i)
+
+			if end == i {
+				return selector{}, errors.New(`unexpected "` + string(r) + `" in selector "` + text + `"`)
+			}
+
+			parsed.elements = append(parsed.elements, name)
+			i = end
+		}
+	}
+
+	return parsed, nil
+}
+
+//parseCondition parses a filter like "feature = 'highway_primary'" or "zoom >= 12"
+func parseCondition(text string) (condition, error) {
+
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '"' || runes[i] == '\'' {
+			i = scanner.ClosingQuote(runes, i) - 1
+			continue
+		}
+
+		for _, operator := range conditionOperators {
+			if strings.HasPrefix(string(runes[i:]), operator) {
+				field := strings.Trim(strings.TrimSpace(string(runes[:i])), `"'`)
+				value := strings.TrimSpace(string(runes[i+len([]rune(operator)):]))
+
+				return condition{field, operator, value}, nil
+			}
+		}
+	}
+
+	return condition{}, errors.New(`invalid filter "[` + text + `]"`)
+}
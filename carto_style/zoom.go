@@ -0,0 +1,100 @@
+package carto
+
+import (
+	"Imposm_Optimizer/sld"
+	"math"
+	"strconv"
+)
+
+//zoomScales are the scale denominators, which are used by the carto compiler for the zoom levels 0 to 26.
+//A zoom level z is drawn between the scale denominators of z+1 and z
+var zoomScales = []float64{1000000000, 500000000, 200000000, 100000000, 50000000, 25000000, 12500000, 6500000, 3000000,
+	1500000, 750000, 400000, 200000, 100000, 50000, 25000, 12500, 5000, 2500, 1500, 750, 500, 250, 100, 50, 25, 12.5}
+
+//maxZoom is the highest zoom level of a stylesheet
+var maxZoom = len(zoomScales) - 1
+
+//zoomRange contains the first and the last zoom level of a rule
+type zoomRange struct {
+	start int
+	end   int
+}
+
+//allZooms is the range of a rule without zoom conditions
+func allZooms() zoomRange {
+	return zoomRange{0, maxZoom}
+}
+
+//restrict applies a zoom condition like "zoom >= 12" to the range, unknown operators do not restrict the range
+func (z zoomRange) restrict(operator string, value string) zoomRange {
+
+	zoom, err := strconv.Atoi(value)
+
+	if err != nil {
+		return z
+	}
+
+	switch operator {
+	case "=":
+		return zoomRange{maxInt(z.start, zoom), minInt(z.end, zoom)}
+	case ">":
+		return zoomRange{maxInt(z.start, zoom+1), z.end}
+	case ">=":
+		return zoomRange{maxInt(z.start, zoom), z.end}
+	case "<":
+		return zoomRange{z.start, minInt(z.end, zoom-1)}
+	case "<=":
+		return zoomRange{z.start, minInt(z.end, zoom)}
+	}
+
+	return z
+}
+
+//isEmpty checks if a rule is drawn in no zoom level
+func (z zoomRange) isEmpty() bool {
+	return z.start > z.end || z.end < 0 || z.start > maxZoom
+}
+
+//scale converts the zoom levels into the scale denominators of a rule, 0 is used for an infinite maximum like in SLD rules
+func (z zoomRange) scale() (int, int) {
+
+	minScale, maxScale := 0, 0
+
+	if z.start > 0 {
+		maxScale = int(math.Round(zoomScales[z.start]))
+	}
+
+	if z.end < maxZoom {
+		minScale = int(math.Round(zoomScales[z.end+1]))
+	}
+
+	return minScale, maxScale
+}
+
+//scaleDenominator returns the scale range of the zoom levels
+func (z zoomRange) scaleDenominator() sld.ScaleDenominator {
+
+	minScale, maxScale := z.scale()
+
+	if maxScale == 0 {
+		maxScale = -2
+	}
+
+	return sld.ScaleDenominator{MinScaleDenominator: minScale, MaxScaleDenominator: maxScale}
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"Imposm_Optimizer/configuration"
+	functions "Imposm_Optimizer/std_functions"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+//commands of the command line, the first argument selects the command
+const (
+	optimizeCommand = "optimize"
+	initCommand     = "init"
+	validateCommand = "validate"
+	diffCommand     = "diff"
+	analyzeCommand  = "analyze"
+	explainCommand  = "explain"
+)
+
+//commandDescriptions are printed by the help of the command line
+var commandDescriptions = [][]string{
+	{optimizeCommand, "writes the optimized mapping file, this is the default command"},
+	{initCommand, "creates the configuration file, missing values are asked on the console"},
+	{validateCommand, "checks the configuration and reads all style files without writing the mapping file"},
+	{diffCommand, "shows the changes of the optimized mapping file without writing it"},
+	{analyzeCommand, "shows the requirements of the styles of every table and proposes scale bands and generalized tables"},
+	{explainCommand, "explains why the columns and mapping values of the given tables are kept or removed"}}
+
+//configFlags map the flags of the command line to the values of the configuration file, which they replace
+var configFlags = map[string]string{
+	"mapping":           "mapping_path",
+	"out":               "mapping_out_path",
+	"prefix":            "mapping_prefix",
+	"research":          "allow_research",
+	"force-filtering":   "force_filtering",
+	"tolerance-scaling": "tolerance_scaling"}
+
+//errMappingChanged is returned by the diff command with the flag -exit-code, if the optimization changes the mapping file
+var errMappingChanged = errors.New("the optimization changes the mapping file")
+
+//commandOptions contains the flags of the command line, which are not part of the configuration file.
+//overrides = configuration values like "table_prefix=osm_", tables = the tables explained by the explain command,
+//exitCode = the diff command fails, if the mapping file is changed
+type commandOptions struct {
+	overrides []string
+	tables    []string
+	exitCode  bool
+}
+
+//stringList is a flag, which can be given several times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+//runCommand parses the arguments of the command line and runs the selected command
+func runCommand(arguments []string) error {
+
+	command := optimizeCommand
+	explicitCommand := len(arguments) > 0 && !strings.HasPrefix(arguments[0], "-")
+
+	if explicitCommand {
+		command = arguments[0]
+		arguments = arguments[1:]
+	}
+
+	if command == "help" {
+		printUsage()
+		return nil
+	}
+
+	if !isCommand(command) {
+		printUsage()
+		return errors.New(`unknown command "` + command + `"`)
+	}
+
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Println("Usage: Imposm_Optimizer " + command + " [flags]")
+		flags.PrintDefaults()
+	}
+
+	flags.StringVar(&configuration.ConfigFile, "config", configuration.ConfigFile, "path of the configuration file")
+	flags.String("mapping", "", `path of the mapping file, replaces "mapping_path"`)
+	flags.String("out", "", `directory of the optimized mapping file, replaces "mapping_out_path"`)
+	flags.String("prefix", "", `prefix of the optimized mapping file, replaces "mapping_prefix"`)
+	research := flags.Bool("research", false, `research missing keys with the tagfinder API, replaces "allow_research"`)
+
+	var overrides stringList
+	var tables stringList
+	var styleDirectory, styleFilePattern *string
+	var noInput, exitCode *bool
+
+	if command == initCommand {
+		styleDirectory = flags.String("style-dir", "", "directory of the style files, which are assigned to the tables automatically")
+		styleFilePattern = flags.String("style-pattern", "", `file name pattern of the style files like "osm_{table}.sld"`)
+		noInput = flags.Bool("no-input", false, "nothing is asked on the console, missing values are taken from an existing configuration file")
+	} else {
+		flags.Bool("force-filtering", false, `filter the mapping values of every table, replaces "force_filtering"`)
+		flags.Float64("tolerance-scaling", 0, `scaling of the tolerance of generalized tables in percent, replaces "tolerance_scaling"`)
+		flags.Var(&overrides, "set", `replaces a value of the configuration file like "table_prefix=osm_" or "database.schema=import", can be repeated`)
+	}
+
+	if command == explainCommand {
+		flags.Var(&tables, "table", "table to explain, can be repeated, all tables are explained by default")
+	}
+
+	if command == diffCommand {
+		exitCode = flags.Bool("exit-code", false, "exit with status 1, if the optimization changes the mapping file")
+	}
+
+	err := flags.Parse(arguments)
+
+	if err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	//the explain command takes the tables as arguments as well
+	if command == explainCommand {
+		tables = append(tables, flags.Args()...)
+	} else if flags.NArg() > 0 {
+		flags.Usage()
+		return errors.New(`unexpected argument "` + flags.Arg(0) + `"`)
+	}
+
+	if command == initCommand {
+		options := configuration.InitOptions{
+			MappingFilePath:  flags.Lookup("mapping").Value.String(),
+			MappingOutPath:   flags.Lookup("out").Value.String(),
+			MappingPrefix:    flags.Lookup("prefix").Value.String(),
+			StyleDirectory:   *styleDirectory,
+			StyleFilePattern: *styleFilePattern,
+			NoInput:          *noInput}
+
+		if isFlagSet(flags, "research") {
+			options.AllowResearch = research
+		}
+
+		return configuration.InitConfigFile(options)
+	}
+
+	//only the flags given on the command line replace the values of the configuration file
+	configOverrides := make([]string, 0)
+
+	flags.Visit(func(setFlag *flag.Flag) {
+		if configName, isConfigFlag := configFlags[setFlag.Name]; isConfigFlag {
+			configOverrides = append(configOverrides, configName+"="+flagJSON(setFlag))
+		}
+	})
+
+	options := commandOptions{append(configOverrides, overrides...), tables, exitCode != nil && *exitCode}
+
+	//without command and configuration file, the configuration is created on the console first
+	if !explicitCommand && !functions.FileExists(configuration.ConfigFile) {
+		err := configuration.InitConfigFile(configuration.InitOptions{})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return runOptimizer(command, options)
+}
+
+//flagJSON returns the value of a flag as JSON value, strings are quoted
+func flagJSON(setFlag *flag.Flag) string {
+
+	value := setFlag.Value.String()
+
+	if getter, isGetter := setFlag.Value.(flag.Getter); isGetter {
+		if _, isString := getter.Get().(string); !isString {
+			return value
+		}
+	}
+
+	quoted, _ := json.Marshal(value)
+
+	return string(quoted)
+}
+
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+
+	set := false
+
+	flags.Visit(func(setFlag *flag.Flag) {
+		if setFlag.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
+func isCommand(command string) bool {
+	for _, description := range commandDescriptions {
+		if description[0] == command {
+			return true
+		}
+	}
+
+	return false
+}
+
+func printUsage() {
+
+	fmt.Println("Usage: Imposm_Optimizer [command] [flags]")
+	fmt.Println()
+	fmt.Println("Commands:")
+
+	for _, description := range commandDescriptions {
+		fmt.Printf("  %-10s %s\n", description[0], description[1])
+	}
+
+	fmt.Println()
+	fmt.Println(`The flags of a command are shown by "Imposm_Optimizer <command> -h"`)
+}
@@ -0,0 +1,106 @@
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+//OverrideValues replaces values of the parsed configuration by values of the command line like "table_prefix=osm_"
+//or "database.schema=import". A value is read as JSON value like "true", "0.5" or `["roads.sld"]`, if it fits the
+//configuration value, otherwise it is a string
+func OverrideValues(overrides []string) error {
+
+	for _, override := range overrides {
+		index := strings.Index(override, "=")
+
+		if index <= 0 {
+			return errors.New(`invalid configuration value "` + override + `", expected "name=value"`)
+		}
+
+		path := strings.Split(override[:index], ".")
+
+		if !isConfigKey(path[0]) {
+			return errors.New(`unknown configuration value "` + path[0] + `"`)
+		}
+
+		var value interface{}
+
+		if json.Unmarshal([]byte(override[index+1:]), &value) != nil {
+			value = override[index+1:]
+		}
+
+		overridden, err := overrideValue(path, value)
+
+		//values like "mapping_prefix=2021" are strings, if the configuration value is a string
+		if err != nil {
+			overridden, err = overrideValue(path, override[index+1:])
+		}
+
+		if err != nil {
+			return errors.New(`invalid configuration value "` + override + `": ` + err.Error())
+		}
+
+		parsedConfig = overridden
+	}
+
+	return nil
+}
+
+//overrideValue returns the parsed configuration with a replaced value, the path contains the names of nested values
+func overrideValue(path []string, value interface{}) (config, error) {
+
+	content, err := json.Marshal(parsedConfig)
+
+	if err != nil {
+		return config{}, err
+	}
+
+	values := make(map[string]interface{})
+	err = json.Unmarshal(content, &values)
+
+	if err != nil {
+		return config{}, err
+	}
+
+	parent := values
+
+	for _, name := range path[:len(path)-1] {
+		child, isObject := parent[name].(map[string]interface{})
+
+		if !isObject {
+			child = make(map[string]interface{})
+			parent[name] = child
+		}
+
+		parent = child
+	}
+
+	parent[path[len(path)-1]] = value
+
+	content, err = json.Marshal(values)
+
+	if err != nil {
+		return config{}, err
+	}
+
+	overridden := config{}
+	err = json.Unmarshal(content, &overridden)
+
+	return overridden, err
+}
+
+//isConfigKey checks if a name is the JSON name of a configuration value
+func isConfigKey(name string) bool {
+
+	configType := reflect.TypeOf(config{})
+
+	for i := 0; i < configType.NumField(); i++ {
+		if strings.Split(configType.Field(i).Tag.Get("json"), ",")[0] == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,29 +1,48 @@
 package configuration
 
 import (
+	geoserver "Imposm_Optimizer/geoserver_api"
 	"Imposm_Optimizer/mapping"
+	database "Imposm_Optimizer/osm_database"
+	"Imposm_Optimizer/sld"
 	functions "Imposm_Optimizer/std_functions"
+	styles "Imposm_Optimizer/style_reader"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
-//ConfigFile Name of the configuration file
-const ConfigFile = "config.json"
+//ConfigFile Path of the configuration file, it can be changed on the command line
+var ConfigFile = "config.json"
 
 var parsedConfig config
 
 type config struct {
-	MappingFilePath      string              `json:"mapping_path"`
-	MappingOutPath       string              `json:"mapping_out_path"`
-	MappingPrefix        string              `json:"mapping_prefix"`
-	KeepColumns          []string            `json:"keep_columns,flow,omitempty"`
-	ForceFiltering       bool                `json:"force_filtering"`
-	AllowResearch        bool                `json:"allow_research"`
-	ToleranceScaling     float32             `json:"tolerance_scaling"`
-	TableList            map[string][]string `json:"tables,flow,omitempty"`
-	GeneralizedTableList map[string][]string `json:"generalized_tables,flow,omitempty"`
+	MappingFilePath      string                          `json:"mapping_path"`
+	MappingOutPath       string                          `json:"mapping_out_path"`
+	MappingPrefix        string                          `json:"mapping_prefix"`
+	KeepColumns          []string                        `json:"keep_columns,flow,omitempty"`
+	ForceFiltering       bool                            `json:"force_filtering"`
+	AllowResearch        bool                            `json:"allow_research"`
+	ToleranceScaling     float64                         `json:"tolerance_scaling"`
+	TableList            map[string][]string             `json:"tables,flow,omitempty"`
+	GeneralizedTableList map[string][]string             `json:"generalized_tables,flow,omitempty"`
+	ScaleBands           map[string]sld.ScaleDenominator `json:"scale_bands,omitempty"`
+	AutoScaleBands       bool                            `json:"auto_scale_bands"`
+	ProposeGenTables     bool                            `json:"propose_generalized_tables"`
+	Tolerance            *mapping.ToleranceSettings      `json:"tolerance,omitempty"`
+	ApplyGeometryTypes   bool                            `json:"apply_geometry_types"`
+	Database             *database.Settings              `json:"database,omitempty"`
+	TablePrefix          string                          `json:"table_prefix,omitempty"`
+	StyleFiles           []string                        `json:"styles,flow,omitempty"`
+	GeoServer            *geoserver.Settings             `json:"geoserver,omitempty"`
+	StyleDirectory       string                          `json:"style_directory,omitempty"`
+	StyleFilePattern     string                          `json:"style_file_pattern,omitempty"`
+	AllowMissingStyles   bool                            `json:"allow_missing_styles"`
 }
 
 func saveConfigFile(conf config) error {
@@ -38,8 +57,8 @@ func saveConfigFile(conf config) error {
 	return ioutil.WriteFile(ConfigFile, newConfByte, 0666)
 }
 
-//InitConfigFile Intialisation of the configuration file, console inputs required
-func InitConfigFile() error {
+//InitConfigFile Intialisation of the configuration file, the values of the options are not asked on the console
+func InitConfigFile(options InitOptions) error {
 	fmt.Println("***************** Initialization ******************")
 	fmt.Println()
 
@@ -57,77 +76,46 @@ func InitConfigFile() error {
 	}
 
 	//Path to mapping file input
-	if foundOldConfig {
-		fmt.Print("Path to mapping file [" + oldConfig.MappingFilePath + "]: ")
-	} else {
-		fmt.Print("Path to mapping file: ")
-	}
-
-	var pathToMapping string
-	for {
+	pathToMapping := options.input(options.MappingFilePath, "Path to mapping file", oldConfig.MappingFilePath)
 
-		fmt.Scanln(&pathToMapping)
-
-		if foundOldConfig && pathToMapping == "" {
-			pathToMapping = oldConfig.MappingFilePath
+	for !functions.FileExists(pathToMapping) {
+		if options.NoInput || options.MappingFilePath != "" {
+			return errors.New(`mapping file "` + pathToMapping + `" not found`)
 		}
 
-		if !functions.FileExists(pathToMapping) {
-			fmt.Print("File could not be found, please enter a correct path to the file: ")
-		} else {
-			break
-		}
+		pathToMapping = options.input("", "File could not be found, please enter a correct path to the file", oldConfig.MappingFilePath)
 	}
 
 	//new mapping file output directory input
-	if foundOldConfig {
-		fmt.Print("Target folder of the new mapping file [" + oldConfig.MappingOutPath + "]: ")
-	} else {
-		fmt.Print("Target folder of the new mapping file: ")
-	}
-
-	var pathOutMapping string
-	for {
-		fmt.Scanln(&pathOutMapping)
+	pathOutMapping := options.input(options.MappingOutPath, "Target folder of the new mapping file", oldConfig.MappingOutPath)
 
-		if foundOldConfig && pathOutMapping == "" {
-			pathOutMapping = oldConfig.MappingOutPath
+	for !functions.DirExists(pathOutMapping) {
+		if options.NoInput || options.MappingOutPath != "" {
+			return errors.New(`target directory "` + pathOutMapping + `" not found`)
 		}
 
-		if !functions.DirExists(pathOutMapping) {
-			fmt.Print("Directory could not be found, please enter a correct path: ")
-		} else {
-			break
-		}
+		pathOutMapping = options.input("", "Directory could not be found, please enter a correct path", oldConfig.MappingOutPath)
 	}
 
 	//new mapping file prefix imput
-	if foundOldConfig {
-		fmt.Print("What prefix should the new mapping file have? If not specified and destination folder is the same, the file will be overwritten! [" + oldConfig.MappingPrefix + "]: ")
-	} else {
-		fmt.Print("What prefix should the new mapping file have? If not specified and destination folder is the same, the file will be overwritten!: ")
-	}
-	var prefix string
-	fmt.Scanln(&prefix)
-	if foundOldConfig && prefix == "" {
-		prefix = oldConfig.MappingPrefix
-	}
+	prefix := options.input(options.MappingPrefix, "What prefix should the new mapping file have? If not specified and destination folder is the same, the file will be overwritten!", oldConfig.MappingPrefix)
 
 	//allow research via api input yes or no
-	fmt.Print("If certain information about column types or keywords is missing, an API(http://tagfinder.herokuapp.com/apidoc) is used to research it. Should this be allowed? (Y/N): ")
-	var ans string
-	fmt.Scanln(&ans)
+	allowResearch := oldConfig.AllowResearch
+
+	if options.AllowResearch != nil {
+		allowResearch = *options.AllowResearch
+	} else {
+		oldAnswer := "N"
 
-	if foundOldConfig && ans == "" {
 		if oldConfig.AllowResearch {
-			ans = "y"
+			oldAnswer = "Y"
 		}
-	}
 
-	//should necessary values not found be looked up
-	allowResearch := false
-	if strings.Compare("y", strings.ToLower(ans)) == 0 || strings.Compare("yes", strings.ToLower(ans)) == 0 {
-		allowResearch = true
+		ans := options.input("", "If certain information about column types or keywords is missing, an API(http://tagfinder.herokuapp.com/apidoc) is used to research it. Should this be allowed? (Y/N)", oldAnswer)
+
+		//should necessary values not found be looked up
+		allowResearch = strings.Compare("y", strings.ToLower(ans)) == 0 || strings.Compare("yes", strings.ToLower(ans)) == 0
 	}
 
 	//should each table force the filtering of mapping values -- no input, must be changed in json file
@@ -138,12 +126,19 @@ func InitConfigFile() error {
 	}
 
 	//tolerance scaling for the tolerance value in generalized tables, is given in percent -- no input, must be changed in json file
-	var toleranceScaling float32 = 0.1
+	var toleranceScaling float64 = 0.1
 
 	if foundOldConfig {
 		toleranceScaling = oldConfig.ToleranceScaling
 	}
 
+	//projection and pixel tolerance for the tolerance value in generalized tables, replaces the tolerance scaling -- no input, must be changed in json file
+	var toleranceSettings *mapping.ToleranceSettings
+
+	if foundOldConfig {
+		toleranceSettings = oldConfig.Tolerance
+	}
+
 	//standart required columns -- no input, must be changed in json file
 	requiredColumnTypes := []string{"geometry", "validated_geometry", "id", "member_id"}
 
@@ -151,21 +146,175 @@ func InitConfigFile() error {
 		requiredColumnTypes = oldConfig.KeepColumns
 	}
 
-	//input sld's for normal tables
+	//scale bands in which the generalized tables are rendered -- no input, must be changed in json file
+	scaleBands := make(map[string]sld.ScaleDenominator)
+
+	if foundOldConfig && oldConfig.ScaleBands != nil {
+		scaleBands = oldConfig.ScaleBands
+	}
+
+	//assign the scale bands of the generalized tables from their tolerance and propose new generalized tables -- no input, must be changed in json file
+	autoScaleBands := false
+	proposeGenTables := false
+
+	if foundOldConfig {
+		autoScaleBands = oldConfig.AutoScaleBands
+		proposeGenTables = oldConfig.ProposeGenTables
+	}
+
+	//narrow the geometry types of the tables and count the saved rows in an existing import -- no input, must be changed in json file
+	applyGeometryTypes := false
+	var databaseSettings *database.Settings
+
+	if foundOldConfig {
+		applyGeometryTypes = oldConfig.ApplyGeometryTypes
+		databaseSettings = oldConfig.Database
+	}
+
+	//table prefix of the imposm import and style files with several layers, which are routed to the tables by the layer names -- no input, must be changed in json file
+	tablePrefix := ""
+	var styleFiles []string
+
+	if foundOldConfig {
+		tablePrefix = oldConfig.TablePrefix
+		styleFiles = oldConfig.StyleFiles
+	}
+
+	//REST API of a GeoServer, whose layer styles are added to the tables of the layers -- no input, must be changed in json file
+	var geoServerSettings *geoserver.Settings
+
+	if foundOldConfig {
+		geoServerSettings = oldConfig.GeoServer
+	}
+
+	//style entries without style file only cause a warning instead of an error -- no input, must be changed in json file
+	allowMissingStyles := false
+
+	if foundOldConfig {
+		allowMissingStyles = oldConfig.AllowMissingStyles
+	}
+
+	//style files of a directory are assigned to the tables by their file names or layer names
+	styleDirectory := options.input(options.StyleDirectory, `Directory of the style files, which are assigned to the tables automatically. Leave empty or type "NONE" to enter the style files of every table`, oldConfig.StyleDirectory)
+
+	for {
+		if strings.Compare("none", strings.ToLower(styleDirectory)) == 0 {
+			styleDirectory = ""
+		}
+
+		if styleDirectory == "" || functions.DirExists(styleDirectory) {
+			break
+		}
+
+		if options.NoInput || options.StyleDirectory != "" {
+			return errors.New(`style directory "` + styleDirectory + `" not found`)
+		}
+
+		styleDirectory = options.input("", "Directory could not be found, please enter a correct path", "")
+	}
+
+	var styleFilePattern string
+
+	if styleDirectory != "" {
+		styleFilePattern = options.input(options.StyleFilePattern, `File name pattern of the style files like "osm_{table}.sld". Leave empty or type "NONE" to use the layer names only`, oldConfig.StyleFilePattern)
+
+		if strings.Compare("none", strings.ToLower(styleFilePattern)) == 0 {
+			styleFilePattern = ""
+		}
+	}
+
 	mappingParser := mapping.New(pathToMapping, forceFiltering, allowResearch, toleranceScaling, requiredColumnTypes)
 	mappingTables := mappingParser.GetTableNames()
+	mappingGeneralizedTables := mappingParser.GetGeneralizedTableNames()
+
+	var tableMap map[string][]string
+	var generalizedTableMap map[string][]string
+
+	if styleDirectory != "" {
+		//imposm adds the prefix "osm_" to the table names by default
+		discoveryPrefix := tablePrefix
+
+		if discoveryPrefix == "" {
+			discoveryPrefix = "osm_"
+		}
+
+		discovery, err := styles.Discover(styleDirectory, styleFilePattern, mappingTables, mappingGeneralizedTables, discoveryPrefix)
+
+		if err != nil {
+			return err
+		}
+
+		printDiscovery(discovery)
+
+		tableMap = discovery.Tables
+		generalizedTableMap = discovery.GeneralizedTables
+	} else {
+		//input sld's for normal and generalized tables
+		tableMap = options.inputStyleFiles(mappingTables, "table")
+		generalizedTableMap = options.inputStyleFiles(mappingGeneralizedTables, "generalized table")
+	}
+
+	//the style entries are resolved from the directory of the configuration file, not from the working directory
+	configRelativePaths(tableMap)
+	configRelativePaths(generalizedTableMap)
+
+	newConf := config{pathToMapping, pathOutMapping, prefix, requiredColumnTypes, forceFiltering, allowResearch, toleranceScaling, tableMap, generalizedTableMap, scaleBands, autoScaleBands, proposeGenTables, toleranceSettings, applyGeometryTypes, databaseSettings, tablePrefix, styleFiles, geoServerSettings, styleDirectory, styleFilePattern, allowMissingStyles}
+
+	err := saveConfigFile(newConf)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//configRelativePaths converts the style files of the tables into paths relative to the directory of the configuration file.
+//Paths, which cannot be expressed relative to it, are written as absolute paths
+func configRelativePaths(tableMap map[string][]string) {
+
+	configDir, err := filepath.Abs(filepath.Dir(ConfigFile))
+
+	if err != nil {
+		return
+	}
+
+	for tableName, styleFiles := range tableMap {
+		for i, styleFile := range styleFiles {
+			absolutePath, err := filepath.Abs(styleFile)
+
+			if styleFile == "ignore" || err != nil {
+				continue
+			}
+
+			if relativePath, err := filepath.Rel(configDir, absolutePath); err == nil {
+				styleFiles[i] = relativePath
+			} else {
+				styleFiles[i] = absolutePath
+			}
+		}
+
+		tableMap[tableName] = styleFiles
+	}
+}
+
+//inputStyleFiles asks for the style files of every table, tables without style file can be ignored
+func (o *InitOptions) inputStyleFiles(tableNames []string, tableKind string) map[string][]string {
 
 	tableMap := make(map[string][]string)
 
-	for _, tableName := range mappingTables {
+	for _, tableName := range tableNames {
 
-		fmt.Println(`Path to the sld file/s that uses the "` + tableName + `" table. Type "END" to exit`)
+		if o.NoInput {
+			break
+		}
 
-		for {
+		fmt.Println(`Path to the sld file/s that uses the ` + tableKind + ` "` + tableName + `". Type "END" to exit`)
+
+		for !o.NoInput {
 			fmt.Print("-> ")
 
-			var fileName string
-			fmt.Scanln(&fileName)
+			fileName := o.readLine()
 
 			if strings.Compare("end", strings.ToLower(fileName)) == 0 ||
 				strings.Compare("exit", strings.ToLower(fileName)) == 0 {
@@ -177,10 +326,9 @@ func InitConfigFile() error {
 			}
 		}
 
-		if len(tableMap[tableName]) <= 0 {
-			fmt.Print(`No files were specified for the table "` + tableName + `", should this table be ignored in the following remapping? (Y/N): `)
-			var ans string
-			fmt.Scanln(&ans)
+		if len(tableMap[tableName]) <= 0 && !o.NoInput {
+			fmt.Print(`No files were specified for the ` + tableKind + ` "` + tableName + `", should this table be ignored in the following remapping? (Y/N): `)
+			ans := o.readLine()
 
 			if strings.Compare("y", strings.ToLower(ans)) == 0 || strings.Compare("yes", strings.ToLower(ans)) == 0 {
 				tableMap[tableName] = append(tableMap[tableName], "ignore")
@@ -188,61 +336,58 @@ func InitConfigFile() error {
 		}
 	}
 
-	//input sld's for generalized tables
-	mappingGeneralizedTables := mappingParser.GetGeneralizedTableNames()
+	return tableMap
+}
 
-	generalizedTableMap := make(map[string][]string)
+//printDiscovery reports the style files of the tables, the style files without table and the tables without style file
+func printDiscovery(discovery styles.Discovery) {
 
-	for _, tableName := range mappingGeneralizedTables {
+	fmt.Println("Discovered style files:")
 
-		fmt.Println(`Path to the sld file/s that uses the generalized "` + tableName + `" table. Type "END" to exit`)
+	for _, tableMap := range []map[string][]string{discovery.Tables, discovery.GeneralizedTables} {
+		tableNames := make([]string, 0, len(tableMap))
 
-		for {
-			fmt.Print("-> ")
+		for tableName := range tableMap {
+			tableNames = append(tableNames, tableName)
+		}
 
-			var fileName string
-			fmt.Scanln(&fileName)
+		sort.Strings(tableNames)
 
-			if strings.Compare("end", strings.ToLower(fileName)) == 0 ||
-				strings.Compare("exit", strings.ToLower(fileName)) == 0 {
-				break
-			} else if !functions.FileExists(fileName) {
-				fmt.Println(`File "` + fileName + `" not found!`)
-			} else {
-				generalizedTableMap[tableName] = append(generalizedTableMap[tableName], fileName)
-			}
+		for _, tableName := range tableNames {
+			fmt.Println("- "+tableName+":", tableMap[tableName])
 		}
+	}
 
-		if len(generalizedTableMap[tableName]) <= 0 {
-			fmt.Print(`No files were specified for the generalized table "` + tableName + `", should this table be ignored in the following remapping? (Y/N): `)
-			var ans string
-			fmt.Scanln(&ans)
+	if len(discovery.UnmatchedStyles) > 0 {
+		fmt.Println("Style files without matching table:")
 
-			if strings.Compare("y", strings.ToLower(ans)) == 0 || strings.Compare("yes", strings.ToLower(ans)) == 0 {
-				generalizedTableMap[tableName] = append(generalizedTableMap[tableName], "ignore")
-			}
+		for _, styleFile := range discovery.UnmatchedStyles {
+			fmt.Println("- " + styleFile)
 		}
 	}
 
-	newConf := config{pathToMapping, pathOutMapping, prefix, requiredColumnTypes, forceFiltering, allowResearch, toleranceScaling, tableMap, generalizedTableMap}
+	if len(discovery.UnstyledTables) > 0 {
+		fmt.Println("Tables without style file, which will not be changed:")
 
-	err := saveConfigFile(newConf)
-
-	if err != nil {
-		return err
+		for _, tableName := range discovery.UnstyledTables {
+			fmt.Println("- " + tableName)
+		}
 	}
 
-	return nil
+	if len(discovery.UnreadableDirectories) > 0 {
+		fmt.Println("Directories, which could not be read and could contain further style files:")
+
+		for _, directory := range discovery.UnreadableDirectories {
+			fmt.Println("- " + directory)
+		}
+	}
 }
 
 //ParseConfig parses the configuration file and saves it into a struct
 func ParseConfig(filePath string) error {
 
 	if !functions.FileExists(filePath) {
-		err := InitConfigFile()
-		if err != nil {
-			return err
-		}
+		return errors.New(`configuration file "` + filePath + `" not found, it is created by the command "init"`)
 	}
 
 	yamlFile, err := ioutil.ReadFile(filePath)
@@ -259,6 +404,7 @@ func ParseConfig(filePath string) error {
 	}
 
 	parsedConfig = root
+	configDirectory = filepath.Dir(filePath)
 	return nil
 }
 
@@ -0,0 +1,63 @@
+package configuration
+
+import (
+	"fmt"
+	"io"
+)
+
+//InitOptions contains the values of the initialization, which were given on the command line, the other values are
+//asked on the console. NoInput = nothing is asked, the other values are taken from an existing configuration file
+//and the style files are only discovered in the style directory
+type InitOptions struct {
+	MappingFilePath  string
+	MappingOutPath   string
+	MappingPrefix    string
+	AllowResearch    *bool
+	StyleDirectory   string
+	StyleFilePattern string
+	NoInput          bool
+}
+
+//input returns the value of the command line or asks for it on the console, an empty answer keeps the old value
+func (o *InitOptions) input(value string, question string, oldValue string) string {
+
+	if value != "" {
+		return value
+	}
+
+	if o.NoInput {
+		return oldValue
+	}
+
+	if oldValue != "" {
+		fmt.Print(question + " [" + oldValue + "]: ")
+	} else {
+		fmt.Print(question + ": ")
+	}
+
+	answer := o.readLine()
+
+	if answer == "" {
+		return oldValue
+	}
+
+	return answer
+}
+
+//readLine reads an answer of the console, nothing is asked anymore after the end of the input
+func (o *InitOptions) readLine() string {
+
+	if o.NoInput {
+		return ""
+	}
+
+	var answer string
+	_, err := fmt.Scanln(&answer)
+
+	if err == io.EOF {
+		fmt.Println()
+		o.NoInput = true
+	}
+
+	return answer
+}
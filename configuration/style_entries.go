@@ -0,0 +1,273 @@
+package configuration
+
+import (
+	functions "Imposm_Optimizer/std_functions"
+	styles "Imposm_Optimizer/style_reader"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//configDirectory is the directory of the parsed configuration file, relative style entries are resolved from it
+var configDirectory = "."
+
+//ResolveStyleEntries resolves the style entries of a table into style files. An entry is a file, a directory with
+//style files, a glob like "styles/roads/*.sld" or "styles/**/*.sld", or an exclusion like "!styles/roads/old_*.sld",
+//which removes matching files and directories of the other entries. Relative entries start at the directory of the
+//configuration file. The second list contains the entries without any style file and the entries, whose directories
+//could not be read completely
+func ResolveStyleEntries(entries []string) ([]string, []string) {
+
+	styleFiles := make([]string, 0)
+	unresolvedEntries := make([]string, 0)
+	exclusions := make([]string, 0)
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry, "!") {
+			exclusions = append(exclusions, resolvePath(entry[1:]))
+		}
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry, "!") {
+			continue
+		}
+
+		entryFiles := make([]string, 0)
+		entryPath := resolvePath(entry)
+		var readErr error
+
+		if isGlob(entry) {
+			var matches []string
+			matches, readErr = globFiles(entryPath)
+
+			for _, match := range matches {
+				if info, err := os.Stat(match); err == nil && info.IsDir() {
+					directoryStyleFiles, err := directoryFiles(match)
+					entryFiles = append(entryFiles, directoryStyleFiles...)
+
+					if readErr == nil {
+						readErr = err
+					}
+				} else if isStyleFile, err := styles.HasStyleContent(match); err != nil {
+					if readErr == nil {
+						readErr = err
+					}
+				} else if isStyleFile {
+					entryFiles = append(entryFiles, match)
+				}
+			}
+		} else if info, err := os.Stat(entryPath); err == nil {
+			if info.IsDir() {
+				entryFiles, readErr = directoryFiles(entryPath)
+			} else {
+				entryFiles = []string{entryPath}
+			}
+		}
+
+		//a directory, which cannot be read, could contain style files
+		if readErr != nil {
+			fmt.Println(`- style entry "` + entry + `" could not be read completely: ` + readErr.Error())
+		}
+
+		resolved := false
+
+		for _, styleFile := range entryFiles {
+			if isExcluded(styleFile, exclusions) {
+				continue
+			}
+
+			resolved = true
+
+			if !functions.StringInSlice(styleFile, styleFiles) {
+				styleFiles = append(styleFiles, styleFile)
+			}
+		}
+
+		if !resolved || readErr != nil {
+			unresolvedEntries = append(unresolvedEntries, entry)
+		}
+	}
+
+	return styleFiles, unresolvedEntries
+}
+
+//resolvePath returns the path of a relative entry from the directory of the configuration file
+func resolvePath(entry string) string {
+
+	if filepath.IsAbs(entry) {
+		return filepath.Clean(entry)
+	}
+
+	return filepath.Join(configDirectory, entry)
+}
+
+//directoryFiles returns all style files of a directory tree, hidden directories like ".git" are skipped.
+//The walk continues after a directory or file, which cannot be read, the first error is returned with the found files
+func directoryFiles(directory string) ([]string, error) {
+
+	styleFiles := make([]string, 0)
+	var readErr error
+
+	filepath.Walk(directory, func(filePath string, info os.FileInfo, err error) error {
+
+		if err != nil {
+			if readErr == nil {
+				readErr = err
+			}
+
+			return nil
+		}
+
+		if info.IsDir() {
+			if filePath != directory && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		isStyleFile, err := styles.HasStyleContent(filePath)
+
+		if err != nil && readErr == nil {
+			readErr = err
+		}
+
+		if isStyleFile {
+			styleFiles = append(styleFiles, filePath)
+		}
+
+		return nil
+	})
+
+	return styleFiles, readErr
+}
+
+//globFiles returns the files and directories matching a glob, "**" matches any number of directories except hidden ones.
+//Like directoryFiles, the first error of a directory, which cannot be read, is returned with the matches
+func globFiles(pattern string) ([]string, error) {
+
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	//the directories before the first wildcard are the root of the search
+	root := pattern[:strings.IndexAny(pattern, "*?[")]
+	root = root[:strings.LastIndex(root, string(filepath.Separator))+1]
+
+	if root == "" {
+		root = "."
+	}
+
+	matches := make([]string, 0)
+	var readErr error
+
+	filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
+
+		if err != nil {
+			if readErr == nil {
+				readErr = err
+			}
+
+			return nil
+		}
+
+		if info.IsDir() && filePath != root && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		if matchesGlob(pattern, filePath) {
+			matches = append(matches, filePath)
+		}
+
+		return nil
+	})
+
+	sort.Strings(matches)
+
+	return matches, readErr
+}
+
+//matchesGlob checks if a path matches a glob, "**" matches any number of directories
+func matchesGlob(pattern string, filePath string) bool {
+
+	if !strings.Contains(pattern, "**") {
+		matched, _ := filepath.Match(pattern, filePath)
+		return matched
+	}
+
+	globRegexp, err := regexp.Compile(globExpression(filepath.ToSlash(pattern)))
+
+	return err == nil && globRegexp.MatchString(filepath.ToSlash(filepath.Clean(filePath)))
+}
+
+//globExpression converts a glob into a regular expression
+func globExpression(pattern string) string {
+
+	var expression strings.Builder
+	expression.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expression.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expression.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expression.WriteString("[^/]")
+		case pattern[i] == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+
+			if end == -1 {
+				expression.WriteString(regexp.QuoteMeta(pattern[i:]))
+				i = len(pattern)
+				continue
+			}
+
+			class := pattern[i+1 : i+end]
+
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			expression.WriteString("[" + class + "]")
+			i += end
+		default:
+			expression.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	expression.WriteString("$")
+
+	return expression.String()
+}
+
+//isExcluded checks if a file matches one of the exclusions or is inside of an excluded directory
+func isExcluded(styleFile string, exclusions []string) bool {
+
+	for _, exclusion := range exclusions {
+		for parent := styleFile; ; parent = filepath.Dir(parent) {
+			if parent == exclusion || matchesGlob(exclusion, parent) {
+				return true
+			}
+
+			if filepath.Dir(parent) == parent {
+				break
+			}
+		}
+	}
+
+	return false
+}
+
+//isGlob checks if an entry contains a wildcard
+func isGlob(entry string) bool {
+	return strings.ContainsAny(entry, "*?[")
+}
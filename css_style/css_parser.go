@@ -0,0 +1,395 @@
+package css
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//zoomZeroScale is the scale denominator of zoom level 0 of the WebMercator grid set of GeoServer
+const zoomZeroScale = 559082264.029
+
+//symbolizerKinds maps the key properties, which create a symbolizer, to the symbolizer kinds of the sld package
+var symbolizerKinds = map[string]string{
+	"mark":            sld.PointKind,
+	"stroke":          sld.LineKind,
+	"fill":            sld.PolygonKind,
+	"label":           sld.TextKind,
+	"raster-channels": sld.RasterKind}
+
+//scaleSuffixes are the multipliers of scale values like "50k" or "1M"
+var scaleSuffixes = map[string]float64{"k": 1e3, "M": 1e6, "G": 1e9}
+
+//Parser class
+type Parser struct {
+	filePath          string
+	successfullPasing bool
+	cssRules          []cssRule
+	tableNames        []string
+	tablePrefix       string
+	usedLayers        []string
+}
+
+//cssRule is a rule of a stylesheet with all selectors of its parent rules
+type cssRule struct {
+	selector     selector
+	declarations []declaration
+}
+
+//New GeoServer CSS parser instance
+func New(filePath string) Parser {
+	s := Parser{filePath, false, nil, nil, "", make([]string, 0)}
+	return s
+}
+
+func (s *Parser) loadStyleFile() error {
+
+	if s.successfullPasing {
+		return nil
+	}
+
+	if filepath.Ext(s.filePath) != ".css" {
+		return errors.New(`"` + s.filePath + `" must be a .css file`)
+	}
+
+	text, err := ioutil.ReadFile(s.filePath)
+
+	if err != nil {
+		return err
+	}
+
+	blocks, err := parseStylesheet(string(text))
+
+	if err != nil {
+		return errors.New(`"` + s.filePath + `": ` + err.Error())
+	}
+
+	s.cssRules = flattenBlocks(blocks, []selector{{}})
+	s.successfullPasing = true
+
+	return nil
+}
+
+//flattenBlocks combines the selectors of nested rules with the selectors of their parents
+func flattenBlocks(blocks []block, parents []selector) []cssRule {
+
+	cssRules := make([]cssRule, 0)
+
+	for _, current := range blocks {
+		combined := make([]selector, 0)
+
+		for _, parent := range parents {
+			for _, child := range current.selectors {
+				combined = append(combined, parent.combine(child))
+			}
+		}
+
+		if len(current.declarations) > 0 {
+			for _, combinedSelector := range combined {
+				cssRules = append(cssRules, cssRule{combinedSelector, current.declarations})
+			}
+		}
+
+		cssRules = append(cssRules, flattenBlocks(current.children, combined)...)
+	}
+
+	return cssRules
+}
+
+//SetTableNames restricts the extracted requirements to the rules, whose type names match one of the tables.
+//The first table matching at least one type name is used, rules without type name are always used.
+//A stylesheet with a single type name is used completely, if no type name matches. Otherwise only the rules
+//without type name are used
+func (s *Parser) SetTableNames(tableNames []string, tablePrefix string) {
+	s.tableNames = tableNames
+	s.tablePrefix = tablePrefix
+}
+
+//LayerNames returns the type names of all rules
+func (s *Parser) LayerNames() ([]string, error) {
+
+	err := s.loadStyleFile()
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+
+	for _, rule := range s.cssRules {
+		for _, typeName := range rule.selector.typeNames {
+			if !functions.StringInSlice(typeName, names) {
+				names = append(names, typeName)
+			}
+		}
+	}
+
+	return names, nil
+}
+
+//UsedLayers returns the type names, which were used for the requirements of the table.
+//The list is empty, if the whole file was used
+func (s *Parser) UsedLayers() []string {
+	return s.usedLayers
+}
+
+//GetFilePath returns the path to the style file
+func (s *Parser) GetFilePath() string {
+	return s.filePath
+}
+
+//ExtractRequirements ( mappingColumns ) will return all required values of the CSS rules in form of a ParsedSLD structure.
+//Every rule with properties is handled like a SLD rule, the scale and zoom conditions are converted into scale denominators
+func (s *Parser) ExtractRequirements(mappingColumns sld.MappingColumnNames) (sld.ParsedSLD, error) {
+
+	err := s.loadStyleFile()
+
+	if err != nil {
+		return sld.ParsedSLD{}, err
+	}
+
+	requirements := sld.NewTableRequirements(mappingColumns)
+	ruleList := make([]sld.Rule, 0)
+
+	for _, cssRule := range s.selectedRules() {
+		if rule, ok := convertRule(cssRule, &requirements); ok {
+			ruleList = append(ruleList, rule)
+		}
+	}
+
+	scaleDenominator, useAllMappingTypes := sld.CompleteRequirements(ruleList, &requirements)
+
+	return sld.ParsedSLD{
+		FileName:           s.filePath,
+		Requirements:       requirements,
+		Scale:              scaleDenominator,
+		UseAllMappingTypes: useAllMappingTypes,
+		Version:            "GeoServer CSS"}, nil
+}
+
+//selectedRules returns the rules of the first table, which matches a type name, and all rules without type name.
+//Without matching table only a stylesheet with a single type name is used completely
+func (s *Parser) selectedRules() []cssRule {
+
+	s.usedLayers = make([]string, 0)
+
+	for _, tableName := range s.tableNames {
+		cssRules := make([]cssRule, 0)
+		matched := false
+
+		for _, rule := range s.cssRules {
+			matchesAll := true
+
+			for _, typeName := range rule.selector.typeNames {
+				matchesAll = matchesAll && sld.MatchesTable(typeName, tableName, s.tablePrefix)
+			}
+
+			switch {
+			case len(rule.selector.typeNames) == 0:
+				cssRules = append(cssRules, rule)
+			case matchesAll:
+				cssRules = append(cssRules, rule)
+				matched = true
+
+				for _, typeName := range rule.selector.typeNames {
+					if !functions.StringInSlice(typeName, s.usedLayers) {
+						s.usedLayers = append(s.usedLayers, typeName)
+					}
+				}
+			}
+		}
+
+		if matched {
+			return cssRules
+		}
+	}
+
+	if names, _ := s.LayerNames(); len(names) <= 1 {
+		return s.cssRules
+	}
+
+	fmt.Println(`WARNING: no type name of the style file "`+s.filePath+`" matches the tables`, s.tableNames)
+
+	cssRules := make([]cssRule, 0)
+
+	for _, rule := range s.cssRules {
+		if len(rule.selector.typeNames) == 0 {
+			cssRules = append(cssRules, rule)
+		}
+	}
+
+	return cssRules
+}
+
+//convertRule converts a CSS rule into a SLD rule and adds the columns of its filters and properties to the requirements.
+//False is returned, if the rule is never drawn
+func convertRule(cssRule cssRule, requirements *sld.TableRequirements) (sld.Rule, bool) {
+
+	rule := sld.Rule{}
+	minScale, maxScale := 0.0, math.Inf(1)
+
+	for _, scaleCondition := range cssRule.selector.scales {
+		minScale, maxScale = restrictScale(scaleCondition, minScale, maxScale)
+	}
+
+	if minScale >= maxScale {
+		return sld.Rule{}, false
+	}
+
+	rule.MinScale = int(math.Round(minScale))
+
+	if !math.IsInf(maxScale, 1) {
+		rule.MaxScale = int(math.Round(maxScale))
+	}
+
+	scale := sld.ScaleDenominator{MinScaleDenominator: rule.MinScale, MaxScaleDenominator: rule.MaxScale}
+
+	if rule.MaxScale == 0 {
+		scale.MaxScaleDenominator = -2
+	}
+
+	filters := make([]sld.FilterNode, 0)
+
+	for _, filterText := range cssRule.selector.filters {
+		filter, err := sld.ParseCQLFilter(filterText)
+
+		if err != nil {
+			fmt.Println("Parsing Error: " + err.Error())
+			filter = sld.UnknownFilter{Name: "Filter"}
+		}
+
+		filters = append(filters, filter)
+
+		for _, column := range sld.CQLPropertyNames(filterText) {
+			sld.AddRequiredColumn(requirements, column, []string{}, scale, sld.FilterUsage)
+		}
+	}
+
+	if len(cssRule.selector.featureIDs) > 0 {
+		filters = append(filters, sld.FeatureIDFilter{IDs: cssRule.selector.featureIDs})
+	}
+
+	switch len(filters) {
+	case 0:
+	case 1:
+		rule.FilterTree = filters[0]
+	default:
+		rule.FilterTree = sld.LogicalFilter{Name: "And", Children: filters}
+	}
+
+	if len(cssRule.selector.filters) > 0 {
+		rule.Filter = sld.Filter{XMLContent: []byte(strings.Join(cssRule.selector.filters, " AND "))}
+	}
+
+	for _, property := range cssRule.declarations {
+		symbolizer := []sld.Symbolizer{{XMLContent: []byte(property.value), UnitOfMeasure: sld.PixelUnit}}
+
+		//properties of pseudo classes like ":mark" style the symbols of other rules
+		if kind, isKeyProperty := symbolizerKinds[property.property]; isKeyProperty && cssRule.selector.pseudoClass == "" {
+			switch kind {
+			case sld.PointKind:
+				rule.PointSymbolizer = symbolizer
+			case sld.LineKind:
+				rule.LineSymbolizer = symbolizer
+			case sld.PolygonKind:
+				rule.PolygonSymbolizer = symbolizer
+			case sld.TextKind:
+				rule.TextSymbolizer = symbolizer
+			case sld.RasterKind:
+				rule.RasterSymbolizer = symbolizer
+			}
+		}
+
+		if property.property == "sort-by" {
+			for _, column := range sld.SortByColumns(strings.Trim(property.value, `"'`)) {
+				sld.AddRequiredColumn(requirements, column, []string{}, scale, sld.SortUsage)
+			}
+
+			continue
+		}
+
+		usage := sld.StyleUsage
+
+		if property.property == "label" {
+			usage = sld.LabelUsage
+		}
+
+		for _, expression := range bracketExpressions(property.value) {
+			for _, column := range sld.CQLPropertyNames(expression) {
+				sld.AddRequiredColumn(requirements, column, []string{}, scale, usage)
+			}
+		}
+	}
+
+	return rule, true
+}
+
+//restrictScale restricts the scale range by a condition like "@scale < 50000", "@sd > 1M" or "@z >= 12"
+func restrictScale(scaleCondition condition, minScale float64, maxScale float64) (float64, float64) {
+
+	value, err := parseScaleValue(scaleCondition.value)
+
+	if err != nil {
+		fmt.Println("Parsing Error: invalid scale condition [@" + scaleCondition.field + " " + scaleCondition.operator + " " + scaleCondition.value + "]")
+		return minScale, maxScale
+	}
+
+	switch scaleCondition.field {
+	case "scale", "sd":
+		switch scaleCondition.operator {
+		case ">", ">=":
+			minScale = math.Max(minScale, value)
+		case "<", "<=":
+			maxScale = math.Min(maxScale, value)
+		}
+
+	case "z":
+		//the zoom levels are separated in the middle between their scale denominators
+		switch scaleCondition.operator {
+		case ">":
+			maxScale = math.Min(maxScale, zoomScale(value+0.5))
+		case ">=":
+			maxScale = math.Min(maxScale, zoomScale(value-0.5))
+		case "<":
+			minScale = math.Max(minScale, zoomScale(value-0.5))
+		case "<=":
+			minScale = math.Max(minScale, zoomScale(value+0.5))
+		case "=":
+			minScale = math.Max(minScale, zoomScale(value+0.5))
+			maxScale = math.Min(maxScale, zoomScale(value-0.5))
+		}
+
+	default:
+		fmt.Println(`Parsing Error: unknown scale condition "@` + scaleCondition.field + `"`)
+	}
+
+	return minScale, maxScale
+}
+
+//parseScaleValue converts a scale value with an optional suffix like "50k" or "1M"
+func parseScaleValue(text string) (float64, error) {
+
+	multiplier := 1.0
+
+	if len(text) > 0 {
+		if suffixMultiplier, hasSuffix := scaleSuffixes[text[len(text)-1:]]; hasSuffix {
+			multiplier = suffixMultiplier
+			text = text[:len(text)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+
+	return value * multiplier, err
+}
+
+//zoomScale converts a zoom level of the WebMercator grid set into a scale denominator
+func zoomScale(zoom float64) float64 {
+	return zoomZeroScale / math.Pow(2, zoom)
+}
@@ -0,0 +1,48 @@
+package css
+
+//########### GeoServer CSS structures ###########//
+
+//block is a rule of a stylesheet with its selectors, declarations and nested rules
+type block struct {
+	selectors    []selector
+	declarations []declaration
+	children     []block
+}
+
+//selector of a rule like "roads[type = 'primary'][@scale < 50000]:mark"
+//typeNames = feature type names, "*" matches all features
+//filters = CQL filters in brackets, scales = scale and zoom conditions like "@scale < 50000"
+//pseudoClass = the rule styles the marks or strokes of another rule like ":mark" or ":nth-stroke(1)"
+type selector struct {
+	typeNames   []string
+	featureIDs  []string
+	filters     []string
+	scales      []condition
+	pseudoClass string
+}
+
+//condition is a scale or zoom condition of a selector like "@scale < 50000"
+type condition struct {
+	field    string
+	operator string
+	value    string
+}
+
+//declaration is a property of a rule like "stroke-width: 2"
+type declaration struct {
+	property string
+	value    string
+}
+
+//combine returns the selector of a nested rule, which contains the conditions of its parent
+func (s selector) combine(child selector) selector {
+
+	combined := selector{
+		append(append([]string{}, s.typeNames...), child.typeNames...),
+		append(append([]string{}, s.featureIDs...), child.featureIDs...),
+		append(append([]string{}, s.filters...), child.filters...),
+		append(append([]condition{}, s.scales...), child.scales...),
+		s.pseudoClass + child.pseudoClass}
+
+	return combined
+}
@@ -0,0 +1,242 @@
+package css
+
+import (
+	functions "Imposm_Optimizer/std_functions"
+	scanner "Imposm_Optimizer/stylesheet_scanner"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+//scaleOperators of the scale and zoom conditions, longer operators have to be tested first
+var scaleOperators = []string{">=", "<=", "=", ">", "<"}
+
+//scaleFields are the fields of scale and zoom conditions, they can be written with or without "@"
+var scaleFields = []string{"scale", "sd", "z"}
+
+//pseudoClasses style the marks, strokes, fills, symbols and shields of other rules
+var pseudoClasses = []string{"mark", "stroke", "fill", "symbol", "shield", "nth-mark", "nth-stroke", "nth-fill", "nth-symbol", "nth-shield"}
+
+//parseStylesheet returns the rules of a stylesheet, directives like "@mode 'Flat';" are skipped
+func parseStylesheet(text string) ([]block, error) {
+
+	scannedBlocks, err := scanner.Parse(text, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return convertBlocks(scannedBlocks)
+}
+
+//convertBlocks parses the selectors and declarations of the scanned blocks
+func convertBlocks(scannedBlocks []scanner.Block) ([]block, error) {
+
+	blocks := make([]block, 0, len(scannedBlocks))
+
+	for _, scannedBlock := range scannedBlocks {
+		selectors, err := parseSelectors(scannedBlock.Selectors)
+
+		if err != nil {
+			return nil, err
+		}
+
+		current := block{selectors, make([]declaration, 0), nil}
+
+		for _, statement := range scannedBlock.Statements {
+			if index := strings.Index(statement, ":"); index != -1 && !strings.HasPrefix(statement, "@") {
+				current.declarations = append(current.declarations, declaration{strings.TrimSpace(statement[:index]), strings.TrimSpace(statement[index+1:])})
+			}
+		}
+
+		current.children, err = convertBlocks(scannedBlock.Children)
+
+		if err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, current)
+	}
+
+	return blocks, nil
+}
+
+//parseSelectors parses a comma separated list of selectors
+func parseSelectors(text string) ([]selector, error) {
+
+	selectors := make([]selector, 0)
+
+	for _, part := range scanner.SplitOutside(text, ',') {
+		parsed, err := parseSelector(strings.TrimSpace(part))
+
+		if err != nil {
+			return nil, err
+		}
+
+		selectors = append(selectors, parsed)
+	}
+
+	return selectors, nil
+}
+
+//parseSelector parses the type names, feature ids, filters and pseudo classes of a selector.
+//All parts of a selector have to match
+func parseSelector(text string) (selector, error) {
+
+	parsed := selector{}
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r) || r == '*':
+			i++
+
+		case r == '#':
+			end := i + 1
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("[:,", runes[end]) {
+				end++
+			}
+
+			parsed.featureIDs = append(parsed.featureIDs, string(runes[i+1:end]))
+			i = end
+
+		case r == ':':
+			name, end := scanner.ReadName(runes, i+1)
+
+			//pseudo classes like ":nth-mark(1)" select the symbol by its index
+			if end < len(runes) && runes[end] == '(' {
+				for end < len(runes) && runes[end] != ')' {
+					end++
+				}
+				end++
+			}
+
+			if name == "" {
+				return selector{}, errors.New(`unexpected ":" in selector "` + text + `"`)
+			}
+
+			parsed.pseudoClass += ":" + name
+			i = end
+
+		case r == '[':
+			end := i + 1
+			depth := 1
+
+			for end < len(runes) {
+				if runes[end] == '"' || runes[end] == '\'' {
+					end = scanner.ClosingQuote(runes, end)
+					continue
+				}
+
+				if runes[end] == '[' {
+					depth++
+				} else if runes[end] == ']' {
+					depth--
+
+					if depth == 0 {
+						break
+					}
+				}
+				end++
+			}
+
+			if end >= len(runes) {
+				return selector{}, errors.New(`missing "]" in selector "` + text + `"`)
+			}
+
+			content := strings.TrimSpace(string(runes[i+1 : end]))
+
+			if isScaleCondition(content) {
+				parsedCondition, err := parseCondition(content)
+
+				if err != nil {
+					return selector{}, err
+				}
+
+				parsed.scales = append(parsed.scales, parsedCondition)
+			} else {
+				parsed.filters = append(parsed.filters, content)
+			}
+
+			i = end + 1
+
+		default:
+			name, end := scanner.ReadName(runes, i)
+
+			if end == i {
+				return selector{}, errors.New(`unexpected "` + string(r) + `" in selector "` + text + `"`)
+			}
+
+			//type names can have a workspace prefix like "osm:roads"
+			if end < len(runes) && runes[end] == ':' {
+				if localName, localEnd := scanner.ReadName(runes, end+1); localName != "" && !functions.StringInSlice(localName, pseudoClasses) {
+					name, end = name+":"+localName, localEnd
+				}
+			}
+
+			parsed.typeNames = append(parsed.typeNames, name)
+			i = end
+		}
+	}
+
+	return parsed, nil
+}
+
+//isScaleCondition checks if the content of brackets is a scale or zoom condition like "@scale < 50000" or "z >= 12".
+//All other conditions are filters, even if they compare a column with the name of a scale field
+func isScaleCondition(text string) bool {
+
+	if strings.HasPrefix(text, "@") {
+		return true
+	}
+
+	runes := []rune(text)
+	name, end := scanner.ReadName(runes, 0)
+	rest := strings.TrimSpace(string(runes[end:]))
+
+	return functions.StringInSlice(name, scaleFields) && rest != "" && strings.ContainsRune("<>=", rune(rest[0]))
+}
+
+//parseCondition parses a scale or zoom condition like "@scale < 50000" or "@z >= 12"
+func parseCondition(text string) (condition, error) {
+
+	for i := range text {
+		for _, operator := range scaleOperators {
+			if strings.HasPrefix(text[i:], operator) {
+				return condition{strings.TrimSpace(strings.TrimPrefix(text[:i], "@")), operator, strings.TrimSpace(text[i+len(operator):])}, nil
+			}
+		}
+	}
+
+	return condition{}, errors.New(`invalid condition "[` + text + `]"`)
+}
+
+//bracketExpressions returns the CQL expressions in brackets of a property value like "[lanes * 2]"
+func bracketExpressions(value string) []string {
+
+	expressions := make([]string, 0)
+	runes := []rune(value)
+
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '"' || runes[i] == '\'':
+			i = scanner.ClosingQuote(runes, i) - 1
+		case runes[i] == '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				if runes[end] == '"' || runes[end] == '\'' {
+					end = scanner.ClosingQuote(runes, end)
+					continue
+				}
+				end++
+			}
+
+			expressions = append(expressions, string(runes[i+1:end]))
+			i = end
+		}
+	}
+
+	return expressions
+}
@@ -0,0 +1,547 @@
+package geoserver
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//Settings contains the connection to the REST API of a GeoServer.
+//URL = base URL of the GeoServer like "http://localhost:8080/geoserver"
+//Workspace = only the layers and layer groups of this workspace are used, all if empty
+//StyleDirectory = directory of the downloaded styles, the default is "geoserver_styles"
+type Settings struct {
+	URL            string `json:"url"`
+	User           string `json:"user,omitempty"`
+	Password       string `json:"password,omitempty"`
+	Workspace      string `json:"workspace,omitempty"`
+	StyleDirectory string `json:"style_directory,omitempty"`
+}
+
+//Client requests the layers, layer groups and styles of a GeoServer
+type Client struct {
+	settings   Settings
+	httpClient *http.Client
+	layers     map[string]layerStyles
+	styleFiles map[string]string
+	stores     map[string]bool
+}
+
+//layerStyles contains the table and the styles of a layer, the table is empty for layers without PostGIS table.
+//Layers of other stores like shapefiles, coverages and cascaded WMS layers have no table
+type layerStyles struct {
+	table  string
+	styles []reference
+}
+
+//New GeoServer client instance, the HTTP client can be replaced for requests to a local stand-in of the GeoServer
+func New(settings Settings, httpClient *http.Client) *Client {
+
+	if settings.StyleDirectory == "" {
+		settings.StyleDirectory = "geoserver_styles"
+	}
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{settings, httpClient, make(map[string]layerStyles), make(map[string]string), make(map[string]bool)}
+}
+
+//TableStyles downloads the default and alternate styles of all layers and the styles of the layer groups.
+//It returns the SLD files of every table, which is the PostGIS table of a layer
+func (c *Client) TableStyles(tableNames []string, tablePrefix string) (map[string][]string, error) {
+
+	if c.settings.URL == "" {
+		return nil, errors.New("the URL of the GeoServer is missing")
+	}
+
+	err := os.MkdirAll(c.settings.StyleDirectory, 0755)
+
+	if err != nil {
+		return nil, err
+	}
+
+	layerNames, err := c.layerNames()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layerName := range layerNames {
+		_, err := c.layer(layerName)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//layer groups can draw their layers with other styles
+	groupStyles, err := c.layerGroupStyles()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for layerName, styles := range groupStyles {
+		styledLayer, err := c.layer(layerName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, style := range styles {
+			if !containsStyle(styledLayer.styles, style) {
+				styledLayer.styles = append(styledLayer.styles, style)
+			}
+		}
+
+		c.layers[layerName] = styledLayer
+	}
+
+	tableStyles := make(map[string][]string)
+
+	for _, layerName := range sortedLayerNames(c.layers) {
+		styledLayer := c.layers[layerName]
+
+		if styledLayer.table == "" {
+			continue
+		}
+
+		matchingTables := make([]string, 0)
+
+		for _, tableName := range tableNames {
+			if sld.MatchesTable(styledLayer.table, tableName, tablePrefix) {
+				matchingTables = append(matchingTables, tableName)
+			}
+		}
+
+		if len(matchingTables) == 0 {
+			fmt.Println(`WARNING: the table "` + styledLayer.table + `" of the GeoServer layer "` + layerName + `" is no table of the mapping`)
+			continue
+		}
+
+		styleNames := make([]string, 0)
+
+		for _, style := range styledLayer.styles {
+			styleFile, err := c.styleFile(style)
+
+			if err != nil {
+				return nil, err
+			}
+
+			styleNames = append(styleNames, styleName(style))
+
+			for _, tableName := range matchingTables {
+				if !functions.StringInSlice(styleFile, tableStyles[tableName]) {
+					tableStyles[tableName] = append(tableStyles[tableName], styleFile)
+				}
+			}
+		}
+
+		fmt.Println("- GeoServer layer "+layerName+" ("+styledLayer.table+"):", styleNames)
+	}
+
+	return tableStyles, nil
+}
+
+//layerNames returns the names of all layers or of the layers of the workspace
+func (c *Client) layerNames() ([]string, error) {
+
+	response := layersResponse{}
+	err := c.getJSON(c.workspacePath("layers.json"), &response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+
+	for _, entry := range response.Layers["layer"] {
+		names = append(names, c.qualifiedName(entry.Name))
+	}
+
+	return names, nil
+}
+
+//layer requests the styles of a layer and the table of its feature type
+func (c *Client) layer(name string) (layerStyles, error) {
+
+	if styledLayer, known := c.layers[name]; known {
+		return styledLayer, nil
+	}
+
+	response := layerResponse{}
+	err := c.getJSON("layers/"+url.PathEscape(name)+".json", &response)
+
+	if err != nil {
+		return layerStyles{}, err
+	}
+
+	styledLayer := layerStyles{"", make([]reference, 0)}
+
+	if response.Layer.DefaultStyle.Name != "" {
+		styledLayer.styles = append(styledLayer.styles, response.Layer.DefaultStyle)
+	}
+
+	for _, style := range response.Layer.Styles["style"] {
+		if style.Name != "" && !containsStyle(styledLayer.styles, style) {
+			styledLayer.styles = append(styledLayer.styles, style)
+		}
+	}
+
+	//only feature types have a table, coverages and cascaded WMS layers are skipped
+	resource := response.Layer.Resource
+
+	if resource.Class == "" || resource.Class == "featureType" {
+		styledLayer.table, err = c.featureTypeTable(resource)
+
+		if err != nil {
+			return layerStyles{}, err
+		}
+	}
+
+	c.layers[name] = styledLayer
+
+	return styledLayer, nil
+}
+
+//featureTypeTable returns the native name of a feature type, which is the name of its table.
+//The name is empty, if the feature type is not stored in a PostGIS database
+func (c *Client) featureTypeTable(resource reference) (string, error) {
+
+	workspace, name := splitName(resource.Name)
+
+	if workspace == "" {
+		workspace = c.settings.Workspace
+	}
+
+	response := featureTypeResponse{}
+	err := c.getJSON(resourcePath(resource.Href, "workspaces/"+url.PathEscape(workspace)+"/featuretypes/"+url.PathEscape(name)+".json"), &response)
+
+	if err != nil {
+		return "", err
+	}
+
+	//older GeoServer versions do not link the store, its feature types are assumed to be tables
+	if response.FeatureType.Store.Name != "" {
+		isPostGIS, err := c.isPostGISStore(response.FeatureType.Store, workspace)
+
+		if err != nil {
+			return "", err
+		}
+
+		if !isPostGIS {
+			fmt.Println(`- GeoServer feature type "` + resource.Name + `" is skipped, its store "` + response.FeatureType.Store.Name + `" is no PostGIS database`)
+			return "", nil
+		}
+	}
+
+	if response.FeatureType.NativeName != "" {
+		return response.FeatureType.NativeName, nil
+	}
+
+	return response.FeatureType.Name, nil
+}
+
+//isPostGISStore checks if a data store is a PostGIS database, every store is requested once
+func (c *Client) isPostGISStore(store reference, workspace string) (bool, error) {
+
+	storeWorkspace, name := splitName(store.Name)
+
+	if storeWorkspace != "" {
+		workspace = storeWorkspace
+	}
+
+	storePath := resourcePath(store.Href, "workspaces/"+url.PathEscape(workspace)+"/datastores/"+url.PathEscape(name)+".json")
+
+	if isPostGIS, known := c.stores[storePath]; known {
+		return isPostGIS, nil
+	}
+
+	response := dataStoreResponse{}
+	err := c.getJSON(storePath, &response)
+
+	if err != nil {
+		return false, err
+	}
+
+	//the type is "PostGIS" or "PostGIS (JNDI)", the parameter dbtype is "postgis" for both
+	isPostGIS := strings.HasPrefix(strings.ToLower(response.DataStore.Type), "postgis")
+
+	for _, entry := range response.DataStore.ConnectionParameters.Entry {
+		if entry.Key == "dbtype" {
+			isPostGIS = strings.EqualFold(entry.Value, "postgis")
+		}
+	}
+
+	c.stores[storePath] = isPostGIS
+
+	return isPostGIS, nil
+}
+
+//layerGroupStyles returns the styles, which the layer groups use for their layers.
+//The default styles of the layers are not contained
+func (c *Client) layerGroupStyles() (map[string][]reference, error) {
+
+	response := layerGroupsResponse{}
+	err := c.getJSON(c.workspacePath("layergroups.json"), &response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	groupStyles := make(map[string][]reference)
+	visitedGroups := make(map[string]bool)
+
+	for _, entry := range response.LayerGroups["layerGroup"] {
+		err := c.addLayerGroupStyles(resourcePath(entry.Href, c.workspacePath("layergroups/"+url.PathEscape(entry.Name)+".json")), groupStyles, visitedGroups)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return groupStyles, nil
+}
+
+//addLayerGroupStyles adds the styles of the published layers of a layer group, nested layer groups are added recursively
+func (c *Client) addLayerGroupStyles(groupPath string, groupStyles map[string][]reference, visitedGroups map[string]bool) error {
+
+	if visitedGroups[groupPath] {
+		return nil
+	}
+
+	visitedGroups[groupPath] = true
+
+	response := layerGroupResponse{}
+	err := c.getJSON(groupPath, &response)
+
+	if err != nil {
+		return err
+	}
+
+	styles := response.LayerGroup.Styles["style"]
+
+	for index, published := range response.LayerGroup.Publishables["published"] {
+		if published.Type == "layerGroup" {
+			workspace, name := splitName(published.Name)
+			groupPath := "layergroups/" + url.PathEscape(name) + ".json"
+
+			if workspace != "" {
+				groupPath = "workspaces/" + url.PathEscape(workspace) + "/" + groupPath
+			}
+
+			err := c.addLayerGroupStyles(resourcePath(published.Href, groupPath), groupStyles, visitedGroups)
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		//an empty style is the default style of the layer
+		if index >= len(styles) || styles[index].Name == "" {
+			continue
+		}
+
+		layerName := c.qualifiedName(published.Name)
+
+		if !containsStyle(groupStyles[layerName], styles[index]) {
+			groupStyles[layerName] = append(groupStyles[layerName], styles[index])
+		}
+	}
+
+	return nil
+}
+
+//styleFile downloads a style as SLD and returns the path of the file, every style is downloaded once
+func (c *Client) styleFile(style reference) (string, error) {
+
+	workspace, name := splitName(style.Name)
+
+	if workspace == "" {
+		workspace = style.Workspace
+	}
+
+	stylePath := "styles/" + url.PathEscape(name) + ".sld"
+	fileName := name + ".sld"
+
+	if workspace != "" {
+		stylePath = "workspaces/" + url.PathEscape(workspace) + "/" + stylePath
+		fileName = workspace + "_" + fileName
+	}
+
+	//GeoServer converts CSS and YSLD styles into SLD
+	if style.Href != "" {
+		stylePath = strings.TrimSuffix(resourcePath(style.Href, stylePath), ".json") + ".sld"
+	}
+
+	if styleFile, known := c.styleFiles[stylePath]; known {
+		return styleFile, nil
+	}
+
+	content, err := c.get(stylePath, "application/vnd.ogc.sld+xml")
+
+	if err != nil {
+		return "", err
+	}
+
+	styleFile := filepath.Join(c.settings.StyleDirectory, strings.NewReplacer("/", "_", `\`, "_", ":", "_").Replace(fileName))
+	err = ioutil.WriteFile(styleFile, content, 0666)
+
+	if err != nil {
+		return "", err
+	}
+
+	c.styleFiles[stylePath] = styleFile
+
+	return styleFile, nil
+}
+
+//getJSON requests a path of the REST API and decodes the JSON response
+func (c *Client) getJSON(restPath string, target interface{}) error {
+
+	content, err := c.get(restPath, "application/json")
+
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(content, target)
+
+	if err != nil {
+		return errors.New(`invalid response of "` + restPath + `": ` + err.Error())
+	}
+
+	return nil
+}
+
+//get requests a path of the REST API like "layers.json"
+func (c *Client) get(restPath string, contentType string) ([]byte, error) {
+
+	request, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(c.settings.URL, "/")+"/rest/"+restPath, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Accept", contentType)
+
+	if c.settings.User != "" {
+		request.SetBasicAuth(c.settings.User, c.settings.Password)
+	}
+
+	response, err := c.httpClient.Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	content, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(`request of "` + restPath + `" failed: ` + response.Status)
+	}
+
+	return content, nil
+}
+
+//workspacePath returns the path of a resource in the workspace of the settings
+func (c *Client) workspacePath(restPath string) string {
+
+	if c.settings.Workspace == "" {
+		return restPath
+	}
+
+	return "workspaces/" + url.PathEscape(c.settings.Workspace) + "/" + restPath
+}
+
+//qualifiedName adds the workspace of the settings to a layer name without workspace
+func (c *Client) qualifiedName(name string) string {
+
+	if c.settings.Workspace == "" || strings.Contains(name, ":") {
+		return name
+	}
+
+	return c.settings.Workspace + ":" + name
+}
+
+//resourcePath returns the path of a link relative to the REST API. The links are not requested directly,
+//because GeoServer writes them with its proxy base URL. Without link the fallback path is used
+func resourcePath(href string, fallback string) string {
+
+	index := strings.Index(href, "/rest/")
+
+	if index == -1 {
+		return fallback
+	}
+
+	if parsedURL, err := url.Parse(href[index:]); err == nil {
+		return strings.TrimPrefix(path.Clean(parsedURL.EscapedPath()), "/rest/")
+	}
+
+	return fallback
+}
+
+//splitName splits a name like "osm:roads" into its workspace and local name
+func splitName(name string) (string, string) {
+
+	if index := strings.Index(name, ":"); index != -1 {
+		return name[:index], name[index+1:]
+	}
+
+	return "", name
+}
+
+func sortedLayerNames(layers map[string]layerStyles) []string {
+
+	names := make([]string, 0, len(layers))
+
+	for name := range layers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+//styleName returns the name of a style with its workspace like "osm:roads"
+func styleName(style reference) string {
+
+	workspace, name := splitName(style.Name)
+
+	if workspace == "" && style.Workspace != "" {
+		return style.Workspace + ":" + name
+	}
+
+	return style.Name
+}
+
+func containsStyle(styles []reference, style reference) bool {
+	for _, entry := range styles {
+		if styleName(entry) == styleName(style) {
+			return true
+		}
+	}
+
+	return false
+}
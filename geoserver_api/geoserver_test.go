@@ -0,0 +1,188 @@
+package geoserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//recordedServer is a local stand-in of a GeoServer, which serves the recorded REST responses of testdata/rest.
+//The colon of qualified names like "osm:roads" is replaced by an underscore in the file names
+type recordedServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests map[string]int
+}
+
+func newRecordedServer(t *testing.T) *recordedServer {
+
+	server := &recordedServer{requests: make(map[string]int)}
+
+	server.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+
+		user, password, ok := request.BasicAuth()
+
+		if !ok || user != "admin" || password != "geoserver" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		restPath := strings.TrimPrefix(request.URL.Path, "/geoserver/rest/")
+
+		server.mutex.Lock()
+		server.requests[restPath]++
+		server.mutex.Unlock()
+
+		//styles are requested as SLD, all other resources as JSON
+		accept := "application/json"
+
+		if strings.HasSuffix(restPath, ".sld") {
+			accept = "application/vnd.ogc.sld+xml"
+		}
+
+		if request.Header.Get("Accept") != accept {
+			t.Errorf(`request of "%s" accepts "%s", expected "%s"`, restPath, request.Header.Get("Accept"), accept)
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join("testdata", "rest", filepath.FromSlash(strings.ReplaceAll(restPath, ":", "_"))))
+
+		if err != nil {
+			http.NotFound(writer, request)
+			return
+		}
+
+		writer.Write(content)
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func (s *recordedServer) settings(t *testing.T, workspace string) Settings {
+	return Settings{
+		URL:            s.URL + "/geoserver/",
+		User:           "admin",
+		Password:       "geoserver",
+		Workspace:      workspace,
+		StyleDirectory: t.TempDir()}
+}
+
+func TestTableStyles(t *testing.T) {
+
+	server := newRecordedServer(t)
+	settings := server.settings(t, "")
+
+	tableStyles, err := New(settings, server.Client()).TableStyles([]string{"roads", "places", "water", "coastline"}, "osm_")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	styleFile := func(name string) string {
+		return filepath.Join(settings.StyleDirectory, name)
+	}
+
+	//the default style comes first, followed by the alternate styles and the styles of the layer groups.
+	//The coastline is a shapefile and the hillshade a coverage, both have no table
+	expected := map[string][]string{
+		"roads":  {styleFile("osm_roads_style.sld"), styleFile("osm_roads_night.sld"), styleFile("osm_roads_gen.sld")},
+		"places": {styleFile("places.sld"), styleFile("places_labels.sld")},
+		"water":  {styleFile("polygon.sld")}}
+
+	if !reflect.DeepEqual(tableStyles, expected) {
+		t.Errorf("table styles %v, expected %v", tableStyles, expected)
+	}
+
+	//the downloaded files contain the recorded styles
+	for recordedFile, downloadedFile := range map[string]string{
+		"workspaces/osm/styles/roads_gen.sld": "osm_roads_gen.sld",
+		"styles/places_labels.sld":            "places_labels.sld"} {
+
+		recorded, _ := ioutil.ReadFile(filepath.Join("testdata", "rest", filepath.FromSlash(recordedFile)))
+		downloaded, err := ioutil.ReadFile(styleFile(downloadedFile))
+
+		if err != nil || string(recorded) != string(downloaded) {
+			t.Errorf(`"%s" does not contain the recorded style "%s": %v`, downloadedFile, recordedFile, err)
+		}
+	}
+
+	//styles, which are used by several layers or layer groups, and data stores are requested once
+	for _, restPath := range []string{"styles/places_labels.sld", "workspaces/osm/datastores/pg.json"} {
+		if server.requests[restPath] != 1 {
+			t.Errorf(`"%s" was requested %d times, expected once`, restPath, server.requests[restPath])
+		}
+	}
+
+	//styles of layers without table are not downloaded
+	for _, restPath := range []string{"styles/line.sld", "styles/raster.sld"} {
+		if server.requests[restPath] != 0 {
+			t.Errorf(`the style "%s" of a layer without PostGIS table was requested`, restPath)
+		}
+	}
+}
+
+func TestTableStylesOfWorkspace(t *testing.T) {
+
+	server := newRecordedServer(t)
+	settings := server.settings(t, "osm")
+
+	//the workspace has no layer groups, they are written as empty string
+	tableStyles, err := New(settings, server.Client()).TableStyles([]string{"roads", "coastline"}, "osm_")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"roads": {filepath.Join(settings.StyleDirectory, "osm_roads_style.sld"), filepath.Join(settings.StyleDirectory, "osm_roads_night.sld")}}
+
+	if !reflect.DeepEqual(tableStyles, expected) {
+		t.Errorf("table styles %v, expected %v", tableStyles, expected)
+	}
+
+	if server.requests["workspaces/osm/layers.json"] != 1 || server.requests["layers.json"] != 0 {
+		t.Errorf("the layers were not requested from the workspace: %v", server.requests)
+	}
+}
+
+func TestTableStylesWithoutAuthorization(t *testing.T) {
+
+	server := newRecordedServer(t)
+	settings := server.settings(t, "")
+	settings.Password = "wrong"
+
+	_, err := New(settings, server.Client()).TableStyles([]string{"roads"}, "osm_")
+
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an error of the unauthorized request, got %v", err)
+	}
+}
+
+func TestReferencesUnmarshal(t *testing.T) {
+
+	tests := []struct {
+		data     string
+		expected referenceList
+	}{
+		{`{"style": [{"name": "a"}, ""]}`, referenceList{"style": {{Name: "a"}, {}}}},
+		{`{"style": {"name": "osm:a", "workspace": "osm"}}`, referenceList{"style": {{Name: "osm:a", Workspace: "osm"}}}},
+		{`{"@class": "linked-hash-set", "style": {"name": "a"}}`, referenceList{"@class": nil, "style": {{Name: "a"}}}},
+		{`""`, referenceList{}}}
+
+	for _, test := range tests {
+		list := referenceList{}
+
+		if err := json.Unmarshal([]byte(test.data), &list); err != nil {
+			t.Errorf("%s: %v", test.data, err)
+		} else if !reflect.DeepEqual(list, test.expected) {
+			t.Errorf("%s: %v, expected %v", test.data, list, test.expected)
+		}
+	}
+}
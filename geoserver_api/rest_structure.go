@@ -0,0 +1,178 @@
+package geoserver
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+//########### GeoServer REST structures ###########//
+
+//reference is a link to another REST resource like {"name": "osm:roads", "href": "http://.../rest/layers/osm:roads.json"}
+//Class = the kind of a resource, e.g. "featureType" or "coverage"
+//Type = the kind of a published entry of a layer group, "layer" or "layerGroup"
+type reference struct {
+	Name      string `json:"name"`
+	Workspace string `json:"workspace"`
+	Href      string `json:"href"`
+	Class     string `json:"@class"`
+	Type      string `json:"@type"`
+}
+
+//references is a list of links. GeoServer writes a single entry as object instead of a list
+type references []reference
+
+//referenceList is a wrapper like {"layer": [...]}. GeoServer writes an empty wrapper as empty string
+type referenceList map[string]references
+
+//UnmarshalJSON reads a link, a style of a layer group can be an empty string for the default style
+func (r *reference) UnmarshalJSON(data []byte) error {
+
+	var name string
+
+	if json.Unmarshal(data, &name) == nil || bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*r = reference{Name: name}
+		return nil
+	}
+
+	//the alias type has no UnmarshalJSON method
+	type plainReference reference
+	return json.Unmarshal(data, (*plainReference)(r))
+}
+
+//UnmarshalJSON reads a list of links or a single link, other values like "@class": "linked-hash-set" are no links
+func (r *references) UnmarshalJSON(data []byte) error {
+
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case len(trimmed) == 0:
+		*r = nil
+	case trimmed[0] == '[':
+		list := make([]reference, 0)
+		err := json.Unmarshal(trimmed, &list)
+		*r = list
+		return err
+	case trimmed[0] == '{':
+		single := reference{}
+		err := json.Unmarshal(trimmed, &single)
+		*r = references{single}
+		return err
+	default:
+		*r = nil
+	}
+
+	return nil
+}
+
+//UnmarshalJSON reads a wrapper of links, an empty string is an empty wrapper
+func (l *referenceList) UnmarshalJSON(data []byte) error {
+
+	trimmed := bytes.TrimSpace(data)
+
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		*l = referenceList{}
+		return nil
+	}
+
+	entries := make(map[string]references)
+	err := json.Unmarshal(trimmed, &entries)
+	*l = entries
+
+	return err
+}
+
+//layersResponse of /rest/layers.json
+type layersResponse struct {
+	Layers referenceList `json:"layers"`
+}
+
+//layerResponse of /rest/layers/{name}.json
+type layerResponse struct {
+	Layer layer `json:"layer"`
+}
+
+//layer contains the default style, the alternate styles and the resource of a layer
+type layer struct {
+	Name         string        `json:"name"`
+	DefaultStyle reference     `json:"defaultStyle"`
+	Styles       referenceList `json:"styles"`
+	Resource     reference     `json:"resource"`
+}
+
+//layerGroupsResponse of /rest/layergroups.json
+type layerGroupsResponse struct {
+	LayerGroups referenceList `json:"layerGroups"`
+}
+
+//layerGroupResponse of /rest/layergroups/{name}.json
+type layerGroupResponse struct {
+	LayerGroup layerGroup `json:"layerGroup"`
+}
+
+//layerGroup contains the published layers and layer groups, the styles belong to the published entries with the same index
+type layerGroup struct {
+	Name         string        `json:"name"`
+	Publishables referenceList `json:"publishables"`
+	Styles       referenceList `json:"styles"`
+}
+
+//featureTypeResponse of /rest/workspaces/{workspace}/featuretypes/{name}.json
+type featureTypeResponse struct {
+	FeatureType featureType `json:"featureType"`
+}
+
+//featureType contains the name of the table of a layer and its data store
+type featureType struct {
+	Name       string    `json:"name"`
+	NativeName string    `json:"nativeName"`
+	Store      reference `json:"store"`
+}
+
+//dataStoreResponse of /rest/workspaces/{workspace}/datastores/{name}.json
+type dataStoreResponse struct {
+	DataStore dataStore `json:"dataStore"`
+}
+
+//dataStore contains the kind of a store like "PostGIS" or "Shapefile" and its connection parameters
+type dataStore struct {
+	Name                 string               `json:"name"`
+	Type                 string               `json:"type"`
+	ConnectionParameters connectionParameters `json:"connectionParameters"`
+}
+
+//connectionParameters is a wrapper like {"entry": [{"@key": "dbtype", "$": "postgis"}]}
+type connectionParameters struct {
+	Entry parameters `json:"entry"`
+}
+
+//parameter is a key and its value like {"@key": "dbtype", "$": "postgis"}
+type parameter struct {
+	Key   string `json:"@key"`
+	Value string `json:"$"`
+}
+
+//parameters is a list of parameters. GeoServer writes a single entry as object instead of a list
+type parameters []parameter
+
+//UnmarshalJSON reads a list of parameters or a single parameter
+func (p *parameters) UnmarshalJSON(data []byte) error {
+
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		list := make([]parameter, 0)
+		err := json.Unmarshal(trimmed, &list)
+		*p = list
+		return err
+	case len(trimmed) > 0 && trimmed[0] == '{':
+		single := parameter{}
+		err := json.Unmarshal(trimmed, &single)
+		*p = parameters{single}
+		return err
+	}
+
+	*p = nil
+
+	return nil
+}
@@ -2,59 +2,70 @@ package main
 
 import (
 	"Imposm_Optimizer/configuration"
+	geoserver "Imposm_Optimizer/geoserver_api"
 	"Imposm_Optimizer/mapping"
+	database "Imposm_Optimizer/osm_database"
 	"Imposm_Optimizer/sld"
 	functions "Imposm_Optimizer/std_functions"
+	styles "Imposm_Optimizer/style_reader"
 	"container/list"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
+	"sort"
 	"strconv"
+	"time"
 )
 
 func main() {
 
-	//argument init will init a new config file
-	if len(os.Args) > 1 {
-		if os.Args[1] == "init" {
-			err := configuration.InitConfigFile()
-			if err != nil {
-				fmt.Println("Error: " + err.Error())
-				return
-			}
-		}
+	err := runCommand(os.Args[1:])
+
+	//the changes of the mapping file were already shown by the diff command
+	if err == errMappingChanged {
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Println("Error: " + err.Error())
+		os.Exit(1)
 	}
+}
 
-	//load config, if not exists init config
+//runOptimizer compares the mapping file with the style files of its tables. The command decides if the configuration
+//is only validated, the requirements of the styles are analyzed, the changes of the tables are explained or shown as
+//difference, or the optimized mapping file is written
+func runOptimizer(command string, options commandOptions) error {
+
+	//load config and replace the values given on the command line
 	configError := configuration.ParseConfig(configuration.ConfigFile)
+
+	if configError == nil {
+		configError = configuration.OverrideValues(options.overrides)
+	}
+
 	config := configuration.GetConfiguration()
 
 	fmt.Println("Loading configurations (" + configuration.ConfigFile + ")...")
 
 	//check if configurations are valid
 	if configError != nil {
-		fmt.Println("Error: " + configError.Error())
-		return
+		return configError
 	}
 
 	if config.MappingFilePath == "" {
-		fmt.Println("Error: mapping_path variable is missing in " + configuration.ConfigFile)
-		return
+		return errors.New("mapping_path variable is missing in " + configuration.ConfigFile)
 	} else if !functions.FileExists(config.MappingFilePath) {
-		fmt.Println(errors.New(`Error: mapping file "` + config.MappingFilePath + `" not found!`))
-		return
+		return errors.New(`mapping file "` + config.MappingFilePath + `" not found!`)
 	} else {
 		fmt.Println("- mapping file path         :", config.MappingFilePath)
 	}
 
 	if config.MappingOutPath == "" {
-		fmt.Println("Error: mapping_out_path variable is missing in " + configuration.ConfigFile)
-		return
+		return errors.New("mapping_out_path variable is missing in " + configuration.ConfigFile)
 	} else if !functions.DirExists(config.MappingOutPath) {
-		fmt.Println(errors.New(`Error: taget directory "` + config.MappingOutPath + `" not found!`))
-		return
+		return errors.New(`taget directory "` + config.MappingOutPath + `" not found!`)
 	} else {
 		fmt.Println("- output directory path     :", config.MappingOutPath)
 	}
@@ -68,16 +79,78 @@ func main() {
 	fmt.Println("- filtering is forced       :", config.ForceFiltering)
 	fmt.Println("- API is used for searching :", config.AllowResearch)
 	fmt.Println("- columns which are kept    :", config.KeepColumns)
-	fmt.Println("- tolerance scaling         :", config.ToleranceScaling, "\b%")
+	if config.Tolerance != nil {
+		if err := config.Tolerance.Validate(); err != nil {
+			return errors.New("tolerance settings in " + configuration.ConfigFile + ": " + err.Error())
+		}
+
+		fmt.Println("- tolerance SRID            :", config.Tolerance.SRID)
+		fmt.Println("- tolerance in pixel        :", config.Tolerance.Pixels())
+		fmt.Println("- reference latitude        :", config.Tolerance.ReferenceLatitude)
+	} else {
+		fmt.Println("- tolerance scaling         :", config.ToleranceScaling, "\b%")
+	}
+	fmt.Println("- scale bands               :", config.ScaleBands)
+	fmt.Println("- scale bands are assigned  :", config.AutoScaleBands)
+	fmt.Println("- gen. tables are proposed  :", config.ProposeGenTables)
+	fmt.Println("- geometry types are applied:", config.ApplyGeometryTypes)
+
+	//imposm adds the prefix "osm_" to the table names by default
+	tablePrefix := "osm_"
+
+	if config.TablePrefix != "" {
+		tablePrefix = config.TablePrefix
+	}
+
+	fmt.Println("- table prefix              :", tablePrefix)
+	fmt.Println("- routed style files        :", config.StyleFiles)
+
+	if config.GeoServer != nil {
+		fmt.Println("- GeoServer REST API        :", config.GeoServer.URL)
+	}
+
 	fmt.Println("")
 
 	//init mapping parser
 	mappingParser := mapping.New(config.MappingFilePath, config.AllowResearch, config.ForceFiltering, config.ToleranceScaling, config.KeepColumns)
+	mappingParser.SetScaleBands(config.ScaleBands)
+	mappingParser.SetToleranceSettings(config.Tolerance)
+	mappingParser.SetApplyGeometryTypes(config.ApplyGeometryTypes)
+
+	//the database of an existing import is used to count the rows saved by the optimization
+	if config.Database != nil {
+		importDatabase, err := database.Connect(*config.Database)
+
+		if err != nil {
+			fmt.Println("WARNING: database connection failed, rows are not counted: " + err.Error())
+		} else {
+			defer importDatabase.Close()
+			mappingParser.SetRowCounter(importDatabase)
+		}
+	}
 
 	//get all tables
 	mappingTables := mappingParser.GetTableNames()
 	mappingGenTables := mappingParser.GetGeneralizedTableNames()
 
+	//the optimized tables are compared with the original tables, before the ignored tables are removed
+	originalMapping := mappingParser.GetMappingContent()
+	originalTables := mapping.Mapping{Tables: make(map[string]mapping.Table), GeneralizedTables: make(map[string]mapping.GeneralizedTable)}
+
+	for tableName, table := range originalMapping.Tables {
+		originalTables.Tables[tableName] = table
+	}
+
+	for genTableName, genTable := range originalMapping.GeneralizedTables {
+		originalTables.GeneralizedTables[genTableName] = genTable
+	}
+
+	var originalFileData []byte
+
+	if command == diffCommand {
+		originalFileData = mappingParser.BuildMappingFile(originalMapping)
+	}
+
 	//init tables
 	tableFilesMap := make(map[string](*list.List))
 	for i := range mappingTables {
@@ -93,8 +166,61 @@ func main() {
 	//load all and check all SLD's
 	fmt.Println("\n**************** Listing SLD Files *****************")
 
+	//globs, directories and exclusions of the style entries are resolved into style files
+	unresolvedEntries := append(resolveStyleEntries(config.TableList, "table"), resolveStyleEntries(config.GeneralizedTableList, "generalized table")...)
+	routableFiles, unresolvedRoutedEntries := configuration.ResolveStyleEntries(config.StyleFiles)
+	config.StyleFiles = routableFiles
+
+	for _, entry := range unresolvedRoutedEntries {
+		unresolvedEntries = append(unresolvedEntries, `"`+entry+`" of the routed style files`)
+	}
+
+	//style files with several layers are added to every table, which matches one of the layer names
+	routedFiles, unreadableFiles := routeStyleFiles(config.StyleFiles, append(mappingTables, mappingGenTables...), tablePrefix)
+
+	for _, styleFile := range unreadableFiles {
+		unresolvedEntries = append(unresolvedEntries, `"`+styleFile+`" of the routed style files`)
+	}
+
+	//a skipped style file would remove the columns it renders
+	if len(unresolvedEntries) > 0 {
+		sort.Strings(unresolvedEntries)
+
+		for _, entry := range unresolvedEntries {
+			fmt.Println("- no style file found, unreadable directory or unreadable style file for " + entry)
+		}
+
+		if !config.AllowMissingStyles {
+			return errors.New(`style entries without style file or with unreadable directories or style files, set "allow_missing_styles" in ` + configuration.ConfigFile + ` to skip them`)
+		}
+
+		fmt.Println("WARNING: style entries without style file or with unreadable directories or style files are skipped")
+	}
+
+	//the styles of the GeoServer layers are added to the PostGIS tables of the layers
+	if config.GeoServer != nil {
+		client := geoserver.New(*config.GeoServer, &http.Client{Timeout: time.Minute})
+		geoServerFiles, err := client.TableStyles(append(mappingTables, mappingGenTables...), tablePrefix)
+
+		if err != nil {
+			return errors.New("the styles of the GeoServer could not be requested: " + err.Error())
+		}
+
+		for tableName, styleFiles := range geoServerFiles {
+			routedFiles[tableName] = append(routedFiles[tableName], styleFiles...)
+		}
+	}
+
 	for tableName, fileList := range tableFilesMap {
 
+		if !functions.StringInSlice("ignore", config.TableList[tableName]) {
+			for _, styleFile := range routedFiles[tableName] {
+				if !functions.StringInSlice(styleFile, config.TableList[tableName]) {
+					config.TableList[tableName] = append(config.TableList[tableName], styleFile)
+				}
+			}
+		}
+
 		if config.TableList[tableName] != nil {
 
 			if functions.StringInSlice("ignore", config.TableList[tableName]) {
@@ -120,6 +246,18 @@ func main() {
 
 	for genTableName, fileList := range genTableFilesMap {
 
+		if config.GeneralizedTableList == nil {
+			config.GeneralizedTableList = make(map[string][]string)
+		}
+
+		if !functions.StringInSlice("ignore", config.GeneralizedTableList[genTableName]) {
+			for _, styleFile := range routedFiles[genTableName] {
+				if !functions.StringInSlice(styleFile, config.GeneralizedTableList[genTableName]) {
+					config.GeneralizedTableList[genTableName] = append(config.GeneralizedTableList[genTableName], styleFile)
+				}
+			}
+		}
+
 		if config.GeneralizedTableList[genTableName] != nil {
 
 			if functions.StringInSlice("ignore", config.GeneralizedTableList[genTableName]) {
@@ -147,6 +285,7 @@ func main() {
 	fmt.Println("\n***************** Comparing tables *****************")
 
 	comparedTables := make(map[string][]sld.ParsedSLD)
+	invalidTables := make([]string, 0)
 
 	for tableName, fileList := range tableFilesMap {
 
@@ -157,11 +296,14 @@ func main() {
 		fmt.Println(`-------- Comparing table "` + tableName + `"... --------`)
 
 		mappingColumns := mappingParser.GetMappingColumnName(tableName)
-		parsedSLDList, err := parseSLDFileList(fileList, mappingColumns)
+		parsedSLDList, err := parseSLDFileList(fileList, mappingColumns, mappingParser.GetSourceTableNames(tableName), tablePrefix)
 
-		if err != nil {
+		//the validation reports the errors of all tables
+		if err != nil && command == validateCommand {
 			fmt.Println("Error: " + err.Error())
-			return
+			invalidTables = append(invalidTables, tableName)
+		} else if err != nil {
+			return err
 		}
 
 		fmt.Print("\n")
@@ -178,11 +320,13 @@ func main() {
 		fmt.Println(`-------- Comparing generalized table "` + genTableName + `"... --------`)
 
 		mappingColumns := mappingParser.GetMappingColumnName(genTableName)
-		parsedSLDList, err := parseSLDFileList(fileList, mappingColumns)
+		parsedSLDList, err := parseSLDFileList(fileList, mappingColumns, mappingParser.GetSourceTableNames(genTableName), tablePrefix)
 
-		if err != nil {
+		if err != nil && command == validateCommand {
 			fmt.Println("Error: " + err.Error())
-			return
+			invalidTables = append(invalidTables, genTableName)
+		} else if err != nil {
+			return err
 		}
 
 		fmt.Print("\n")
@@ -190,10 +334,66 @@ func main() {
 		comparedTables[genTableName] = parsedSLDList
 	}
 
+	if command == validateCommand {
+		if len(invalidTables) > 0 {
+			sort.Strings(invalidTables)
+			return errors.New(fmt.Sprint("the style files of the tables ", invalidTables, " could not be read"))
+		}
+
+		fmt.Println(`The configuration "` + configuration.ConfigFile + `" and all style files are valid`)
+		return nil
+	}
+
+	if config.AutoScaleBands || command == analyzeCommand {
+		fmt.Println("************** Assigning scale bands ***************")
+
+		comparedTables = mappingParser.AssignScaleBands(comparedTables)
+
+		fmt.Println("")
+	}
+
+	if command == analyzeCommand {
+		printAnalysis(comparedTables)
+
+		fmt.Println("\n********** Proposing generalized tables ************")
+
+		proposedTables := mappingParser.ProposeGeneralizedTables(comparedTables)
+
+		if proposedTables != nil {
+			fmt.Println("\n" + string(proposedTables))
+		}
+
+		return nil
+	}
+
 	fmt.Println("************** Rebuilding mapping file *************")
 
+	if command == explainCommand {
+		sourceTables := make(map[string][]string)
+
+		for _, genTableName := range mappingGenTables {
+			sourceTables[genTableName] = mappingParser.GetSourceTableNames(genTableName)
+		}
+
+		optimizedMapping := mappingParser.RebuildMapping(comparedTables)
+
+		return explainTables(options.tables, originalTables, optimizedMapping, comparedTables, sourceTables, config.KeepColumns)
+	}
+
 	newFileData := mappingParser.RebuildMappingStructure(comparedTables)
 
+	if command == diffCommand {
+		fmt.Println("************* Changes of the mapping file **********")
+
+		changed := printMappingDiff(config.MappingFilePath, originalFileData, newFileData)
+
+		if changed && options.exitCode {
+			return errMappingChanged
+		}
+
+		return nil
+	}
+
 	newMappingFilePath := config.MappingOutPath + "/" + config.MappingPrefix + path.Base(config.MappingFilePath)
 	newMappingFilePath = path.Clean(newMappingFilePath)
 
@@ -201,19 +401,31 @@ func main() {
 	err := ioutil.WriteFile(newMappingFilePath, newFileData, 0666)
 
 	if err != nil {
-		fmt.Println("Error: " + err.Error())
-		return
+		return err
 	}
 
-	return
+	if config.ProposeGenTables {
+		fmt.Println("\n********** Proposing generalized tables ************")
+
+		proposedTables := mappingParser.ProposeGeneralizedTables(comparedTables)
+
+		if proposedTables != nil {
+			fmt.Println("\n" + string(proposedTables))
+		}
+	}
+
+	return nil
 }
 
-func parseSLDFileList(fileList *list.List, mappingColumns sld.MappingColumnNames) ([]sld.ParsedSLD, error) {
+//parseSLDFileList extracts the requirements of all SLD files of a table. Files with several layers only use
+//the layers of the table, a generalized table uses the layers of its source tables, if there is no own layer
+func parseSLDFileList(fileList *list.List, mappingColumns sld.MappingColumnNames, tableNames []string, tablePrefix string) ([]sld.ParsedSLD, error) {
 
 	parsedSLDList := make([]sld.ParsedSLD, 0)
 
 	for filePath := fileList.Front(); filePath != nil; filePath = filePath.Next() {
-		sldParser := sld.New(fmt.Sprintf("%v", filePath.Value))
+		sldParser := styles.New(fmt.Sprintf("%v", filePath.Value))
+		sldParser.SetTableNames(tableNames, tablePrefix)
 
 		fmt.Println("\n" + `Extracting required columns and mapping types from "` + sldParser.GetFilePath() + `"...`)
 
@@ -225,6 +437,14 @@ func parseSLDFileList(fileList *list.List, mappingColumns sld.MappingColumnNames
 
 		parsedSLDList = append(parsedSLDList, newParsedSLD)
 
+		if newParsedSLD.Version != "" {
+			fmt.Println("- style version: " + newParsedSLD.Version)
+		}
+
+		if len(sldParser.UsedLayers()) > 0 {
+			fmt.Println("- used layers:", sldParser.UsedLayers())
+		}
+
 		fmt.Print("- required columns: ")
 		if len(newParsedSLD.Requirements.RequiredColumnList) <= 15 && len(newParsedSLD.Requirements.RequiredColumnList) > 0 {
 			fmt.Print("[")
@@ -253,7 +473,77 @@ func parseSLDFileList(fileList *list.List, mappingColumns sld.MappingColumnNames
 		}
 
 		fmt.Println("- required minimum/maximum scaling: " + strconv.Itoa(newParsedSLD.Scale.MinScaleDenominator) + "/" + maxScale)
+
+		//sizes in ground units depend on the scale
+		if len(newParsedSLD.Requirements.SymbolizerUnits) > 1 || (len(newParsedSLD.Requirements.SymbolizerUnits) == 1 && newParsedSLD.Requirements.SymbolizerUnits[0] != sld.PixelUnit) {
+			fmt.Println("- symbolizer units of measure:", newParsedSLD.Requirements.SymbolizerUnits)
+		}
 	}
 
 	return parsedSLDList, nil
 }
+
+//resolveStyleEntries replaces the style entries of the tables by their style files and returns the entries without
+//style file. Tables without any style file are removed, so they are not changed
+func resolveStyleEntries(tableList map[string][]string, tableKind string) []string {
+
+	unresolvedEntries := make([]string, 0)
+
+	for tableName, entries := range tableList {
+		if functions.StringInSlice("ignore", entries) {
+			continue
+		}
+
+		styleFiles, unresolved := configuration.ResolveStyleEntries(entries)
+
+		if len(styleFiles) > 0 {
+			tableList[tableName] = styleFiles
+		} else {
+			delete(tableList, tableName)
+		}
+
+		for _, entry := range unresolved {
+			unresolvedEntries = append(unresolvedEntries, `"`+entry+`" of the `+tableKind+` "`+tableName+`"`)
+		}
+	}
+
+	return unresolvedEntries
+}
+
+//routeStyleFiles assigns the style files to all tables, which match at least one of the layer or feature type names of a file.
+//The style files, which cannot be read or parsed, are returned separately
+func routeStyleFiles(styleFiles []string, tableNames []string, tablePrefix string) (map[string][]string, []string) {
+
+	routedFiles := make(map[string][]string)
+	unreadableFiles := make([]string, 0)
+
+	for _, styleFile := range styleFiles {
+
+		styleParser := styles.New(styleFile)
+		layerNames, err := styleParser.LayerNames()
+
+		if err != nil {
+			fmt.Println(`- style file "` + styleFile + `" could not be read: ` + err.Error())
+			unreadableFiles = append(unreadableFiles, styleFile)
+			continue
+		}
+
+		routed := false
+
+		for _, tableName := range tableNames {
+			for _, layerName := range layerNames {
+				if sld.MatchesTable(layerName, tableName, tablePrefix) {
+					routedFiles[tableName] = append(routedFiles[tableName], styleFile)
+					routed = true
+					break
+				}
+			}
+		}
+
+		if !routed {
+			fmt.Println(`WARNING: no table matches the layers of the style file "`+styleFile+`":`, layerNames)
+		}
+	}
+
+	return routedFiles, unreadableFiles
+}
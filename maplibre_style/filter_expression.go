@@ -0,0 +1,373 @@
+package maplibre
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"strconv"
+	"strings"
+)
+
+//comparisonOperators maps the comparison operators of filters to the OGC comparison operators
+var comparisonOperators = map[string]string{
+	"==": "PropertyIsEqualTo",
+	"!=": "PropertyIsNotEqualTo",
+	"<":  "PropertyIsLessThan",
+	"<=": "PropertyIsLessThanOrEqualTo",
+	">":  "PropertyIsGreaterThan",
+	">=": "PropertyIsGreaterThanOrEqualTo"}
+
+//specialKeys of legacy filters are no columns
+var specialKeys = []string{"$type", "$id"}
+
+//ConvertFilter converts a legacy filter or a filter expression into a filter tree. Parts of the filter, which can not be
+//represented (e.g. geometry types or string functions), are returned as unknown filter
+func ConvertFilter(filter interface{}) sld.FilterNode {
+
+	array, isArray := filter.([]interface{})
+
+	if !isArray || len(array) == 0 {
+		if value, isBool := filter.(bool); isBool {
+			return booleanFilter(value)
+		}
+
+		return sld.UnknownFilter{Name: "maplibre:" + literalText(filter)}
+	}
+
+	operator, _ := array[0].(string)
+	arguments := array[1:]
+
+	switch operator {
+	case "all", "any", "none":
+		children := make([]sld.FilterNode, 0, len(arguments))
+
+		for _, argument := range arguments {
+			children = append(children, ConvertFilter(argument))
+		}
+
+		switch {
+		case len(children) == 0:
+			return booleanFilter(operator != "any")
+		case operator == "all":
+			return sld.LogicalFilter{Name: "And", Children: children}
+		case operator == "any":
+			return sld.LogicalFilter{Name: "Or", Children: children}
+		}
+
+		return sld.NotFilter{Child: sld.LogicalFilter{Name: "Or", Children: children}}
+
+	case "!":
+		if len(arguments) == 1 {
+			return sld.NotFilter{Child: ConvertFilter(arguments[0])}
+		}
+
+	case "has", "!has":
+		if column, ok := filterColumn(arguments); ok {
+			isNull := sld.NullFilter{Name: "PropertyIsNull", Expression: sld.PropertyNameExpression{Name: column}}
+
+			if operator == "!has" {
+				return isNull
+			}
+
+			return sld.NotFilter{Child: isNull}
+		}
+
+	case "==", "!=", "<", "<=", ">", ">=":
+		column, isColumn := filterColumn(arguments)
+
+		if !isColumn || len(arguments) != 2 {
+			break
+		}
+
+		//comparisons with null check if the property is missing
+		if arguments[1] == nil && (operator == "==" || operator == "!=") {
+			isNull := sld.NullFilter{Name: "PropertyIsNull", Expression: sld.PropertyNameExpression{Name: column}}
+
+			if operator == "!=" {
+				return sld.NotFilter{Child: isNull}
+			}
+
+			return isNull
+		}
+
+		if value, isLiteral := literalValue(arguments[1]); isLiteral {
+			return sld.ComparisonFilter{Name: comparisonOperators[operator], Left: sld.PropertyNameExpression{Name: column}, Right: sld.LiteralExpression{Value: value}, MatchCase: true}
+		}
+
+	case "in", "!in":
+		column, isColumn := filterColumn(arguments)
+
+		if !isColumn {
+			break
+		}
+
+		//legacy filters list the values as arguments, expressions use a literal array
+		values := arguments[1:]
+
+		if _, isLegacy := arguments[0].(string); !isLegacy {
+			if len(arguments) != 2 {
+				break
+			}
+
+			list, isList := literalList(arguments[1])
+
+			if !isList {
+				break
+			}
+
+			values = list
+		}
+
+		filter := valueListFilter(column, values)
+
+		if operator == "!in" {
+			return sld.NotFilter{Child: filter}
+		}
+
+		return filter
+
+	case "match":
+		if filter, ok := matchFilter(arguments); ok {
+			return filter
+		}
+	}
+
+	return sld.UnknownFilter{Name: "maplibre:" + operator}
+}
+
+//matchFilter converts a match expression with boolean outputs like ["match", ["get", "class"], ["a", "b"], true, false]
+func matchFilter(arguments []interface{}) (sld.FilterNode, bool) {
+
+	if len(arguments) < 4 || len(arguments)%2 != 0 {
+		return nil, false
+	}
+
+	column, isColumn := filterColumn(arguments[:1])
+	fallback, isBool := arguments[len(arguments)-1].(bool)
+
+	if !isColumn || !isBool {
+		return nil, false
+	}
+
+	matchingValues := make([]interface{}, 0)
+
+	for i := 1; i+1 < len(arguments); i += 2 {
+		output, isBool := arguments[i+1].(bool)
+
+		if !isBool {
+			return nil, false
+		}
+
+		labels, isList := arguments[i].([]interface{})
+
+		if !isList {
+			labels = []interface{}{arguments[i]}
+		}
+
+		//with a true fallback the features of the labels with false output are excluded
+		if output != fallback {
+			matchingValues = append(matchingValues, labels...)
+		}
+	}
+
+	filter := valueListFilter(column, matchingValues)
+
+	if fallback {
+		return sld.NotFilter{Child: filter}, true
+	}
+
+	return filter, true
+}
+
+//valueListFilter returns a filter, which is true if the column has one of the values
+func valueListFilter(column string, values []interface{}) sld.FilterNode {
+
+	children := make([]sld.FilterNode, 0, len(values))
+
+	for _, value := range values {
+		if literal, isLiteral := literalValue(value); isLiteral {
+			children = append(children, sld.ComparisonFilter{Name: "PropertyIsEqualTo", Left: sld.PropertyNameExpression{Name: column}, Right: sld.LiteralExpression{Value: literal}, MatchCase: true})
+		}
+	}
+
+	if len(children) == 0 {
+		return booleanFilter(false)
+	}
+
+	return sld.LogicalFilter{Name: "Or", Children: children}
+}
+
+//filterColumn returns the column of a legacy key or a get expression, which is the first argument of a filter
+func filterColumn(arguments []interface{}) (string, bool) {
+
+	if len(arguments) == 0 {
+		return "", false
+	}
+
+	switch argument := arguments[0].(type) {
+	case string:
+		return argument, !functions.StringInSlice(argument, specialKeys)
+	case []interface{}:
+		if len(argument) == 2 && argument[0] == "get" {
+			column, isString := argument[1].(string)
+			return column, isString
+		}
+	}
+
+	return "", false
+}
+
+//literalValue returns the text of a string, number or boolean value
+func literalValue(value interface{}) (string, bool) {
+
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+
+	return "", false
+}
+
+//literalList returns the values of a ["literal", [...]] expression
+func literalList(value interface{}) ([]interface{}, bool) {
+
+	expression, isArray := value.([]interface{})
+
+	if !isArray || len(expression) != 2 || expression[0] != "literal" {
+		return nil, false
+	}
+
+	list, isList := expression[1].([]interface{})
+
+	return list, isList
+}
+
+func literalText(value interface{}) string {
+
+	text, _ := literalValue(value)
+	return text
+}
+
+//booleanFilter returns a filter, which is always true or always false
+func booleanFilter(value bool) sld.FilterNode {
+
+	if value {
+		return sld.UnknownFilter{Name: "maplibre:true"}
+	}
+
+	return sld.NotFilter{Child: sld.UnknownFilter{Name: "maplibre:true"}}
+}
+
+//zoomRestriction returns the zoom range of the zoom comparisons of a filter like ["all", [">=", ["zoom"], 12], ...].
+//Only comparisons, which have to be true for the whole filter, restrict the range
+func zoomRestriction(filter interface{}, minZoom float64, maxZoom float64) (float64, float64) {
+
+	array, isArray := filter.([]interface{})
+
+	if !isArray || len(array) == 0 {
+		return minZoom, maxZoom
+	}
+
+	switch array[0] {
+	case "all":
+		for _, child := range array[1:] {
+			minZoom, maxZoom = zoomRestriction(child, minZoom, maxZoom)
+		}
+
+	case ">=", ">", "<", "<=", "==":
+		zoom, isZoom := array[1].([]interface{})
+		value, isNumber := array[len(array)-1].(float64)
+
+		if len(array) != 3 || !isZoom || len(zoom) != 1 || zoom[0] != "zoom" || !isNumber {
+			break
+		}
+
+		switch array[0] {
+		case ">=", ">":
+			if value > minZoom {
+				minZoom = value
+			}
+		case "<", "<=":
+			if value < maxZoom {
+				maxZoom = value
+			}
+		case "==":
+			minZoom, maxZoom = value, value+1
+		}
+	}
+
+	return minZoom, maxZoom
+}
+
+//expressionColumns returns the columns, which are used by an expression, a legacy filter or a legacy property function.
+//In filters the key of a legacy comparison is a column, texts like "{name} {ref}" reference columns, if tokens are allowed
+func expressionColumns(value interface{}, legacyFilter bool, tokens bool) []string {
+
+	columns := make([]string, 0)
+
+	switch v := value.(type) {
+	case string:
+		if tokens {
+			for _, token := range strings.Split(v, "{")[1:] {
+				if end := strings.Index(token, "}"); end > 0 {
+					columns = functions.AppendUnique(columns, token[:end])
+				}
+			}
+		}
+
+	case map[string]interface{}:
+		//legacy functions like {"property": "width", "stops": [...]}
+		if property, isString := v["property"].(string); isString {
+			columns = functions.AppendUnique(columns, property)
+		}
+
+		for _, stop := range expressionList(v["stops"]) {
+			for _, column := range expressionColumns(stop, false, tokens) {
+				columns = functions.AppendUnique(columns, column)
+			}
+		}
+
+	case []interface{}:
+		if len(v) == 0 {
+			break
+		}
+
+		operator, _ := v[0].(string)
+
+		if (operator == "get" || operator == "has" || operator == "!has") && len(v) == 2 {
+			if column, isString := v[1].(string); isString {
+				return functions.AppendUnique(columns, column)
+			}
+		}
+
+		if operator == "literal" {
+			break
+		}
+
+		//legacy filters like ["==", "class", "primary"] reference the column by its name
+		if legacyFilter && len(v) >= 2 {
+			if key, isString := v[1].(string); isString && !functions.StringInSlice(key, specialKeys) && operator != "all" && operator != "any" && operator != "none" {
+				columns = functions.AppendUnique(columns, key)
+			}
+		}
+
+		for _, argument := range v[1:] {
+			isFilter := legacyFilter && (operator == "all" || operator == "any" || operator == "none")
+
+			for _, column := range expressionColumns(argument, isFilter, tokens) {
+				columns = functions.AppendUnique(columns, column)
+			}
+		}
+	}
+
+	return columns
+}
+
+func expressionList(value interface{}) []interface{} {
+
+	list, _ := value.([]interface{})
+	return list
+}
@@ -0,0 +1,278 @@
+package maplibre
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//zoomZeroScale is the scale denominator of zoom level 0 with the 512 pixel tiles of MapLibre and 0.28 mm pixels
+const zoomZeroScale = 279541132.014
+
+//layerKinds maps the layer types to the symbolizer kinds of the sld package, symbol layers depend on their layout
+var layerKinds = map[string][]string{
+	"fill":           {sld.PolygonKind},
+	"fill-extrusion": {sld.PolygonKind},
+	"line":           {sld.LineKind},
+	"circle":         {sld.PointKind},
+	"heatmap":        {sld.PointKind},
+	"raster":         {sld.RasterKind},
+	"hillshade":      {sld.RasterKind}}
+
+//tokenProperties can reference columns with tokens like "{name}"
+var tokenProperties = []string{"text-field", "icon-image"}
+
+//Parser class
+type Parser struct {
+	filePath          string
+	successfullPasing bool
+	style             Style
+	tableNames        []string
+	tablePrefix       string
+	usedLayers        []string
+}
+
+//New MapLibre style parser instance
+func New(filePath string) Parser {
+	s := Parser{filePath, false, Style{}, nil, "", make([]string, 0)}
+	return s
+}
+
+func (s *Parser) loadStyleFile() error {
+
+	if s.successfullPasing {
+		return nil
+	}
+
+	if filepath.Ext(s.filePath) != ".json" {
+		return errors.New(`"` + s.filePath + `" must be a .json file`)
+	}
+
+	jsonFile, err := ioutil.ReadFile(s.filePath)
+
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(jsonFile, &s.style)
+
+	if err != nil {
+		return errors.New(`"` + s.filePath + `" is no valid style file: ` + err.Error())
+	}
+
+	s.successfullPasing = true
+
+	return nil
+}
+
+//SetTableNames restricts the extracted requirements to the style layers, whose source layer matches one of the tables.
+//The first table matching at least one source layer is used. A style with a single source layer is used completely,
+//if no source layer matches. Otherwise no style layer is used
+func (s *Parser) SetTableNames(tableNames []string, tablePrefix string) {
+	s.tableNames = tableNames
+	s.tablePrefix = tablePrefix
+}
+
+//LayerNames returns the names of all source layers
+func (s *Parser) LayerNames() ([]string, error) {
+
+	err := s.loadStyleFile()
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+
+	for _, layer := range s.style.Layers {
+		names = functions.AppendUnique(names, layer.SourceLayer)
+	}
+
+	return names, nil
+}
+
+//UsedLayers returns the source layers, which were used for the requirements of the table.
+//The list is empty, if the whole file was used
+func (s *Parser) UsedLayers() []string {
+	return s.usedLayers
+}
+
+//GetFilePath returns the path to the style file
+func (s *Parser) GetFilePath() string {
+	return s.filePath
+}
+
+//ExtractRequirements ( mappingColumns ) will return all required values of the style layers in form of a ParsedSLD structure.
+//Every style layer is handled like a SLD rule, the zoom levels are converted into scale denominators
+func (s *Parser) ExtractRequirements(mappingColumns sld.MappingColumnNames) (sld.ParsedSLD, error) {
+
+	err := s.loadStyleFile()
+
+	if err != nil {
+		return sld.ParsedSLD{}, err
+	}
+
+	requirements := sld.NewTableRequirements(mappingColumns)
+	ruleList := make([]sld.Rule, 0)
+
+	for layerIndex, layer := range s.selectedLayers() {
+		if rule, ok := convertLayer(layer, layerIndex, &requirements); ok {
+			ruleList = append(ruleList, rule)
+		}
+	}
+
+	scaleDenominator, useAllMappingTypes := sld.CompleteRequirements(ruleList, &requirements)
+
+	return sld.ParsedSLD{
+		FileName:           s.filePath,
+		Requirements:       requirements,
+		Scale:              scaleDenominator,
+		UseAllMappingTypes: useAllMappingTypes,
+		Version:            strings.TrimSpace("maplibre " + strconv.Itoa(s.style.Version))}, nil
+}
+
+//selectedLayers returns the style layers of the first table, which matches a source layer.
+//Without matching table only a style with a single source layer is used
+func (s *Parser) selectedLayers() []Layer {
+
+	s.usedLayers = make([]string, 0)
+
+	for _, tableName := range s.tableNames {
+		layers := make([]Layer, 0)
+
+		for _, layer := range s.style.Layers {
+			if sld.MatchesTable(layer.SourceLayer, tableName, s.tablePrefix) {
+				layers = append(layers, layer)
+				s.usedLayers = functions.AppendUnique(s.usedLayers, layer.SourceLayer)
+			}
+		}
+
+		if len(layers) > 0 {
+			return layers
+		}
+	}
+
+	if names, _ := s.LayerNames(); len(names) <= 1 {
+		return s.style.Layers
+	}
+
+	fmt.Println(`WARNING: no source layer of the style file "`+s.filePath+`" matches the tables`, s.tableNames)
+
+	return []Layer{}
+}
+
+//convertLayer converts a style layer into a SLD rule and adds the columns of its filter and properties to the requirements.
+//False is returned, if the layer is never drawn
+func convertLayer(layer Layer, layerIndex int, requirements *sld.TableRequirements) (sld.Rule, bool) {
+
+	rule := sld.Rule{Name: layer.ID, FeatureTypeStyle: layerIndex}
+
+	minZoom, maxZoom := 0.0, math.Inf(1)
+
+	if layer.MinZoom != nil {
+		minZoom = *layer.MinZoom
+	}
+
+	if layer.MaxZoom != nil {
+		maxZoom = *layer.MaxZoom
+	}
+
+	minZoom, maxZoom = zoomRestriction(layer.Filter, minZoom, maxZoom)
+
+	if minZoom >= maxZoom {
+		return sld.Rule{}, false
+	}
+
+	//the layer is drawn up to, but not including the maximum zoom level
+	if minZoom > 0 {
+		rule.MaxScale = zoomScale(minZoom)
+	}
+
+	if !math.IsInf(maxZoom, 1) {
+		rule.MinScale = zoomScale(maxZoom)
+	}
+
+	scale := sld.ScaleDenominator{MinScaleDenominator: rule.MinScale, MaxScaleDenominator: rule.MaxScale}
+
+	if rule.MaxScale == 0 {
+		scale.MaxScaleDenominator = -2
+	}
+
+	if layer.Filter != nil {
+		filterText, _ := json.Marshal(layer.Filter)
+		rule.Filter = sld.Filter{XMLContent: filterText}
+		rule.FilterTree = ConvertFilter(layer.Filter)
+
+		for _, column := range expressionColumns(layer.Filter, true, false) {
+			sld.AddRequiredColumn(requirements, column, []string{}, scale, sld.FilterUsage)
+		}
+	}
+
+	symbolizer := []sld.Symbolizer{{XMLContent: nil, UnitOfMeasure: sld.PixelUnit}}
+
+	for _, kind := range layerSymbolizerKinds(layer) {
+		switch kind {
+		case sld.PointKind:
+			rule.PointSymbolizer = symbolizer
+		case sld.LineKind:
+			rule.LineSymbolizer = symbolizer
+		case sld.PolygonKind:
+			rule.PolygonSymbolizer = symbolizer
+		case sld.TextKind:
+			rule.TextSymbolizer = symbolizer
+		case sld.RasterKind:
+			rule.RasterSymbolizer = symbolizer
+		}
+	}
+
+	for _, properties := range []map[string]interface{}{layer.Layout, layer.Paint} {
+		for property, value := range properties {
+			usage := sld.StyleUsage
+
+			switch {
+			case property == "text-field":
+				usage = sld.LabelUsage
+			case strings.HasSuffix(property, "-sort-key"):
+				usage = sld.SortUsage
+			}
+
+			for _, column := range expressionColumns(value, false, functions.StringInSlice(property, tokenProperties)) {
+				sld.AddRequiredColumn(requirements, column, []string{}, scale, usage)
+			}
+		}
+	}
+
+	return rule, true
+}
+
+//layerSymbolizerKinds returns the symbolizer kinds of a style layer, symbol layers draw labels and icons
+func layerSymbolizerKinds(layer Layer) []string {
+
+	if layer.Type != "symbol" {
+		return layerKinds[layer.Type]
+	}
+
+	kinds := make([]string, 0)
+
+	if _, hasIcon := layer.Layout["icon-image"]; hasIcon {
+		kinds = append(kinds, sld.PointKind)
+	}
+
+	if _, hasText := layer.Layout["text-field"]; hasText {
+		kinds = append(kinds, sld.TextKind)
+	}
+
+	return kinds
+}
+
+//zoomScale converts a zoom level of MapLibre into a scale denominator
+func zoomScale(zoom float64) int {
+	return int(math.Round(zoomZeroScale / math.Pow(2, zoom)))
+}
@@ -0,0 +1,25 @@
+package maplibre
+
+//########### MapLibre style structures ###########//
+
+//Style is the root object of a Mapbox GL or MapLibre style JSON file
+type Style struct {
+	Version int     `json:"version"`
+	Name    string  `json:"name"`
+	Layers  []Layer `json:"layers"`
+}
+
+//Layer draws the features of a source layer with one symbolizer type
+//Filter = a legacy filter like ["==", "class", "primary"] or an expression like ["==", ["get", "class"], "primary"]
+//MinZoom/MaxZoom = the layer is drawn from the minimum zoom level up to, but not including, the maximum zoom level
+type Layer struct {
+	ID          string                 `json:"id"`
+	Type        string                 `json:"type"`
+	Source      string                 `json:"source"`
+	SourceLayer string                 `json:"source-layer"`
+	MinZoom     *float64               `json:"minzoom"`
+	MaxZoom     *float64               `json:"maxzoom"`
+	Filter      interface{}            `json:"filter"`
+	Layout      map[string]interface{} `json:"layout"`
+	Paint       map[string]interface{} `json:"paint"`
+}
@@ -0,0 +1,525 @@
+package mapnik
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+//token kinds of a Mapnik expression
+const (
+	attributeToken = iota
+	stringToken
+	numberToken
+	identifierToken
+	operatorToken
+)
+
+type token struct {
+	kind  int
+	value string
+}
+
+//expression is a node of a parsed Mapnik expression
+//kind = attribute, literal, function, binary, unary, match or replace
+type expression struct {
+	kind     string
+	value    string
+	children []expression
+}
+
+//comparisonOperators maps the Mapnik comparison operators to the OGC comparison operators
+var comparisonOperators = map[string]string{
+	"=":   "PropertyIsEqualTo",
+	"==":  "PropertyIsEqualTo",
+	"eq":  "PropertyIsEqualTo",
+	"!=":  "PropertyIsNotEqualTo",
+	"<>":  "PropertyIsNotEqualTo",
+	"neq": "PropertyIsNotEqualTo",
+	"<":   "PropertyIsLessThan",
+	"lt":  "PropertyIsLessThan",
+	"<=":  "PropertyIsLessThanOrEqualTo",
+	"le":  "PropertyIsLessThanOrEqualTo",
+	">":   "PropertyIsGreaterThan",
+	"gt":  "PropertyIsGreaterThan",
+	">=":  "PropertyIsGreaterThanOrEqualTo",
+	"ge":  "PropertyIsGreaterThanOrEqualTo"}
+
+//arithmeticOperators maps the Mapnik arithmetic operators to the OGC arithmetic operators
+var arithmeticOperators = map[string]string{
+	"+": "Add",
+	"-": "Sub",
+	"*": "Mul",
+	"/": "Div"}
+
+//binaryPrecedence of the binary operators, higher values bind stronger
+var binaryPrecedence = map[string]int{
+	"or": 1, "||": 1,
+	"and": 2, "&&": 2,
+	"=": 3, "==": 3, "eq": 3, "!=": 3, "<>": 3, "neq": 3,
+	"<": 4, "lt": 4, "<=": 4, "le": 4, ">": 4, "gt": 4, ">=": 4, "ge": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6}
+
+//attributePattern matches the attributes of an expression like [name]
+var attributePattern = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+//AttributeNames returns the names of all attributes used in an expression. The special
+//attributes of Mapnik like [mapnik::geometry_type] are no columns and will be skipped
+func AttributeNames(text string) []string {
+
+	names := make([]string, 0)
+	attributes := make([]string, 0)
+
+	//brackets in string literals are no attributes, texts which are no valid expression are searched by pattern
+	if tokens, err := tokenize(text); err == nil {
+		for _, t := range tokens {
+			if t.kind == attributeToken {
+				attributes = append(attributes, t.value)
+			}
+		}
+	} else {
+		for _, match := range attributePattern.FindAllStringSubmatch(text, -1) {
+			attributes = append(attributes, strings.TrimSpace(match[1]))
+		}
+	}
+
+	for _, name := range attributes {
+		if name != "" && !strings.HasPrefix(name, "mapnik::") && !functions.StringInSlice(name, names) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+//ParseFilter converts a Mapnik filter expression like "[highway] = 'primary' and [tunnel] != 'yes'" into a filter tree.
+//Parts of the expression, which can not be represented as filter, are returned as unknown filter
+func ParseFilter(text string) (sld.FilterNode, error) {
+
+	tokens, err := tokenize(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parser := expressionParser{tokens, 0}
+	parsed, err := parser.parseBinary(0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.position < len(parser.tokens) {
+		return nil, errors.New(`unexpected "` + parser.tokens[parser.position].value + `" in filter "` + text + `"`)
+	}
+
+	return parsed.filter(), nil
+}
+
+func tokenize(text string) ([]token, error) {
+
+	tokens := make([]token, 0)
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+
+			if end == len(runes) {
+				return nil, errors.New(`unclosed attribute in "` + text + `"`)
+			}
+
+			tokens = append(tokens, token{attributeToken, strings.TrimSpace(string(runes[i+1 : end]))})
+			i = end + 1
+
+		case r == '\'' || r == '"':
+			value := ""
+			end := i + 1
+
+			for ; end < len(runes) && runes[end] != r; end++ {
+				if runes[end] == '\\' && end+1 < len(runes) {
+					end++
+				}
+				value += string(runes[end])
+			}
+
+			if end == len(runes) {
+				return nil, errors.New(`unclosed string in "` + text + `"`)
+			}
+
+			tokens = append(tokens, token{stringToken, value})
+			i = end + 1
+
+		case unicode.IsDigit(r):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.' || runes[end] == 'e' || runes[end] == 'E') {
+				end++
+			}
+
+			tokens = append(tokens, token{numberToken, string(runes[i:end])})
+			i = end
+
+		case unicode.IsLetter(r) || r == '_' || r == '@':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+
+			tokens = append(tokens, token{identifierToken, string(runes[i:end])})
+			i = end
+
+		default:
+			operator := string(r)
+
+			if i+1 < len(runes) {
+				if pair := string(runes[i : i+2]); functions.StringInSlice(pair, []string{"==", "!=", "<>", "<=", ">=", "&&", "||"}) {
+					operator = pair
+				}
+			}
+
+			if !strings.Contains("=!<>&|+-*/%().,", string(r)) {
+				return nil, errors.New(`unknown character "` + string(r) + `" in "` + text + `"`)
+			}
+
+			tokens = append(tokens, token{operatorToken, operator})
+			i += len([]rune(operator))
+		}
+	}
+
+	return tokens, nil
+}
+
+type expressionParser struct {
+	tokens   []token
+	position int
+}
+
+func (p *expressionParser) peek() (token, bool) {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position], true
+	}
+
+	return token{}, false
+}
+
+func (p *expressionParser) expect(value string) error {
+	next, ok := p.peek()
+
+	if !ok || next.kind != operatorToken || next.value != value {
+		return errors.New(`"` + value + `" expected`)
+	}
+
+	p.position++
+	return nil
+}
+
+//binaryOperator returns the operator of the next token, keywords like "and" or "eq" are operators as well
+func (p *expressionParser) binaryOperator() (string, bool) {
+
+	next, ok := p.peek()
+
+	if !ok || (next.kind != operatorToken && next.kind != identifierToken) {
+		return "", false
+	}
+
+	operator := strings.ToLower(next.value)
+
+	if next.kind == identifierToken && !functions.StringInSlice(operator, []string{"and", "or", "eq", "neq", "lt", "le", "gt", "ge"}) {
+		return "", false
+	}
+
+	_, known := binaryPrecedence[operator]
+
+	return operator, known
+}
+
+//parseBinary parses binary operators by precedence climbing
+func (p *expressionParser) parseBinary(minPrecedence int) (expression, error) {
+
+	left, err := p.parseUnary()
+
+	if err != nil {
+		return expression{}, err
+	}
+
+	for {
+		operator, ok := p.binaryOperator()
+
+		if !ok || binaryPrecedence[operator] <= minPrecedence {
+			return left, nil
+		}
+
+		p.position++
+
+		right, err := p.parseBinary(binaryPrecedence[operator])
+
+		if err != nil {
+			return expression{}, err
+		}
+
+		left = expression{"binary", operator, []expression{left, right}}
+	}
+}
+
+func (p *expressionParser) parseUnary() (expression, error) {
+
+	next, ok := p.peek()
+
+	if ok && ((next.kind == operatorToken && (next.value == "!" || next.value == "-")) || (next.kind == identifierToken && strings.ToLower(next.value) == "not")) {
+		p.position++
+
+		operand, err := p.parseUnary()
+
+		if err != nil {
+			return expression{}, err
+		}
+
+		operator := "not"
+
+		if next.value == "-" {
+			operator = "-"
+		}
+
+		return expression{"unary", operator, []expression{operand}}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+//parsePostfix parses the methods of an expression like [name].match('^A')
+func (p *expressionParser) parsePostfix() (expression, error) {
+
+	primary, err := p.parsePrimary()
+
+	if err != nil {
+		return expression{}, err
+	}
+
+	for {
+		next, ok := p.peek()
+
+		if !ok || next.kind != operatorToken || next.value != "." {
+			return primary, nil
+		}
+
+		p.position++
+		method, ok := p.peek()
+
+		if !ok || method.kind != identifierToken {
+			return expression{}, errors.New("method name expected")
+		}
+
+		p.position++
+		arguments, err := p.parseArguments()
+
+		if err != nil {
+			return expression{}, err
+		}
+
+		primary = expression{strings.ToLower(method.value), "", append([]expression{primary}, arguments...)}
+	}
+}
+
+func (p *expressionParser) parsePrimary() (expression, error) {
+
+	next, ok := p.peek()
+
+	if !ok {
+		return expression{}, errors.New("unexpected end of expression")
+	}
+
+	p.position++
+
+	switch next.kind {
+	case attributeToken:
+		return expression{"attribute", next.value, nil}, nil
+
+	case stringToken, numberToken:
+		return expression{"literal", next.value, nil}, nil
+
+	case identifierToken:
+		//function call like pow([a], 2)
+		if following, ok := p.peek(); ok && following.kind == operatorToken && following.value == "(" {
+			arguments, err := p.parseArguments()
+
+			if err != nil {
+				return expression{}, err
+			}
+
+			return expression{"function", next.value, arguments}, nil
+		}
+
+		//keywords like true, false, null, point or polygon and variables like @zoom
+		return expression{"literal", next.value, nil}, nil
+
+	case operatorToken:
+		if next.value == "(" {
+			inner, err := p.parseBinary(0)
+
+			if err != nil {
+				return expression{}, err
+			}
+
+			return inner, p.expect(")")
+		}
+	}
+
+	return expression{}, errors.New(`unexpected "` + next.value + `"`)
+}
+
+func (p *expressionParser) parseArguments() ([]expression, error) {
+
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	arguments := make([]expression, 0)
+
+	if next, ok := p.peek(); ok && next.kind == operatorToken && next.value == ")" {
+		p.position++
+		return arguments, nil
+	}
+
+	for {
+		argument, err := p.parseBinary(0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		arguments = append(arguments, argument)
+
+		if next, ok := p.peek(); ok && next.kind == operatorToken && next.value == "," {
+			p.position++
+			continue
+		}
+
+		return arguments, p.expect(")")
+	}
+}
+
+//filter converts the parsed expression into a filter tree
+func (e expression) filter() sld.FilterNode {
+
+	switch e.kind {
+	case "binary":
+		switch e.value {
+		case "and", "&&":
+			return sld.LogicalFilter{Name: "And", Children: append(e.children[0].logicalChildren("And"), e.children[1].logicalChildren("And")...)}
+		case "or", "||":
+			return sld.LogicalFilter{Name: "Or", Children: append(e.children[0].logicalChildren("Or"), e.children[1].logicalChildren("Or")...)}
+		}
+
+		operator, isComparison := comparisonOperators[e.value]
+
+		if !isComparison {
+			break
+		}
+
+		left, right := e.children[0], e.children[1]
+
+		//the geometry type and the feature id are no columns
+		if left.isSpecialAttribute() || right.isSpecialAttribute() {
+			return sld.UnknownFilter{Name: "mapnik::" + operator}
+		}
+
+		//comparisons with null check if the attribute is missing
+		if right.isNull() || left.isNull() {
+			nullCheck := sld.NullFilter{Name: "PropertyIsNull", Expression: left.expression()}
+
+			if left.isNull() {
+				nullCheck.Expression = right.expression()
+			}
+
+			switch operator {
+			case "PropertyIsEqualTo":
+				return nullCheck
+			case "PropertyIsNotEqualTo":
+				return sld.NotFilter{Child: nullCheck}
+			}
+		}
+
+		return sld.ComparisonFilter{Name: operator, Left: left.expression(), Right: right.expression(), MatchCase: true}
+
+	case "unary":
+		if e.value == "not" {
+			return sld.NotFilter{Child: e.children[0].filter()}
+		}
+
+	case "match":
+		if len(e.children) == 2 && e.children[1].kind == "literal" && !e.children[0].isSpecialAttribute() {
+			//the whole value has to match the regular expression
+			pattern := "^(?:" + e.children[1].value + ")$"
+
+			if _, err := regexp.Compile(pattern); err == nil {
+				return sld.RegexpFilter{Expression: e.children[0].expression(), Pattern: pattern}
+			}
+		}
+	}
+
+	return sld.UnknownFilter{Name: "mapnik:" + e.kind}
+}
+
+//logicalChildren flattens nested logical operators of the same kind
+func (e expression) logicalChildren(name string) []sld.FilterNode {
+
+	child := e.filter()
+
+	if logical, ok := child.(sld.LogicalFilter); ok && logical.Name == name {
+		return logical.Children
+	}
+
+	return []sld.FilterNode{child}
+}
+
+//expression converts the parsed expression into an OGC expression
+func (e expression) expression() sld.Expression {
+
+	switch e.kind {
+	case "attribute":
+		return sld.PropertyNameExpression{Name: e.value}
+
+	case "literal":
+		return sld.LiteralExpression{Value: e.value}
+
+	case "binary":
+		if operator, isArithmetic := arithmeticOperators[e.value]; isArithmetic {
+			return sld.ArithmeticExpression{Name: operator, Left: e.children[0].expression(), Right: e.children[1].expression()}
+		}
+	}
+
+	arguments := make([]sld.Expression, 0)
+
+	for _, child := range e.children {
+		arguments = append(arguments, child.expression())
+	}
+
+	name := e.value
+
+	if e.kind != "function" {
+		name = e.kind + e.value
+	}
+
+	return sld.FunctionExpression{Name: name, Arguments: arguments}
+}
+
+func (e expression) isSpecialAttribute() bool {
+	return e.kind == "attribute" && strings.HasPrefix(e.value, "mapnik::")
+}
+
+func (e expression) isNull() bool {
+	return e.kind == "literal" && e.value == "null"
+}
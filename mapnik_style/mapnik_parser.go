@@ -0,0 +1,439 @@
+package mapnik
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//symbolizerKinds maps the Mapnik symbolizers to the symbolizer kinds of the sld package
+var symbolizerKinds = map[string][]string{
+	"PointSymbolizer":          {sld.PointKind},
+	"MarkersSymbolizer":        {sld.PointKind},
+	"DotSymbolizer":            {sld.PointKind},
+	"LineSymbolizer":           {sld.LineKind},
+	"LinePatternSymbolizer":    {sld.LineKind},
+	"PolygonSymbolizer":        {sld.PolygonKind},
+	"PolygonPatternSymbolizer": {sld.PolygonKind},
+	"BuildingSymbolizer":       {sld.PolygonKind},
+	"TextSymbolizer":           {sld.TextKind},
+	"ShieldSymbolizer":         {sld.PointKind, sld.TextKind},
+	"GroupSymbolizer":          {sld.TextKind},
+	"RasterSymbolizer":         {sld.RasterKind}}
+
+//labelAttributes are the symbolizer attributes, which contain the text of a label
+var labelAttributes = []string{"name", "text"}
+
+//Parser class
+type Parser struct {
+	filePath          string
+	successfullPasing bool
+	fileByteArray     []byte
+	tableNames        []string
+	tablePrefix       string
+	usedLayers        []string
+}
+
+//New Mapnik XML parser instance
+func New(filePath string) Parser {
+	s := Parser{filePath, false, []byte{}, nil, "", make([]string, 0)}
+	return s
+}
+
+func (s *Parser) loadMapnikFile() error {
+
+	fileExt := filepath.Ext(s.filePath)
+
+	if fileExt != ".xml" {
+		return errors.New(`"` + s.filePath + `" must be a .xml file`)
+	}
+
+	xmlFile, err := ioutil.ReadFile(s.filePath)
+
+	if err != nil {
+		return err
+	}
+
+	s.fileByteArray = xmlFile
+	s.successfullPasing = true
+
+	return nil
+}
+
+func (s *Parser) parseMap() (Map, error) {
+
+	if s.successfullPasing == false {
+		err := s.loadMapnikFile()
+
+		if err != nil {
+			return Map{}, err
+		}
+	}
+
+	var mapnikMap Map
+
+	//stylesheets often use entities for shared values, which are declared in the DTD
+	decoder := xml.NewDecoder(bytes.NewBuffer(s.fileByteArray))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	err := decoder.Decode(&mapnikMap)
+
+	if err != nil {
+		return Map{}, err
+	}
+
+	return mapnikMap, nil
+}
+
+//SetTableNames restricts the extracted requirements to the styles of the layers, which match one of the tables by their
+//name or the table of their datasource query. The first table matching at least one layer is used. A map with a single
+//layer is used completely, if no layer matches. Otherwise no style is used
+func (s *Parser) SetTableNames(tableNames []string, tablePrefix string) {
+	s.tableNames = tableNames
+	s.tablePrefix = tablePrefix
+}
+
+//LayerNames returns the names of all layers and the tables of their datasource queries
+func (s *Parser) LayerNames() ([]string, error) {
+
+	mapnikMap, err := s.parseMap()
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+
+	for _, layer := range allLayers(mapnikMap.Layers) {
+		for _, name := range layerTableNames(layer) {
+			names = functions.AppendUnique(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+//UsedLayers returns the names of the layers, which were used for the requirements of the table.
+//The list is empty, if the whole file was used
+func (s *Parser) UsedLayers() []string {
+	return s.usedLayers
+}
+
+//GetFilePath returns the path to the Mapnik XML file
+func (s *Parser) GetFilePath() string {
+	return s.filePath
+}
+
+//ExtractRequirements ( mappingColumns ) will return all required values of the Mapnik styles in form of a ParsedSLD structure.
+//The rules of the styles are handled like SLD rules, the columns of the datasource queries are required as well
+func (s *Parser) ExtractRequirements(mappingColumns sld.MappingColumnNames) (sld.ParsedSLD, error) {
+
+	mapnikMap, err := s.parseMap()
+
+	if err != nil {
+		return sld.ParsedSLD{}, err
+	}
+
+	requirements := sld.NewTableRequirements(mappingColumns)
+	styleNames, layers := s.selectedStyles(mapnikMap)
+	ruleList := make([]sld.Rule, 0)
+
+	//the columns of the datasource queries are selected from the table, filters on computed columns are resolved
+	queries := make([]SQLColumns, 0)
+
+	for _, layer := range layers {
+		queries = append(queries, ParseDatasourceSQL(layer.Datasource.parameter("table")))
+	}
+
+	query := MergeQueries(queries...)
+
+	for column, usages := range query.Columns {
+		for _, usage := range usages {
+			sld.AddRequiredColumn(&requirements, column, []string{}, sld.ScaleDenominator{MinScaleDenominator: 0, MaxScaleDenominator: -2}, usage)
+		}
+	}
+
+	for styleIndex, style := range mapnikMap.Styles {
+
+		if styleNames != nil && !functions.StringInSlice(style.Name, styleNames) {
+			continue
+		}
+
+		//with filter-mode "first" a rule only draws the features, which are not drawn by a previous rule.
+		//The rules are handled independently, which requires a superset of the columns and mapping values
+		styleRules := make([]sld.Rule, 0)
+
+		for _, mapnikRule := range style.Rules {
+			rule, err := convertRule(mapnikRule, styleIndex, query, &requirements)
+
+			if err != nil {
+				return sld.ParsedSLD{}, err
+			}
+
+			styleRules = append(styleRules, rule)
+		}
+
+		ruleList = append(ruleList, resolveAlsoFilters(style.Rules, styleRules)...)
+	}
+
+	scaleDenominator, useAllMappingTypes := sld.CompleteRequirements(ruleList, &requirements)
+
+	return sld.ParsedSLD{
+		FileName:           s.filePath,
+		Requirements:       requirements,
+		Scale:              scaleDenominator,
+		UseAllMappingTypes: useAllMappingTypes,
+		Version:            strings.TrimSpace("mapnik " + mapnikMap.MinimumVersion)}, nil
+}
+
+//selectedStyles returns the style names and layers of the first table, which matches a layer.
+//Nil is returned as style list, if no table matches a map with a single layer and all styles have to be used
+func (s *Parser) selectedStyles(mapnikMap Map) ([]string, []Layer) {
+
+	s.usedLayers = make([]string, 0)
+
+	for _, tableName := range s.tableNames {
+		styleNames := make([]string, 0)
+		layers := make([]Layer, 0)
+
+		for _, layer := range allLayers(mapnikMap.Layers) {
+			for _, name := range layerTableNames(layer) {
+				if sld.MatchesTable(name, tableName, s.tablePrefix) {
+					layers = append(layers, layer)
+					s.usedLayers = functions.AppendUnique(s.usedLayers, layer.Name)
+
+					for _, styleName := range layer.StyleNames {
+						styleNames = functions.AppendUnique(styleNames, strings.TrimSpace(styleName))
+					}
+					break
+				}
+			}
+		}
+
+		if len(layers) > 0 {
+			return styleNames, layers
+		}
+	}
+
+	if layers := allLayers(mapnikMap.Layers); len(layers) <= 1 {
+		return nil, layers
+	}
+
+	fmt.Println(`WARNING: no layer of the style file "`+s.filePath+`" matches the tables`, s.tableNames)
+
+	return []string{}, []Layer{}
+}
+
+//convertRule converts a Mapnik rule into a SLD rule and adds the columns of its symbolizers to the requirements
+func convertRule(mapnikRule Rule, styleIndex int, query SQLColumns, requirements *sld.TableRequirements) (sld.Rule, error) {
+
+	rule := sld.Rule{Name: mapnikRule.Name, FeatureTypeStyle: styleIndex}
+	var err error
+
+	if rule.MinScale, err = parseScale(mapnikRule.MinScaleDenominator); err != nil {
+		return sld.Rule{}, err
+	}
+
+	if rule.MaxScale, err = parseScale(mapnikRule.MaxScaleDenominator); err != nil {
+		return sld.Rule{}, err
+	}
+
+	scale := sld.ScaleDenominator{MinScaleDenominator: rule.MinScale, MaxScaleDenominator: rule.MaxScale}
+
+	if rule.MaxScale == 0 {
+		scale.MaxScaleDenominator = -2
+	}
+
+	if filterText := strings.TrimSpace(mapnikRule.Filter); filterText != "" {
+		rule.Filter = sld.Filter{XMLContent: []byte(filterText)}
+		filterTree, err := ParseFilter(filterText)
+
+		if err != nil {
+			fmt.Println("Parsing Error: " + err.Error())
+			filterTree = sld.UnknownFilter{Name: "Filter"}
+		}
+
+		rule.FilterTree = query.ResolveFilter(filterTree)
+
+		for _, column := range query.FilterColumns(rule.FilterTree, AttributeNames(filterText)) {
+			sld.AddRequiredColumn(requirements, column, []string{}, scale, sld.FilterUsage)
+		}
+	}
+
+	if mapnikRule.ElseFilter != nil {
+		rule.ElseFilter = &sld.Filter{}
+	}
+
+	for _, symbolizer := range mapnikRule.Elements {
+		kinds, isSymbolizer := symbolizerKinds[symbolizer.XMLName.Local]
+
+		if !isSymbolizer {
+			continue
+		}
+
+		//Mapnik has no units of measure, all sizes are pixels
+		symbolizers := []sld.Symbolizer{{XMLContent: []byte(symbolizer.Text), UnitOfMeasure: sld.PixelUnit}}
+
+		for _, kind := range kinds {
+			switch kind {
+			case sld.PointKind:
+				rule.PointSymbolizer = symbolizers
+			case sld.LineKind:
+				rule.LineSymbolizer = symbolizers
+			case sld.PolygonKind:
+				rule.PolygonSymbolizer = symbolizers
+			case sld.TextKind:
+				rule.TextSymbolizer = symbolizers
+			case sld.RasterKind:
+				rule.RasterSymbolizer = symbolizers
+			}
+		}
+
+		for name, usage := range symbolizerColumns(symbolizer, symbolizer.XMLName.Local == "TextSymbolizer" || symbolizer.XMLName.Local == "ShieldSymbolizer") {
+			for _, column := range query.SourceColumns(name) {
+				sld.AddRequiredColumn(requirements, column, []string{}, scale, usage)
+			}
+		}
+	}
+
+	return rule, nil
+}
+
+//symbolizerColumns returns the attributes used by a symbolizer and its child elements with their usage.
+//The content of text and shield symbolizers and their name attribute contain the label
+func symbolizerColumns(symbolizer element, isLabel bool) map[string]string {
+
+	columns := make(map[string]string)
+
+	add := func(text string, usage string) {
+		for _, column := range AttributeNames(text) {
+			if columns[column] != sld.LabelUsage {
+				columns[column] = usage
+			}
+		}
+	}
+
+	for _, attr := range symbolizer.Attrs {
+		if isLabel && functions.StringInSlice(attr.Name.Local, labelAttributes) {
+			add(attr.Value, sld.LabelUsage)
+		} else {
+			add(attr.Value, sld.StyleUsage)
+		}
+	}
+
+	if isLabel {
+		add(symbolizer.Text, sld.LabelUsage)
+	}
+
+	for _, child := range symbolizer.Children {
+		for column, usage := range symbolizerColumns(child, isLabel) {
+			if columns[column] != sld.LabelUsage {
+				columns[column] = usage
+			}
+		}
+	}
+
+	return columns
+}
+
+//resolveAlsoFilters replaces the AlsoFilter of a rule by the filters of all other rules of the style.
+//If one of the other rules has no filter, the rule matches all features
+func resolveAlsoFilters(mapnikRules []Rule, ruleList []sld.Rule) []sld.Rule {
+
+	for i := range ruleList {
+
+		if mapnikRules[i].AlsoFilter == nil {
+			continue
+		}
+
+		siblingFilters := make([]sld.FilterNode, 0)
+
+		for j, sibling := range ruleList {
+			if mapnikRules[j].AlsoFilter != nil || mapnikRules[j].ElseFilter != nil {
+				continue
+			}
+
+			if sibling.FilterTree == nil {
+				siblingFilters = nil
+				break
+			}
+
+			siblingFilters = append(siblingFilters, sibling.FilterTree)
+		}
+
+		if len(siblingFilters) > 0 {
+			ruleList[i].FilterTree = sld.LogicalFilter{Name: "Or", Children: siblingFilters}
+		}
+	}
+
+	return ruleList
+}
+
+//parseScale parses a scale denominator, which can be a double like 50000.0
+func parseScale(text string) (int, error) {
+
+	text = strings.TrimSpace(text)
+
+	if text == "" {
+		return 0, nil
+	}
+
+	scale, err := strconv.ParseFloat(text, 64)
+
+	if err != nil {
+		return 0, errors.New(`invalid scale denominator "` + text + `"`)
+	}
+
+	return int(math.Round(scale)), nil
+}
+
+//allLayers returns the layers and their nested layers
+func allLayers(layers []Layer) []Layer {
+
+	result := make([]Layer, 0)
+
+	for _, layer := range layers {
+		result = append(result, layer)
+		result = append(result, allLayers(layer.Layers)...)
+	}
+
+	return result
+}
+
+//layerTableNames returns the name of a layer and the tables of its datasource query
+func layerTableNames(layer Layer) []string {
+	return append([]string{layer.Name}, ParseDatasourceSQL(layer.Datasource.parameter("table")).Tables...)
+}
+
+//MergeQueries combines the tables, columns and aliases of several datasource queries
+func MergeQueries(queries ...SQLColumns) SQLColumns {
+
+	merged := SQLColumns{make([]string, 0), make(map[string][]string), make(map[string][]AliasSource)}
+
+	for _, query := range queries {
+		for _, table := range query.Tables {
+			merged.Tables = functions.AppendUnique(merged.Tables, table)
+		}
+
+		for column, usages := range query.Columns {
+			for _, usage := range usages {
+				merged.Columns[column] = functions.AppendUnique(merged.Columns[column], usage)
+			}
+		}
+
+		for alias, sources := range query.Aliases {
+			merged.Aliases[alias] = append(merged.Aliases[alias], sources...)
+		}
+	}
+
+	return merged
+}
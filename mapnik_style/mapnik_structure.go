@@ -0,0 +1,72 @@
+package mapnik
+
+import "encoding/xml"
+
+//########### Mapnik XML structures ###########//
+
+//Map is the root element of a Mapnik XML stylesheet
+type Map struct {
+	MinimumVersion string  `xml:"minimum-version,attr"`
+	Styles         []Style `xml:"Style"`
+	Layers         []Layer `xml:"Layer"`
+}
+
+//Style contains the rules of a named style
+//FilterMode = "all" or "first", with "first" only the first matching rule is drawn
+type Style struct {
+	Name       string `xml:"name,attr"`
+	FilterMode string `xml:"filter-mode,attr"`
+	Rules      []Rule `xml:"Rule"`
+}
+
+//Rule describes the structure of a rule in a Mapnik style
+//ElseFilter = matches all features, which are not matched by another rule of the style
+//AlsoFilter = matches all features, which are matched by another rule of the style
+type Rule struct {
+	Name                string    `xml:"name,attr"`
+	MinScaleDenominator string    `xml:"MinScaleDenominator"`
+	MaxScaleDenominator string    `xml:"MaxScaleDenominator"`
+	Filter              string    `xml:"Filter"`
+	ElseFilter          *element  `xml:"ElseFilter"`
+	AlsoFilter          *element  `xml:"AlsoFilter"`
+	Elements            []element `xml:",any"`
+}
+
+//Layer references the styles, which are drawn with the features of its datasource
+type Layer struct {
+	Name       string     `xml:"name,attr"`
+	StyleNames []string   `xml:"StyleName"`
+	Datasource Datasource `xml:"Datasource"`
+	Layers     []Layer    `xml:"Layer"`
+}
+
+//Datasource contains the parameters of a layer datasource, e.g. type, dbname and table
+type Datasource struct {
+	Parameters []Parameter `xml:"Parameter"`
+}
+
+//Parameter is a named datasource parameter
+type Parameter struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+//element contains an arbitrary XML element, used for the symbolizers and their content
+type element struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []element  `xml:",any"`
+}
+
+//parameter returns the value of a datasource parameter
+func (d Datasource) parameter(name string) string {
+
+	for _, parameter := range d.Parameters {
+		if parameter.Name == name {
+			return parameter.Value
+		}
+	}
+
+	return ""
+}
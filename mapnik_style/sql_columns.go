@@ -0,0 +1,359 @@
+package mapnik
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"strings"
+	"unicode"
+)
+
+//sqlKeywords are the words of a datasource query, which are no column names
+var sqlKeywords = []string{"select", "from", "where", "and", "or", "not", "as", "in", "is", "null", "like", "ilike", "between",
+	"case", "when", "then", "else", "end", "order", "by", "group", "having", "limit", "offset", "asc", "desc", "nulls", "first",
+	"last", "join", "left", "right", "inner", "outer", "full", "cross", "on", "using", "union", "all", "distinct", "true", "false",
+	"cast", "with", "lateral", "exists", "any", "some", "similar", "to", "over", "partition", "filter", "within", "interval",
+	"integer", "int", "bigint", "text", "varchar", "numeric", "real", "float", "double", "precision", "boolean"}
+
+//SQLColumns contains the tables of a datasource query and the columns used in its clauses
+//Columns = the usages (filter, sort or style) of every column
+//Aliases = the source columns of the computed result columns like "'highway_' || highway AS feature"
+type SQLColumns struct {
+	Tables  []string
+	Columns map[string][]string
+	Aliases map[string][]AliasSource
+}
+
+//AliasSource is a column used by a result column
+//Prefix = the string, which is concatenated in front of the value
+//Computed = the column is used in a computation (e.g. a CASE condition), the result is no value of the column
+type AliasSource struct {
+	Prefix   string
+	Column   string
+	Computed bool
+}
+
+//ParseDatasourceSQL returns the tables and columns of a PostGIS datasource query like
+//"(SELECT way, highway, name FROM osm_roads WHERE tunnel <> 'yes' ORDER BY z_order) AS data".
+//Columns of the WHERE clause are used for filtering, columns of the ORDER BY clause for sorting
+func ParseDatasourceSQL(query string) SQLColumns {
+
+	result := SQLColumns{make([]string, 0), make(map[string][]string), make(map[string][]AliasSource)}
+
+	//the table parameter can also be a plain table name
+	if query = strings.TrimSpace(query); query != "" && !strings.ContainsAny(query, " \t\n()") {
+		result.Tables = append(result.Tables, unqualifiedName(query))
+		return result
+	}
+
+	usage := sld.StyleUsage
+	previous := ""
+	expectTable := false
+	inTableList := false
+
+	//the columns of the current select expression and the parenthesis depth of its select list
+	sources := make([]AliasSource, 0)
+	expressionLength := 0
+	prefix := ""
+	depth := 0
+	selectDepth := 0
+
+	for _, word := range sqlTokens(query) {
+		lower := strings.ToLower(word)
+		isName := isSQLIdentifier(word) && !functions.StringInSlice(lower, sqlKeywords) && !isSQLFunction(word)
+		isTable := false
+
+		switch lower {
+		case "from", "join":
+			expectTable = true
+		case "where", "on", "having":
+			usage = sld.FilterUsage
+		case "order":
+			usage = sld.SortUsage
+		case "select", "group":
+			usage = sld.StyleUsage
+		}
+
+		switch {
+		case expectTable && isName:
+			result.Tables = functions.AppendUnique(result.Tables, unqualifiedName(word))
+			isTable = true
+		case inTableList && word == ",":
+			//the next entry of a FROM list is a table as well
+			expectTable = true
+		case inTableList && (isName || lower == "as"):
+			//table alias
+		case previous == "as" && isName && usage == sld.StyleUsage:
+			//only a renamed column keeps the values, other columns without prefix are computed
+			for i := range sources {
+				sources[i].Computed = sources[i].Prefix == "" && (len(sources) > 1 || expressionLength > 1)
+			}
+
+			if len(sources) > 0 {
+				result.Aliases[unqualifiedName(word)] = sources
+			}
+		case previous == "as" || previous == "::" || strings.HasPrefix(word, "!") || strings.HasPrefix(word, "@"):
+			//casts and tokens like !bbox! or @zoom are no columns
+		case isName:
+			name := unqualifiedName(word)
+			result.Columns[name] = functions.AppendUnique(result.Columns[name], usage)
+
+			if usage == sld.StyleUsage {
+				sources = append(sources, AliasSource{prefix, name, false})
+				prefix = ""
+			}
+		}
+
+		//a string concatenated with a column, e.g. 'highway_' || highway
+		if strings.HasPrefix(word, "'") {
+			prefix = strings.Trim(word, "'")
+		} else if word != "|" && word != "(" && !functions.StringInSlice(lower, []string{"case", "when"}) {
+			prefix = ""
+		}
+
+		if word != "::" && previous != "::" && lower != "as" {
+			expressionLength++
+		}
+
+		switch {
+		case lower == "select" || (word == "," && depth == selectDepth):
+			if lower == "select" {
+				selectDepth = depth
+			}
+
+			sources = make([]AliasSource, 0)
+			expressionLength = 0
+		case word == "(" || isSQLFunction(word):
+			depth++
+		case word == ")":
+			depth--
+		}
+
+		inTableList = isTable || (inTableList && (isName || lower == "as" || word == ","))
+
+		if isTable || (!isName && word != "," && lower != "from" && lower != "join") {
+			expectTable = false
+		}
+
+		previous = lower
+	}
+
+	//aliases of the tables are no columns
+	for _, table := range result.Tables {
+		delete(result.Columns, table)
+	}
+
+	return result
+}
+
+//SourceColumns returns the columns of the table, which are used by a result column of the query
+func (c SQLColumns) SourceColumns(name string) []string {
+
+	sources, isAlias := c.Aliases[name]
+
+	if !isAlias {
+		return []string{name}
+	}
+
+	columns := make([]string, 0)
+
+	for _, source := range sources {
+		columns = functions.AppendUnique(columns, source.Column)
+	}
+
+	return columns
+}
+
+//FilterColumns returns the columns of the table, which are used by a resolved filter and the attributes of its expression
+func (c SQLColumns) FilterColumns(filter sld.FilterNode, attributes []string) []string {
+
+	columns := make([]string, 0)
+
+	for _, name := range append(sld.FilterPropertyNames(filter), attributes...) {
+		for _, column := range c.SourceColumns(name) {
+			columns = functions.AppendUnique(columns, column)
+		}
+	}
+
+	return columns
+}
+
+//ResolveFilter replaces the result columns of the query in a filter by the columns of the table.
+//A comparison of a prefixed alias like [feature] = 'highway_primary' becomes [highway] = 'primary',
+//filters on computed columns, which can not be resolved, are unknown filters
+func (c SQLColumns) ResolveFilter(filter sld.FilterNode) sld.FilterNode {
+
+	switch node := filter.(type) {
+	case sld.LogicalFilter:
+		children := make([]sld.FilterNode, 0, len(node.Children))
+
+		for _, child := range node.Children {
+			children = append(children, c.ResolveFilter(child))
+		}
+
+		return sld.LogicalFilter{Name: node.Name, Children: children}
+
+	case sld.NotFilter:
+		return sld.NotFilter{Child: c.ResolveFilter(node.Child)}
+
+	case sld.ComparisonFilter:
+		property, isProperty := node.Left.(sld.PropertyNameExpression)
+		literal, isLiteral := node.Right.(sld.LiteralExpression)
+
+		if !isProperty || !c.isAlias(property.Name) {
+			break
+		}
+
+		if source, found := c.aliasSource(property.Name, literal.Value); found && isLiteral {
+			node.Left = sld.PropertyNameExpression{Name: source.Column}
+			node.Right = sld.LiteralExpression{Value: strings.TrimPrefix(literal.Value, source.Prefix)}
+			return node
+		}
+
+		return sld.UnknownFilter{Name: node.Name}
+
+	case sld.NullFilter:
+		if property, isProperty := node.Expression.(sld.PropertyNameExpression); isProperty && c.isAlias(property.Name) {
+			if source, found := c.aliasSource(property.Name, ""); found && source.Prefix == "" {
+				node.Expression = sld.PropertyNameExpression{Name: source.Column}
+				return node
+			}
+
+			return sld.UnknownFilter{Name: node.Name}
+		}
+
+	case sld.RegexpFilter:
+		if property, isProperty := node.Expression.(sld.PropertyNameExpression); isProperty && c.isAlias(property.Name) {
+			if source, found := c.aliasSource(property.Name, ""); found && source.Prefix == "" {
+				node.Expression = sld.PropertyNameExpression{Name: source.Column}
+				return node
+			}
+
+			return sld.UnknownFilter{Name: node.Operator()}
+		}
+	}
+
+	return filter
+}
+
+func (c SQLColumns) isAlias(name string) bool {
+	_, isAlias := c.Aliases[name]
+	return isAlias
+}
+
+//aliasSource returns the source column of an alias, which produces the value. The longest matching prefix is used,
+//an alias with several columns without prefix can not be resolved
+func (c SQLColumns) aliasSource(name string, value string) (AliasSource, bool) {
+
+	sources := c.Aliases[name]
+
+	if len(sources) == 1 && sources[0].Prefix == "" && !sources[0].Computed {
+		return sources[0], true
+	}
+
+	var best *AliasSource
+
+	for i, source := range sources {
+		if source.Prefix != "" && strings.HasPrefix(value, source.Prefix) && (best == nil || len(source.Prefix) > len(best.Prefix)) {
+			best = &sources[i]
+		}
+	}
+
+	if best == nil {
+		return AliasSource{}, false
+	}
+
+	return *best, true
+}
+
+//sqlTokens splits a query into identifiers, strings, numbers and operators. String literals keep their quotes
+func sqlTokens(query string) []string {
+
+	tokens := make([]string, 0)
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+
+			if end < len(runes) {
+				end++
+			}
+
+			tokens = append(tokens, string(runes[i:end]))
+			i = end
+
+		case r == '"' || unicode.IsLetter(r) || r == '_' || r == '!' && i+1 < len(runes) && unicode.IsLetter(runes[i+1]):
+			end := i
+			for end < len(runes) {
+				if runes[end] == '"' {
+					for end++; end < len(runes) && runes[end] != '"'; end++ {
+					}
+					end++
+				} else if unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || strings.ContainsRune("_.!", runes[end]) {
+					end++
+				} else {
+					break
+				}
+			}
+
+			if end > len(runes) {
+				end = len(runes)
+			}
+
+			tokens = append(tokens, string(runes[i:end]))
+
+			//a function call is marked by its opening parenthesis
+			if next := skipSpaces(runes, end); next < len(runes) && runes[next] == '(' && !functions.StringInSlice(strings.ToLower(tokens[len(tokens)-1]), sqlKeywords) {
+				tokens[len(tokens)-1] += "("
+				end = next + 1
+			}
+			i = end
+
+		case r == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			tokens = append(tokens, "::")
+			i += 2
+
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+
+	return tokens
+}
+
+func skipSpaces(runes []rune, position int) int {
+	for position < len(runes) && unicode.IsSpace(runes[position]) {
+		position++
+	}
+
+	return position
+}
+
+func isSQLIdentifier(word string) bool {
+	first := []rune(word)[0]
+	return first == '"' || unicode.IsLetter(first) || first == '_'
+}
+
+func isSQLFunction(word string) bool {
+	return strings.HasSuffix(word, "(")
+}
+
+//unqualifiedName removes the schema or table qualifier and the quotes of an identifier
+func unqualifiedName(word string) string {
+
+	if index := strings.LastIndex(word, "."); index != -1 {
+		word = word[index+1:]
+	}
+
+	return strings.Trim(word, `"`)
+}
@@ -0,0 +1,139 @@
+package mapping
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"fmt"
+	"strings"
+)
+
+//geometryTypes contains the imposm3 table types of single geometries and the keys of their type mappings
+var geometryTypes = []string{"point", "linestring", "polygon"}
+
+//RowCounter counts the rows of an imported table, whose geometry has one of the geometry types
+type RowCounter interface {
+	CountRows(tableName string, geometryColumn string, geometryTypes []string) (int64, error)
+}
+
+//geometryRecommendation describes how the geometry type of a table can be narrowed.
+//newType = narrower table type, empty if the table type cannot be changed
+//unusedTypes = stored geometry types, which are never drawn
+type geometryRecommendation struct {
+	newType     string
+	unusedTypes []string
+	message     string
+}
+
+//recommendGeometryType checks which geometry types of a table are drawn by the symbolizers of its styles.
+//The type mappings of the new table are already pruned
+func recommendGeometryType(table Table, newTable Table, kinds []string) geometryRecommendation {
+
+	recommendation := geometryRecommendation{}
+
+	switch table.Type {
+	case "geometry":
+		drawnTypes := make([]string, 0)
+
+		for _, geometryType := range geometryTypes {
+			stored := true
+			drawn := sld.DrawsGeometry(kinds, geometryType)
+
+			//only the geometry types of the type mappings are stored
+			if table.TypeMappings != nil {
+				stored = len(getTypeMapping(*table.TypeMappings, geometryType)) > 0
+				drawn = drawn && newTable.TypeMappings != nil && len(getTypeMapping(*newTable.TypeMappings, geometryType)) > 0
+			}
+
+			if stored && drawn {
+				drawnTypes = append(drawnTypes, geometryType)
+			} else if stored {
+				recommendation.unusedTypes = append(recommendation.unusedTypes, geometryType)
+			}
+		}
+
+		if len(drawnTypes) == 1 {
+			recommendation.newType = drawnTypes[0]
+			recommendation.message = `only ` + drawnTypes[0] + ` geometries are drawn, the table type can be narrowed to "` + drawnTypes[0] + `"`
+		} else if len(drawnTypes) == 0 {
+			recommendation.message = "no geometry is drawn, the table can be removed"
+		} else if len(recommendation.unusedTypes) > 0 {
+			recommendation.message = strings.Join(recommendation.unusedTypes, ", ") + " geometries are never drawn"
+		}
+
+	case "point", "linestring", "polygon":
+		if !sld.DrawsGeometry(kinds, table.Type) {
+			recommendation.unusedTypes = []string{table.Type}
+			recommendation.message = "no geometry is drawn, the table can be removed"
+		} else if table.Type != "point" && !functions.StringInSlice(sld.LineKind, kinds) && !functions.StringInSlice(sld.PolygonKind, kinds) {
+			recommendation.message = "only point and text symbolizers are drawn, the geometries are only used for label points"
+		}
+	}
+
+	return recommendation
+}
+
+//applyGeometryRecommendation narrows the table type, the type mapping of the new type becomes the mapping of the table
+func applyGeometryRecommendation(newTable *Table, recommendation geometryRecommendation) {
+
+	if recommendation.newType == "" {
+		return
+	}
+
+	if newTable.TypeMappings != nil {
+		newTable.Mapping = getTypeMapping(*newTable.TypeMappings, recommendation.newType)
+		newTable.TypeMappings = nil
+	}
+
+	newTable.Type = recommendation.newType
+	fmt.Println(`- Table type changed to "` + recommendation.newType + `"`)
+}
+
+//reportGeometryRecommendation prints the recommendation and the number of rows of the unused geometry types
+func (m *mappingParser) reportGeometryRecommendation(tableName string, table Table, recommendation geometryRecommendation) {
+
+	if recommendation.message == "" {
+		return
+	}
+
+	fmt.Println("- Geometry types: " + recommendation.message)
+
+	if m.rowCounter == nil || len(recommendation.unusedTypes) == 0 {
+		return
+	}
+
+	geometryColumn := ""
+
+	for _, column := range table.Columns {
+		if column.Type == "geometry" || column.Type == "validated_geometry" {
+			geometryColumn = column.Name
+		}
+	}
+
+	if geometryColumn == "" {
+		return
+	}
+
+	rows, err := m.rowCounter.CountRows(tableName, geometryColumn, recommendation.unusedTypes)
+
+	if err != nil {
+		fmt.Println("- WARNING: rows of unused geometry types could not be counted: " + err.Error())
+		return
+	}
+
+	fmt.Println("- Rows saved by removing the unused geometry types:", rows)
+}
+
+//getTypeMapping returns the type mapping of a geometry type
+func getTypeMapping(typeMappings TypeMappings, geometryType string) map[string][]string {
+
+	switch geometryType {
+	case "point":
+		return typeMappings.Points
+	case "linestring":
+		return typeMappings.LineStrings
+	case "polygon":
+		return typeMappings.Polygons
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package mapping
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//the json package has no inline maps, therefore the unknown keys of the mapping structures are handled by these functions
+
+//unmarshalJSONWithExtra decodes the json object into the structure and stores all keys, which are not part of the structure, in extra
+func unmarshalJSONWithExtra(data []byte, structure interface{}, extra *map[string]interface{}) error {
+
+	err := json.Unmarshal(data, structure)
+
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]json.RawMessage)
+
+	err = json.Unmarshal(data, &values)
+
+	if err != nil {
+		return err
+	}
+
+	knownKeys := jsonFieldNames(reflect.TypeOf(structure).Elem())
+
+	for key, rawValue := range values {
+		if knownKeys[key] {
+			continue
+		}
+
+		var value interface{}
+
+		err = json.Unmarshal(rawValue, &value)
+
+		if err != nil {
+			return err
+		}
+
+		if *extra == nil {
+			*extra = make(map[string]interface{})
+		}
+
+		(*extra)[key] = value
+	}
+
+	return nil
+}
+
+//marshalJSONWithExtra encodes the structure and appends the unknown keys at the end of the json object
+func marshalJSONWithExtra(structure interface{}, extra map[string]interface{}) ([]byte, error) {
+
+	data, err := json.Marshal(structure)
+
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0)
+
+	for key := range extra {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var buffer bytes.Buffer
+
+	buffer.Write(data[:len(data)-1])
+
+	for i, key := range keys {
+		keyData, _ := json.Marshal(key)
+		valueData, err := json.Marshal(extra[key])
+
+		if err != nil {
+			return nil, err
+		}
+
+		if i > 0 || len(data) > 2 {
+			buffer.WriteByte(',')
+		}
+
+		buffer.Write(keyData)
+		buffer.WriteByte(':')
+		buffer.Write(valueData)
+	}
+
+	buffer.WriteByte('}')
+
+	return buffer.Bytes(), nil
+}
+
+//jsonFieldNames returns the json keys of all fields of a structure
+func jsonFieldNames(structType reflect.Type) map[string]bool {
+
+	names := make(map[string]bool)
+
+	for i := 0; i < structType.NumField(); i++ {
+		name := strings.Split(structType.Field(i).Tag.Get("json"), ",")[0]
+
+		if name == "" {
+			name = structType.Field(i).Name
+		}
+
+		names[name] = true
+	}
+
+	return names
+}
+
+func (t *Tags) UnmarshalJSON(data []byte) error {
+	type tags Tags
+	return unmarshalJSONWithExtra(data, (*tags)(t), &t.Extra)
+}
+
+func (t Tags) MarshalJSON() ([]byte, error) {
+	type tags Tags
+	return marshalJSONWithExtra(tags(t), t.Extra)
+}
+
+func (g *GeneralizedTable) UnmarshalJSON(data []byte) error {
+	type generalizedTable GeneralizedTable
+	return unmarshalJSONWithExtra(data, (*generalizedTable)(g), &g.Extra)
+}
+
+func (g GeneralizedTable) MarshalJSON() ([]byte, error) {
+	type generalizedTable GeneralizedTable
+	return marshalJSONWithExtra(generalizedTable(g), g.Extra)
+}
+
+func (a *Areas) UnmarshalJSON(data []byte) error {
+	type areas Areas
+	return unmarshalJSONWithExtra(data, (*areas)(a), &a.Extra)
+}
+
+func (a Areas) MarshalJSON() ([]byte, error) {
+	type areas Areas
+	return marshalJSONWithExtra(areas(a), a.Extra)
+}
+
+func (f *TableFilter) UnmarshalJSON(data []byte) error {
+	type tableFilter TableFilter
+	return unmarshalJSONWithExtra(data, (*tableFilter)(f), &f.Extra)
+}
+
+func (f TableFilter) MarshalJSON() ([]byte, error) {
+	type tableFilter TableFilter
+	return marshalJSONWithExtra(tableFilter(f), f.Extra)
+}
+
+func (c *TableColumn) UnmarshalJSON(data []byte) error {
+	type tableColumn TableColumn
+	return unmarshalJSONWithExtra(data, (*tableColumn)(c), &c.Extra)
+}
+
+func (c TableColumn) MarshalJSON() ([]byte, error) {
+	type tableColumn TableColumn
+	return marshalJSONWithExtra(tableColumn(c), c.Extra)
+}
+
+func (t *TableMapping) UnmarshalJSON(data []byte) error {
+	type tableMapping TableMapping
+	return unmarshalJSONWithExtra(data, (*tableMapping)(t), &t.Extra)
+}
+
+func (t TableMapping) MarshalJSON() ([]byte, error) {
+	type tableMapping TableMapping
+	return marshalJSONWithExtra(tableMapping(t), t.Extra)
+}
+
+func (t *TypeMappings) UnmarshalJSON(data []byte) error {
+	type typeMappings TypeMappings
+	return unmarshalJSONWithExtra(data, (*typeMappings)(t), &t.Extra)
+}
+
+func (t TypeMappings) MarshalJSON() ([]byte, error) {
+	type typeMappings TypeMappings
+	return marshalJSONWithExtra(typeMappings(t), t.Extra)
+}
+
+func (t *Table) UnmarshalJSON(data []byte) error {
+	type table Table
+	return unmarshalJSONWithExtra(data, (*table)(t), &t.Extra)
+}
+
+func (t Table) MarshalJSON() ([]byte, error) {
+	type table Table
+	return marshalJSONWithExtra(table(t), t.Extra)
+}
+
+func (m *Mapping) UnmarshalJSON(data []byte) error {
+	type mapping Mapping
+	return unmarshalJSONWithExtra(data, (*mapping)(m), &m.Extra)
+}
+
+func (m Mapping) MarshalJSON() ([]byte, error) {
+	type mapping Mapping
+	return marshalJSONWithExtra(mapping(m), m.Extra)
+}
@@ -7,13 +7,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
+//numericColumnTypes contains all imposm3 column types, which are stored as number
+var numericColumnTypes = []string{"id", "integer", "boolint", "direction", "enumerate", "categorize", "zorder", "wayzorder", "area", "webmerc_area", "pseudoarea"}
+
+//relationMemberColumnTypes contains all imposm3 column types, which describe the member of a relation
+var relationMemberColumnTypes = []string{"member_id", "member_role", "member_type", "member_index"}
+
 type mappingParser struct {
 	filePath            string
 	successfullPasing   bool
@@ -21,13 +29,19 @@ type mappingParser struct {
 	sourceFileType      string
 	forceFiltering      bool
 	allowResearch       bool
-	toleranceScaling    float32
+	toleranceScaling    float64
 	requiredColumnTypes []string
+	scaleBands          map[string]sld.ScaleDenominator
+	toleranceSettings   *ToleranceSettings
+	mappingNode         *yamlv3.Node
+	mappingFormat       mappingFormat
+	applyGeometryTypes  bool
+	rowCounter          RowCounter
 }
 
 //New (filePath) createts a new parser object, file path to the mapping file is requiered
-func New(filePath string, allowResearch bool, forceFiltering bool, toleranceScaling float32, requiredColumnTypes []string) mappingParser {
-	m := mappingParser{filePath, false, Mapping{}, "", forceFiltering, allowResearch, toleranceScaling, requiredColumnTypes}
+func New(filePath string, allowResearch bool, forceFiltering bool, toleranceScaling float64, requiredColumnTypes []string) mappingParser {
+	m := mappingParser{filePath, false, Mapping{}, "", forceFiltering, allowResearch, toleranceScaling, requiredColumnTypes, make(map[string]sld.ScaleDenominator), nil, nil, mappingFormat{2, false, nil}, false, nil}
 	return m
 }
 
@@ -46,6 +60,12 @@ func (m *mappingParser) parseMappingFileYAML() {
 		panic(err)
 	}
 
+	//the node tree is used to rewrite the file with its comments, key order and anchors
+	m.mappingNode, m.mappingFormat, err = parseMappingNode(yamlFile)
+	if err != nil {
+		panic(err)
+	}
+
 	m.mappingRoot = root
 	m.successfullPasing = true
 }
@@ -102,7 +122,7 @@ func (m *mappingParser) GetMappingColumnName(tableName string) sld.MappingColumn
 		m.GetMappingContent()
 	}
 
-	if m.mappingRoot.GeneralizedTables[tableName] != (GeneralizedTable{}) {
+	if _, isGenTable := m.mappingRoot.GeneralizedTables[tableName]; isGenTable {
 		tableName = m.GetGeneralizedRootSourceTable(tableName)
 	}
 
@@ -119,7 +139,8 @@ func (m *mappingParser) GetMappingColumnName(tableName string) sld.MappingColumn
 	return mappingColumns
 }
 
-func (m *mappingParser) buildMappingFile(newMappingStructure Mapping) []byte {
+//BuildMappingFile returns the content of a mapping file in the format of the parsed mapping file
+func (m *mappingParser) BuildMappingFile(newMappingStructure Mapping) []byte {
 	var fileContent []byte
 	var err error
 
@@ -127,7 +148,11 @@ func (m *mappingParser) buildMappingFile(newMappingStructure Mapping) []byte {
 	case ".json":
 		fileContent, err = json.MarshalIndent(newMappingStructure, "", "    ")
 	case ".yaml":
-		fileContent, err = yaml.Marshal(newMappingStructure)
+		if m.mappingNode != nil {
+			fileContent, err = buildMappingNode(m.mappingNode, newMappingStructure, m.mappingFormat)
+		} else {
+			fileContent, err = yaml.Marshal(newMappingStructure)
+		}
 	default:
 		if err != nil {
 			panic(`Cannot build mapping file: Unknown file type "` + m.sourceFileType + `!`)
@@ -141,7 +166,13 @@ func (m *mappingParser) buildMappingFile(newMappingStructure Mapping) []byte {
 	return fileContent
 }
 
+//RebuildMappingStructure returns the content of the mapping file, which only contains the required columns and mapping values
 func (m *mappingParser) RebuildMappingStructure(parsedSLDs map[string][]sld.ParsedSLD) []byte {
+	return m.BuildMappingFile(m.RebuildMapping(parsedSLDs))
+}
+
+//RebuildMapping returns the mapping structure, which only contains the columns and mapping values required by the styles
+func (m *mappingParser) RebuildMapping(parsedSLDs map[string][]sld.ParsedSLD) Mapping {
 	if m.successfullPasing == false {
 		panic("Try to rebuild a non existing mapping structure!")
 	}
@@ -149,8 +180,10 @@ func (m *mappingParser) RebuildMappingStructure(parsedSLDs map[string][]sld.Pars
 	newMappingRoot := new(Mapping)
 
 	newMappingRoot.Areas = m.mappingRoot.Areas
-	newMappingRoot.GeneralizedTables = m.mappingRoot.GeneralizedTables
+	newMappingRoot.GeneralizedTables = make(map[string]GeneralizedTable)
 	newMappingRoot.Tags = m.mappingRoot.Tags
+	newMappingRoot.UseSingleIDSpace = m.mappingRoot.UseSingleIDSpace
+	newMappingRoot.Extra = m.mappingRoot.Extra
 	newMappingRoot.Tables = make(map[string]Table)
 
 	//build all known tables
@@ -163,7 +196,9 @@ func (m *mappingParser) RebuildMappingStructure(parsedSLDs map[string][]sld.Pars
 		//copy static table data
 		newTable.Type = table.Type
 		newTable.RelationTypes = table.RelationTypes
-		newTable.Filter = table.Filter
+		newTable.TypeMappings = table.TypeMappings
+		newTable.UseSingleTable = table.UseSingleTable
+		newTable.Extra = table.Extra
 
 		//merge the parsed sld data to a list
 		combinedRequirements := sld.TableRequirements{}
@@ -183,8 +218,15 @@ func (m *mappingParser) RebuildMappingStructure(parsedSLDs map[string][]sld.Pars
 			fmt.Println("- Related generalized tables:", relatedGenTables)
 		}
 
+		//generalized tables only need the requirements of the rules, which are visible in their scale band
 		for _, relGenTable := range relatedGenTables {
+			band, hasBand := m.getScaleBand(relGenTable, parsedSLDs)
+
 			for _, comparedTable := range parsedSLDs[relGenTable] {
+				if hasBand {
+					comparedTable = comparedTable.InScale(band)
+				}
+
 				appendRequirements(&combinedRequirements, comparedTable)
 
 				if comparedTable.UseAllMappingTypes {
@@ -195,12 +237,16 @@ func (m *mappingParser) RebuildMappingStructure(parsedSLDs map[string][]sld.Pars
 
 		requiredColumnList := combinedRequirements.RequiredColumnList
 		requiredMappingValues := combinedRequirements.RequiredMappingValues
-		implicitFilteredValues := combinedRequirements.ImplicitFilteredValues
 
-		buildColumnList(table, newTable, requiredColumnList, m.allowResearch, useAllMappingTypes, m.requiredColumnTypes, implicitFilteredValues)
+		buildColumnList(table, newTable, requiredColumnList, m.allowResearch, m.requiredColumnTypes)
+		buildTableFilter(table, newTable, combinedRequirements.ColumnValueSets)
+
+		if table.TypeMappings != nil && len(combinedRequirements.RuleScales) > 0 {
+			newTable.TypeMappings = buildTypeMappings(*table.TypeMappings, combinedRequirements, m.forceFiltering)
+		}
 
-		if len(requiredMappingValues) > 0 && (!useAllMappingTypes || m.forceFiltering) {
-			buildMappingValueList(table, newTable, requiredMappingValues, m.allowResearch)
+		if (len(requiredMappingValues) > 0 || len(combinedRequirements.RequiredMappingPatterns) > 0) && (!useAllMappingTypes || m.forceFiltering) {
+			buildMappingValueList(table, newTable, requiredMappingValues, combinedRequirements.RequiredMappingPatterns, m.allowResearch)
 		} else {
 			fmt.Println("- Not all filter tags filter a mapping type, therefore all existing mapping types are used!")
 
@@ -208,6 +254,16 @@ func (m *mappingParser) RebuildMappingStructure(parsedSLDs map[string][]sld.Pars
 			newTable.Mappings = table.Mappings
 		}
 
+		//narrow the geometry types to the types, which are drawn by the symbolizers
+		if len(combinedRequirements.RuleScales) > 0 {
+			recommendation := recommendGeometryType(table, *newTable, combinedRequirements.SymbolizerKinds)
+			m.reportGeometryRecommendation(tableName, table, recommendation)
+
+			if m.applyGeometryTypes {
+				applyGeometryRecommendation(newTable, recommendation)
+			}
+		}
+
 		newMappingRoot.Tables[tableName] = *newTable
 
 		fmt.Println("")
@@ -220,24 +276,29 @@ func (m *mappingParser) RebuildMappingStructure(parsedSLDs map[string][]sld.Pars
 
 		//copy static table data
 		newGenTable.Source = table.Source
+		newGenTable.GeometryTransform = table.GeometryTransform
+		newGenTable.Extra = table.Extra
 
 		//merge the parsed sld data to a list
 		combinedRequirements := sld.TableRequirements{}
 		useAllMappingTypes := false
 
-		//get the minimum min scale
-		var minScale int = -1
+		band, hasBand := m.getScaleBand(genTableName, parsedSLDs)
+
+		if hasBand {
+			fmt.Println("- Scale band:", band.MinScaleDenominator, "-", scaleText(band.MaxScaleDenominator))
+		}
 
 		for _, comparedTable := range parsedSLDs[genTableName] {
+			if hasBand {
+				comparedTable = comparedTable.InScale(band)
+			}
+
 			appendRequirements(&combinedRequirements, comparedTable)
 
 			if comparedTable.UseAllMappingTypes {
 				useAllMappingTypes = true
 			}
-
-			if minScale == -1 || minScale > comparedTable.Scale.MinScaleDenominator {
-				minScale = comparedTable.Scale.MinScaleDenominator
-			}
 		}
 
 		relatedGenTables := m.getRelatedGeneralizedTables(genTableName)
@@ -247,7 +308,13 @@ func (m *mappingParser) RebuildMappingStructure(parsedSLDs map[string][]sld.Pars
 		}
 
 		for _, relGenTable := range relatedGenTables {
+			relBand, hasRelBand := m.getScaleBand(relGenTable, parsedSLDs)
+
 			for _, comparedTable := range parsedSLDs[relGenTable] {
+				if hasRelBand {
+					comparedTable = comparedTable.InScale(relBand)
+				}
+
 				appendRequirements(&combinedRequirements, comparedTable)
 
 				if comparedTable.UseAllMappingTypes {
@@ -256,21 +323,28 @@ func (m *mappingParser) RebuildMappingStructure(parsedSLDs map[string][]sld.Pars
 			}
 		}
 
-		newGenTable.SQLFilter = generateSQLFilter(m.GetMappingColumnName(genTableName), combinedRequirements.RequiredColumnList, combinedRequirements.RequiredMappingValues, table.SQLFilter, (useAllMappingTypes && !m.forceFiltering))
+		sourceColumns := m.mappingRoot.Tables[m.GetGeneralizedRootSourceTable(genTableName)].Columns
+
+		newGenTable.SQLFilter = generateSQLFilter(m.GetMappingColumnName(genTableName), combinedRequirements.RequiredColumnList, combinedRequirements.RequiredMappingValues, combinedRequirements.RequiredMappingPatterns, combinedRequirements.NumericRanges, sourceColumns, table.SQLFilter, (useAllMappingTypes && !m.forceFiltering))
 
 		if newGenTable.SQLFilter != "" {
 			fmt.Println("- SQL-Filter: " + newGenTable.SQLFilter)
 		}
 
-		newGenTable.Tolerance = float64(minScale) * (float64(m.toleranceScaling) / float64(100.0))
-		fmt.Println("- Tolerance:", newGenTable.Tolerance)
+		//the minimum scale of the scale band is used for the tolerance, without a scale band the tolerance is kept
+		if hasBand {
+			newGenTable.Tolerance = m.tolerance(band.MinScaleDenominator)
+		} else {
+			newGenTable.Tolerance = table.Tolerance
+			fmt.Println("- Tolerance is kept:", newGenTable.Tolerance)
+		}
 
 		newMappingRoot.GeneralizedTables[genTableName] = *newGenTable
 
 		fmt.Println("")
 	}
 
-	return m.buildMappingFile(*newMappingRoot)
+	return *newMappingRoot
 }
 
 func appendRequirements(source *sld.TableRequirements, new sld.ParsedSLD) {
@@ -287,25 +361,126 @@ func appendRequirements(source *sld.TableRequirements, new sld.ParsedSLD) {
 					source.RequiredColumnList[foundAt].Literals = append(source.RequiredColumnList[foundAt].Literals, literal)
 				}
 			}
+
+			for _, scale := range value.Scales {
+				source.RequiredColumnList[foundAt].Scales = sld.MergeScale(source.RequiredColumnList[foundAt].Scales, scale)
+			}
+
+			for _, usage := range value.Usages {
+				if !functions.StringInSlice(usage, source.RequiredColumnList[foundAt].Usages) {
+					source.RequiredColumnList[foundAt].Usages = append(source.RequiredColumnList[foundAt].Usages, usage)
+				}
+			}
 		}
 	}
 
+	if source.MappingValueScales == nil {
+		source.MappingValueScales = make(map[string][]sld.ScaleDenominator)
+		source.MappingPatternScales = make(map[string][]sld.ScaleDenominator)
+	}
+
 	//add all found required types
 	for _, value := range new.Requirements.RequiredMappingValues {
 		if !functions.StringInSlice(value, source.RequiredMappingValues) {
 			source.RequiredMappingValues = append(source.RequiredMappingValues, value)
 		}
+
+		for _, scale := range new.Requirements.MappingValueScales[value] {
+			source.MappingValueScales[value] = sld.MergeScale(source.MappingValueScales[value], scale)
+		}
 	}
 
+	//add all found required type patterns
+	for _, value := range new.Requirements.RequiredMappingPatterns {
+		if !functions.StringInSlice(value, source.RequiredMappingPatterns) {
+			source.RequiredMappingPatterns = append(source.RequiredMappingPatterns, value)
+		}
+
+		for _, scale := range new.Requirements.MappingPatternScales[value] {
+			source.MappingPatternScales[value] = sld.MergeScale(source.MappingPatternScales[value], scale)
+		}
+	}
+
+	for _, scale := range new.Requirements.UnfilteredScales {
+		source.UnfilteredScales = sld.MergeScale(source.UnfilteredScales, scale)
+	}
+
+	//add the symbolizer kinds of all mapping values
+	if source.MappingValueKinds == nil {
+		source.MappingValueKinds = make(map[string][]string)
+		source.MappingPatternKinds = make(map[string][]string)
+	}
+
+	for value, kinds := range new.Requirements.MappingValueKinds {
+		source.MappingValueKinds[value] = appendKinds(source.MappingValueKinds[value], kinds)
+	}
+
+	for pattern, kinds := range new.Requirements.MappingPatternKinds {
+		source.MappingPatternKinds[pattern] = appendKinds(source.MappingPatternKinds[pattern], kinds)
+	}
+
+	source.UnfilteredKinds = appendKinds(source.UnfilteredKinds, new.Requirements.UnfilteredKinds)
+	source.SymbolizerKinds = appendKinds(source.SymbolizerKinds, new.Requirements.SymbolizerKinds)
+	source.SymbolizerUnits = appendKinds(source.SymbolizerUnits, new.Requirements.SymbolizerUnits)
+
+	//add all distinct rule scales
+	for _, scale := range new.Requirements.RuleScales {
+		found := false
+
+		for _, sourceScale := range source.RuleScales {
+			if sourceScale == scale {
+				found = true
+			}
+		}
+
+		if !found {
+			source.RuleScales = append(source.RuleScales, scale)
+		}
+	}
+
+	//add the numeric ranges of all rules
+	source.NumericRanges = append(source.NumericRanges, new.Requirements.NumericRanges...)
+
 	//add all found implicit filtered values
 	for _, value := range new.Requirements.ImplicitFilteredValues {
 		if !functions.StringInSlice(value, source.ImplicitFilteredValues) {
 			source.ImplicitFilteredValues = append(source.ImplicitFilteredValues, value)
 		}
 	}
+
+	//combine the filtered column values, a column must be filtered by all styles to stay filtered
+	if source.ColumnValueSets == nil {
+		source.ColumnValueSets = make(map[string]sld.ValueSet)
+
+		for column, valueSet := range new.Requirements.ColumnValueSets {
+			source.ColumnValueSets[column] = valueSet
+		}
+	} else {
+		source.ColumnValueSets = sld.MergeColumnValueSets(source.ColumnValueSets, new.Requirements.ColumnValueSets)
+	}
 }
 
 //getter setter
+func (m *mappingParser) SetScaleBands(scaleBands map[string]sld.ScaleDenominator) {
+	m.scaleBands = make(map[string]sld.ScaleDenominator)
+
+	for tableName, band := range scaleBands {
+		m.scaleBands[tableName] = band
+	}
+}
+
+func (m *mappingParser) SetToleranceSettings(toleranceSettings *ToleranceSettings) {
+	m.toleranceSettings = toleranceSettings
+}
+
+func (m *mappingParser) SetApplyGeometryTypes(applyGeometryTypes bool) {
+	m.applyGeometryTypes = applyGeometryTypes
+}
+
+func (m *mappingParser) SetRowCounter(rowCounter RowCounter) {
+	m.rowCounter = rowCounter
+}
+
 func (m *mappingParser) IsParsed() bool {
 	return m.successfullPasing
 }
@@ -351,7 +526,7 @@ func (m *mappingParser) GetGeneralizedRootSourceTable(genTabelName string) strin
 
 	genSourceRootTable := genTabelName
 
-	if m.mappingRoot.GeneralizedTables[genSourceRootTable] != (GeneralizedTable{}) {
+	if _, isGenTable := m.mappingRoot.GeneralizedTables[genSourceRootTable]; isGenTable {
 		genSourceRootTable = m.mappingRoot.GeneralizedTables[genSourceRootTable].Source
 		genSourceRootTable = m.GetGeneralizedRootSourceTable(genSourceRootTable)
 	}
@@ -359,6 +534,21 @@ func (m *mappingParser) GetGeneralizedRootSourceTable(genTabelName string) strin
 	return genSourceRootTable
 }
 
+//GetSourceTableNames returns the table name followed by the names of all source tables of a generalized table
+func (m *mappingParser) GetSourceTableNames(tableName string) []string {
+	if m.successfullPasing == false {
+		m.GetMappingContent()
+	}
+
+	tableNames := []string{tableName}
+
+	if genTable, isGenTable := m.mappingRoot.GeneralizedTables[tableName]; isGenTable {
+		tableNames = append(tableNames, m.GetSourceTableNames(genTable.Source)...)
+	}
+
+	return tableNames
+}
+
 func (m *mappingParser) RemoveTableFromRoot(tableName string) {
 	delete(m.mappingRoot.Tables, tableName)
 }
@@ -436,7 +626,7 @@ func guessColumnType(literals []string) string {
 	return "string"
 }
 
-func buildColumnList(rootTable Table, newTable *Table, requiredColumnList []sld.RequiredColumn, allowResearch bool, useAllMappingTypes bool, requiredColumnTypes []string, implicitFilteredValues []string) {
+func buildColumnList(rootTable Table, newTable *Table, requiredColumnList []sld.RequiredColumn, allowResearch bool, requiredColumnTypes []string) {
 	if len(requiredColumnList) > 0 {
 
 		newTable.Columns = make([]TableColumn, 0)
@@ -446,9 +636,18 @@ func buildColumnList(rootTable Table, newTable *Table, requiredColumnList []sld.
 
 		for _, column := range rootTable.Columns {
 
-			found, _ := sld.ColumnInColumnlist(column.Name, requiredColumnList)
+			found, foundAt := sld.ColumnInColumnlist(column.Name, requiredColumnList)
 			required := found || functions.StringInSlice(column.Type, requiredColumnTypes)
 
+			if found && !functions.StringInSlice(column.Type, requiredColumnTypes) {
+				fmt.Println(`- Tabel column kept "` + column.Name + `", used for ` + strings.Join(requiredColumnList[foundAt].Usages, ", "))
+			}
+
+			//the member columns describe the rows of a relation member table and are always kept
+			if rootTable.Type == "relation_member" && functions.StringInSlice(column.Type, relationMemberColumnTypes) {
+				required = true
+			}
+
 			if required {
 
 				newTable.Columns = append(newTable.Columns, column)
@@ -474,7 +673,7 @@ func buildColumnList(rootTable Table, newTable *Table, requiredColumnList []sld.
 						columnType := guessColumnType(rColumn.Literals)
 						fmt.Println(`-  Key found. Tabel column "` + rColumn.PropertyName + `" added, data type "` + columnType + `" was guessed`)
 
-						newColumn := TableColumn{columnType, rColumn.PropertyName, "", nil, false}
+						newColumn := TableColumn{columnType, rColumn.PropertyName, "", nil, false, nil}
 						newTable.Columns = append(newTable.Columns, newColumn)
 
 					} else {
@@ -483,24 +682,52 @@ func buildColumnList(rootTable Table, newTable *Table, requiredColumnList []sld.
 				}
 			}
 		}
+	} else {
+		newTable.Columns = rootTable.Columns
+	}
+}
+
+//filterMappingValues returns the values of a mapping class, which are required or match a required pattern.
+//The wildcard "__any__" is replaced by the required values, if no pattern is required
+func filterMappingValues(keyList []string, requiredMappingValues []string, requiredMappingPatterns []string, usedRequiredMappingTypes *[]string) ([]string, []string) {
 
-		if !useAllMappingTypes {
-			for _, implicitFilteredValue := range implicitFilteredValues {
+	patternSet := sld.ValueSet{Patterns: requiredMappingPatterns, Exact: true}
 
-				splitLable := strings.Split(implicitFilteredValue, ":")
+	keptKeys := make([]string, 0)
+	excludedKeys := make([]string, 0)
 
-				key := splitLable[0]
-				value := splitLable[1]
+	for _, key := range keyList {
 
-				newTable.Filter.Reject[key] = append(newTable.Filter.Reject[key], value)
+		if key == anyValue {
+			if len(requiredMappingPatterns) > 0 {
+				keptKeys = append(keptKeys, key)
+			} else {
+				for _, rType := range requiredMappingValues {
+					if !functions.StringInSlice(rType, keyList) {
+						keptKeys = append(keptKeys, rType)
+					}
+				}
 			}
+
+			*usedRequiredMappingTypes = append(*usedRequiredMappingTypes, requiredMappingValues...)
+
+		} else if functions.StringInSlice(key, requiredMappingValues) {
+			keptKeys = append(keptKeys, key)
+			*usedRequiredMappingTypes = append(*usedRequiredMappingTypes, key)
+
+		} else if patternSet.Contains(key) {
+			//the pattern only matches a known subset of the mapping values
+			keptKeys = append(keptKeys, key)
+
+		} else {
+			excludedKeys = append(excludedKeys, key)
 		}
-	} else {
-		newTable.Columns = rootTable.Columns
 	}
+
+	return keptKeys, excludedKeys
 }
 
-func buildMappingValueList(rootTable Table, newTable *Table, requiredMappingValues []string, allowResearch bool) {
+func buildMappingValueList(rootTable Table, newTable *Table, requiredMappingValues []string, requiredMappingPatterns []string, allowResearch bool) {
 
 	if len(rootTable.Mapping) > 0 {
 
@@ -508,15 +735,15 @@ func buildMappingValueList(rootTable Table, newTable *Table, requiredMappingValu
 
 		usedRequiredMappingTypes := make([]string, 0)
 		for class, keyList := range rootTable.Mapping {
-			for _, key := range keyList {
 
-				if functions.StringInSlice(key, requiredMappingValues) {
-					newTable.Mapping[class] = append(newTable.Mapping[class], key)
+			keptKeys, excludedKeys := filterMappingValues(keyList, requiredMappingValues, requiredMappingPatterns, &usedRequiredMappingTypes)
 
-					usedRequiredMappingTypes = append(usedRequiredMappingTypes, key)
-				} else {
-					fmt.Println(`- Mapping value excluded in mapping class "` + class + `:` + key + `"`)
-				}
+			if len(keptKeys) > 0 {
+				newTable.Mapping[class] = keptKeys
+			}
+
+			for _, key := range excludedKeys {
+				fmt.Println(`- Mapping value excluded in mapping class "` + class + `:` + key + `"`)
 			}
 		}
 
@@ -554,23 +781,24 @@ func buildMappingValueList(rootTable Table, newTable *Table, requiredMappingValu
 		usedRequiredMappingTypes := make([]string, 0)
 
 		for mainClass, mappingList := range rootTable.Mappings {
-			for class, keyList := range mappingList.Mapping {
 
-				newMapping := new(TableMapping)
-				newMapping.Mapping = make(map[string][]string)
+			newMapping := new(TableMapping)
+			newMapping.Mapping = make(map[string][]string)
 
-				for _, key := range keyList {
+			for class, keyList := range mappingList.Mapping {
 
-					if functions.StringInSlice(key, requiredMappingValues) {
-						newMapping.Mapping[class] = append(newMapping.Mapping[class], key)
-						usedRequiredMappingTypes = append(usedRequiredMappingTypes, key)
-					} else {
-						fmt.Println(`- Mapping value "` + key + `" excluded in mapping class "` + mainClass + `"`)
-					}
+				keptKeys, excludedKeys := filterMappingValues(keyList, requiredMappingValues, requiredMappingPatterns, &usedRequiredMappingTypes)
+
+				if len(keptKeys) > 0 {
+					newMapping.Mapping[class] = keptKeys
 				}
 
-				newTable.Mappings[mainClass] = *newMapping
+				for _, key := range excludedKeys {
+					fmt.Println(`- Mapping value "` + key + `" excluded in mapping class "` + mainClass + `"`)
+				}
 			}
+
+			newTable.Mappings[mainClass] = *newMapping
 		}
 
 		for _, rType := range requiredMappingValues {
@@ -615,7 +843,7 @@ func (m *mappingParser) getRelatedGeneralizedTables(tableName string) []string {
 
 	for genTableName, genTable := range m.mappingRoot.GeneralizedTables {
 		if genTable.Source == tableName {
-			foundGenTable = m.getRelatedGeneralizedTables(genTableName)
+			foundGenTable = append(foundGenTable, m.getRelatedGeneralizedTables(genTableName)...)
 			foundGenTable = append(foundGenTable, genTableName)
 		}
 	}
@@ -623,9 +851,46 @@ func (m *mappingParser) getRelatedGeneralizedTables(tableName string) []string {
 	return foundGenTable
 }
 
+//getScaleBand returns the scale range in which a generalized table is rendered.
+//A configured scale band is preferred, otherwise the scale range of all rules of the table styles is used.
+//The second return value is false, if the scale band is unknown
+func (m *mappingParser) getScaleBand(genTableName string, parsedSLDs map[string][]sld.ParsedSLD) (sld.ScaleDenominator, bool) {
+
+	if band, found := m.scaleBands[genTableName]; found {
+		return band, true
+	}
+
+	bands := make([]sld.ScaleDenominator, 0)
+
+	for _, parsedSLD := range parsedSLDs[genTableName] {
+		if parsedSLD.Scale.MinScaleDenominator != -1 {
+			bands = sld.MergeScale(bands, parsedSLD.Scale)
+		}
+	}
+
+	if len(bands) == 0 {
+		return sld.ScaleDenominator{}, false
+	}
+
+	//gaps between the styles are part of the scale band
+	return sld.ScaleDenominator{MinScaleDenominator: bands[0].MinScaleDenominator, MaxScaleDenominator: bands[len(bands)-1].MaxScaleDenominator}, true
+}
+
+//scaleText returns the scale denominator as text, -2 is an infinite scale denominator
+func scaleText(scale int) string {
+	if scale == -2 {
+		return "∞"
+	}
+
+	return strconv.Itoa(scale)
+}
+
 func generateSQLFilter(mappingColumns sld.MappingColumnNames,
 	requiredColumnList []sld.RequiredColumn,
 	requiredMappingValues []string,
+	requiredMappingPatterns []string,
+	numericRanges []sld.NumericRangeRequirement,
+	tableColumns []TableColumn,
 	oldSQLFilter string,
 	useAllMappingTypes bool) string {
 
@@ -639,40 +904,124 @@ func generateSQLFilter(mappingColumns sld.MappingColumnNames,
 		//the new filter is generated, if not then the old filter is used.
 		found, _ := sld.ColumnInColumnlist(mappingColumns.MappingValueColumnName, requiredColumnList)
 
+		//All predicates are combined with OR
+		predicates := make([]string, 0)
+
 		if found && len(requiredMappingValues) > 0 {
 
 			//Set column name before IN operator
-			filter = mappingColumns.MappingValueColumnName + " IN ("
+			predicate := mappingColumns.MappingValueColumnName + " IN ("
 
 			//Add each literal separated by commas
 			for i, mappingValue := range requiredMappingValues {
 				if i > 0 {
-					filter = filter + ", '" + mappingValue + "'"
+					predicate = predicate + ", " + sqlQuote(mappingValue)
 				} else {
-					filter = filter + "'" + mappingValue + "'"
+					predicate = predicate + sqlQuote(mappingValue)
 				}
 			}
 
 			//Close enumeration with )
-			filter = filter + ")"
+			predicates = append(predicates, predicate+")")
+		}
+
+		if found {
+			//Add each pattern as POSIX regular expression match
+			for _, pattern := range requiredMappingPatterns {
+				predicates = append(predicates, mappingColumns.MappingValueColumnName+" ~ "+sqlQuote(pattern))
+			}
+		}
+
+		filter = strings.Join(predicates, " OR ")
+
+		if len(predicates) > 1 {
+			filter = "(" + filter + ")"
 		}
 
 		if oldSQLFilter != "" && filter != "" {
 			//Minimize the old filter so that only unused filter statements are left
 			oldSQLFilter = discardMappingValueFilter(oldSQLFilter, mappingColumns)
+		}
+	}
 
-			if oldSQLFilter != "" {
-				//Append old filter to the new SQL filter
-				filter = filter + " AND " + oldSQLFilter
-			}
+	//All filters are combined with AND
+	filters := make([]string, 0)
+
+	if filter != "" {
+		filters = append(filters, filter)
+	}
+
+	//The numeric ranges do not depend on the mapping values, each numeric column gets its own range filter
+	for _, column := range tableColumns {
+		if !functions.StringInSlice(column.Type, numericColumnTypes) {
+			continue
+		}
+
+		ranges, constrained := sld.CombineNumericRanges(numericRanges, column.Name)
+
+		if constrained {
+			filters = append(filters, numericRangePredicate(column.Name, ranges))
 		}
 	}
 
-	if filter == "" {
-		filter = oldSQLFilter
+	if strings.TrimSpace(oldSQLFilter) != "" {
+		//Append old filter to the new SQL filter
+		filters = append(filters, strings.TrimSpace(oldSQLFilter))
 	}
 
-	return filter
+	return strings.Join(filters, " AND ")
+}
+
+//numericRangePredicate builds a SQL predicate from a range set, the intervals are combined with OR
+func numericRangePredicate(columnName string, ranges sld.NumericRangeSet) string {
+
+	predicates := make([]string, 0)
+
+	for _, interval := range ranges.Intervals {
+
+		min := strconv.FormatFloat(interval.Min, 'f', -1, 64)
+		max := strconv.FormatFloat(interval.Max, 'f', -1, 64)
+
+		lowerOperator := " > "
+		if interval.MinInclusive {
+			lowerOperator = " >= "
+		}
+
+		upperOperator := " < "
+		if interval.MaxInclusive {
+			upperOperator = " <= "
+		}
+
+		switch {
+		case math.IsInf(interval.Min, -1) && math.IsInf(interval.Max, 1):
+			predicates = append(predicates, columnName+" IS NOT NULL")
+		case math.IsInf(interval.Min, -1):
+			predicates = append(predicates, columnName+upperOperator+max)
+		case math.IsInf(interval.Max, 1):
+			predicates = append(predicates, columnName+lowerOperator+min)
+		case interval.Min == interval.Max:
+			predicates = append(predicates, columnName+" = "+min)
+		case interval.MinInclusive && interval.MaxInclusive:
+			predicates = append(predicates, columnName+" BETWEEN "+min+" AND "+max)
+		default:
+			predicates = append(predicates, "("+columnName+lowerOperator+min+" AND "+columnName+upperOperator+max+")")
+		}
+	}
+
+	if ranges.Null {
+		predicates = append(predicates, columnName+" IS NULL")
+	}
+
+	if len(predicates) == 0 {
+		//no value of the column reaches a symbolizer
+		return "FALSE"
+	}
+
+	if len(predicates) > 1 {
+		return "(" + strings.Join(predicates, " OR ") + ")"
+	}
+
+	return predicates[0]
 }
 
 func discardMappingValueFilter(sqlFilter string, mappingColumns sld.MappingColumnNames) string {
@@ -710,3 +1059,8 @@ func discardMappingValueFilter(sqlFilter string, mappingColumns sld.MappingColum
 
 	return newFilter
 }
+
+//sqlQuote returns a SQL string literal, single quotes are escaped
+func sqlQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -1,57 +1,77 @@
 package mapping
 
 //file parsing structures
+//every structure keeps unknown keys in the Extra map, so that no configuration is lost when the mapping file is rewritten
 
-//Tags wip
+//Tags contains the tags which are stored in hstore_tags columns, with load_all all tags are stored except the excluded tags
 type Tags struct {
-	LoadAll bool     `yaml:"load_all,omitempty" json:"load_all,omitempty"`
-	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
-	Include []string `yaml:"include,omitempty" json:"include,omitempty"`
+	LoadAll bool                   `yaml:"load_all,omitempty" json:"load_all,omitempty"`
+	Exclude []string               `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	Include []string               `yaml:"include,omitempty" json:"include,omitempty"`
+	Extra   map[string]interface{} `yaml:",inline" json:"-"`
 }
 
-//GeneralizedTable wip
+//GeneralizedTable contains all informations about one generalized table exported from the mappingfile
 type GeneralizedTable struct {
-	Source    string  `yaml:"source" json:"source"`
-	SQLFilter string  `yaml:"sql_filter,omitempty" json:"sql_filter,omitempty"`
-	Tolerance float64 `yaml:"tolerance" json:"tolerance"`
+	Source            string                 `yaml:"source" json:"source"`
+	SQLFilter         string                 `yaml:"sql_filter,omitempty" json:"sql_filter,omitempty"`
+	Tolerance         float64                `yaml:"tolerance" json:"tolerance"`
+	GeometryTransform string                 `yaml:"geometry_transform,omitempty" json:"geometry_transform,omitempty"`
+	Extra             map[string]interface{} `yaml:",inline" json:"-"`
 }
 
-//Areas wip
+//Areas contains the tags which decide if a closed way is a polygon or a linestring
 type Areas struct {
-	AreaTags   []string `yaml:"area_tags" json:"area_tags"`
-	LinearTags []string `yaml:"linear_tags" json:"linear_tags"`
+	AreaTags   []string               `yaml:"area_tags" json:"area_tags"`
+	LinearTags []string               `yaml:"linear_tags" json:"linear_tags"`
+	Extra      map[string]interface{} `yaml:",inline" json:"-"`
 }
 
-//TableFilter wip
+//TableFilter contains the imposm3 table filters, a regular expression filter has exactly one expression per key
 type TableFilter struct {
-	Require       map[string][]string `yaml:"require" json:"require"`
-	Reject        map[string][]string `yaml:"reject" json:"reject"`
-	RequireRegexp map[string][]string `yaml:"require_regexp" json:"require_regexp"`
-	RejectRegexp  map[string][]string `yaml:"reject_regexp" json:"reject_regexp"`
+	Require       map[string][]string    `yaml:"require,omitempty" json:"require,omitempty"`
+	Reject        map[string][]string    `yaml:"reject,omitempty" json:"reject,omitempty"`
+	RequireRegexp map[string]string      `yaml:"require_regexp,omitempty" json:"require_regexp,omitempty"`
+	RejectRegexp  map[string]string      `yaml:"reject_regexp,omitempty" json:"reject_regexp,omitempty"`
+	Extra         map[string]interface{} `yaml:",inline" json:"-"`
 }
 
-//TableColumn contains all informations about a table column from a mapping file
+//TableColumn contains all informations about a table column from a mapping file.
+//Arguments contains the column type arguments, e.g. the values of an enumerate column
 type TableColumn struct {
 	Type       string                 `yaml:"type" json:"type"`
 	Name       string                 `yaml:"name" json:"name"`
 	Key        string                 `yaml:"key,omitempty" json:"key,omitempty"`
 	Arguments  map[string]interface{} `yaml:"args,omitempty" json:"args,omitempty"`
 	FromMember bool                   `yaml:"from_member,omitempty" json:"from_member,omitempty"`
+	Extra      map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 //TableMapping contains mapping values in a map key=mapping key value=array of mapping values
 type TableMapping struct {
-	Mapping map[string][]string `yaml:"mapping,flow" json:"mapping,flow"`
+	Mapping map[string][]string    `yaml:"mapping,flow" json:"mapping,flow"`
+	Extra   map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+//TypeMappings contains the mapping values of a geometry table for each geometry type
+type TypeMappings struct {
+	Points      map[string][]string    `yaml:"points,flow,omitempty" json:"points,flow,omitempty"`
+	LineStrings map[string][]string    `yaml:"linestrings,flow,omitempty" json:"linestrings,flow,omitempty"`
+	Polygons    map[string][]string    `yaml:"polygons,flow,omitempty" json:"polygons,flow,omitempty"`
+	Extra       map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 //Table structure contains all informations about one table exported from the mappingfile
 type Table struct {
-	Type          string                  `yaml:"type" json:"type"`
-	Columns       []TableColumn           `yaml:"columns" json:"columns"`
-	Mapping       map[string][]string     `yaml:"mapping,flow,omitempty" json:"mapping,flow,omitempty"`
-	Mappings      map[string]TableMapping `yaml:"mappings,flow,omitempty" json:"mappings,flow,omitempty"`
-	RelationTypes []string                `yaml:"relation_types,flow,omitempty" json:"relation_types,flow,omitempty"`
-	Filter        *TableFilter            `yaml:"filters,omitempty" json:"filters,omitempty"`
+	Type           string                  `yaml:"type" json:"type"`
+	Columns        []TableColumn           `yaml:"columns" json:"columns"`
+	Mapping        map[string][]string     `yaml:"mapping,flow,omitempty" json:"mapping,flow,omitempty"`
+	Mappings       map[string]TableMapping `yaml:"mappings,flow,omitempty" json:"mappings,flow,omitempty"`
+	TypeMappings   *TypeMappings           `yaml:"type_mappings,omitempty" json:"type_mappings,omitempty"`
+	RelationTypes  []string                `yaml:"relation_types,flow,omitempty" json:"relation_types,flow,omitempty"`
+	Filter         *TableFilter            `yaml:"filters,omitempty" json:"filters,omitempty"`
+	UseSingleTable bool                    `yaml:"use_single_table,omitempty" json:"use_single_table,omitempty"`
+	Extra          map[string]interface{}  `yaml:",inline" json:"-"`
 }
 
 //Mapping Root of the mapping file
@@ -60,4 +80,6 @@ type Mapping struct {
 	Areas             *Areas                      `yaml:"areas" json:"areas"`
 	GeneralizedTables map[string]GeneralizedTable `yaml:"generalized_tables" json:"generalized_tables"`
 	Tags              *Tags                       `yaml:"tags,omitempty" json:"tags,omitempty"`
+	UseSingleIDSpace  bool                        `yaml:"use_single_id_space,omitempty" json:"use_single_id_space,omitempty"`
+	Extra             map[string]interface{}      `yaml:",inline" json:"-"`
 }
@@ -0,0 +1,219 @@
+package mapping
+
+import (
+	"Imposm_Optimizer/sld"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+)
+
+//minimumBandRatio is the minimum ratio between the maximum and minimum scale of a proposed generalized table
+const minimumBandRatio = 2
+
+//generalizedTableIndex matches the number at the end of a generalized table name
+var generalizedTableIndex = regexp.MustCompile(`_gen(\d+)$`)
+
+//AssignScaleBands infers the scale band of every generalized table from its tolerance, the band ends at the minimum scale
+//of the next generalized table of the same root table. Generalized tables without own styles use the styles of their root table,
+//so that they only get the requirements of the rules which are visible in their scale band. Configured scale bands are kept
+func (m *mappingParser) AssignScaleBands(parsedSLDs map[string][]sld.ParsedSLD) map[string][]sld.ParsedSLD {
+
+	assignedSLDs := make(map[string][]sld.ParsedSLD)
+
+	for tableName, parsedSLDList := range parsedSLDs {
+		assignedSLDs[tableName] = parsedSLDList
+	}
+
+	if !m.canCalculateTolerance() {
+		fmt.Println("- The tolerance scaling is not set, therefore no scale bands can be assigned!")
+		return assignedSLDs
+	}
+
+	for _, tableName := range m.GetTableNames() {
+		genTables := m.getGeneralizedTablesByTolerance(tableName)
+
+		for i, genTableName := range genTables {
+
+			if band, found := m.scaleBands[genTableName]; found {
+				fmt.Println(`- Generalized table "`+genTableName+`" keeps the configured scale band:`, band.MinScaleDenominator, "-", scaleText(band.MaxScaleDenominator))
+			} else {
+				band := sld.ScaleDenominator{MinScaleDenominator: m.toleranceScale(m.mappingRoot.GeneralizedTables[genTableName].Tolerance), MaxScaleDenominator: -2}
+
+				if i+1 < len(genTables) {
+					band.MaxScaleDenominator = m.toleranceScale(m.mappingRoot.GeneralizedTables[genTables[i+1]].Tolerance)
+				}
+
+				m.scaleBands[genTableName] = band
+				fmt.Println(`- Generalized table "`+genTableName+`" gets the scale band:`, band.MinScaleDenominator, "-", scaleText(band.MaxScaleDenominator))
+			}
+
+			if _, found := assignedSLDs[genTableName]; !found && len(parsedSLDs[tableName]) > 0 {
+				assignedSLDs[genTableName] = parsedSLDs[tableName]
+				fmt.Println(`  uses the styles of table "` + tableName + `"`)
+			}
+		}
+	}
+
+	return assignedSLDs
+}
+
+//ProposeGeneralizedTables searches for wide scale bands, which are served by the full resolution tables.
+//A new generalized table is proposed at every rule scale boundary, which is at least minimumBandRatio times smaller
+//than the end of the band and the previous proposal. The proposals are returned as mapping file section
+func (m *mappingParser) ProposeGeneralizedTables(parsedSLDs map[string][]sld.ParsedSLD) []byte {
+
+	proposals := make(map[string]GeneralizedTable)
+
+	if !m.canCalculateTolerance() {
+		fmt.Println("- The tolerance scaling is not set, therefore no generalized tables can be proposed!")
+		return nil
+	}
+
+	for _, tableName := range m.GetTableNames() {
+
+		if len(parsedSLDs[tableName]) == 0 {
+			continue
+		}
+
+		combinedRequirements := sld.TableRequirements{}
+
+		for _, parsedSLD := range parsedSLDs[tableName] {
+			appendRequirements(&combinedRequirements, parsedSLD)
+		}
+
+		//the full resolution table is used until the first generalized table
+		genTables := m.getGeneralizedTablesByTolerance(tableName)
+		bandEnd := -2
+
+		if len(genTables) > 0 {
+			bandEnd = m.toleranceScale(m.mappingRoot.GeneralizedTables[genTables[0]].Tolerance)
+
+			if band, found := m.scaleBands[genTables[0]]; found {
+				bandEnd = band.MinScaleDenominator
+			}
+		}
+
+		boundaries := make([]int, 0)
+
+		for _, scale := range combinedRequirements.RuleScales {
+			for _, boundary := range []int{scale.MinScaleDenominator, scale.MaxScaleDenominator} {
+				if boundary > 0 && (bandEnd == -2 || boundary*minimumBandRatio <= bandEnd) {
+					boundaries = append(boundaries, boundary)
+				}
+			}
+		}
+
+		sort.Ints(boundaries)
+
+		proposedScales := make([]int, 0)
+
+		for _, boundary := range boundaries {
+			if len(proposedScales) == 0 || boundary >= proposedScales[len(proposedScales)-1]*minimumBandRatio {
+				proposedScales = append(proposedScales, boundary)
+			}
+		}
+
+		index := m.nextGeneralizedTableIndex(tableName)
+		mappingColumns := m.GetMappingColumnName(tableName)
+
+		//the most generalized proposal gets the lowest index, every proposal reads from the next less generalized proposal
+		proposalName := func(i int) string {
+			return tableName + "_gen" + strconv.Itoa(index+len(proposedScales)-1-i)
+		}
+
+		for i, proposedScale := range proposedScales {
+			band := sld.ScaleDenominator{MinScaleDenominator: proposedScale, MaxScaleDenominator: bandEnd}
+
+			if i+1 < len(proposedScales) {
+				band.MaxScaleDenominator = proposedScales[i+1]
+			}
+
+			bandRequirements := sld.TableRequirements{}
+			useAllMappingTypes := false
+
+			for _, parsedSLD := range parsedSLDs[tableName] {
+				parsedSLD = parsedSLD.InScale(band)
+				appendRequirements(&bandRequirements, parsedSLD)
+
+				if parsedSLD.UseAllMappingTypes {
+					useAllMappingTypes = true
+				}
+			}
+
+			proposal := GeneralizedTable{}
+			proposal.Source = tableName
+
+			if i > 0 {
+				proposal.Source = proposalName(i - 1)
+			}
+
+			fmt.Println(`- Proposed generalized table "`+proposalName(i)+`" for the scale band:`, band.MinScaleDenominator, "-", scaleText(band.MaxScaleDenominator))
+
+			proposal.Tolerance = m.tolerance(band.MinScaleDenominator)
+			proposal.SQLFilter = generateSQLFilter(mappingColumns, bandRequirements.RequiredColumnList, bandRequirements.RequiredMappingValues, bandRequirements.RequiredMappingPatterns, bandRequirements.NumericRanges, m.mappingRoot.Tables[tableName].Columns, "", (useAllMappingTypes && !m.forceFiltering))
+
+			proposals[proposalName(i)] = proposal
+		}
+	}
+
+	if len(proposals) == 0 {
+		fmt.Println("- No generalized tables are proposed")
+		return nil
+	}
+
+	proposalSection, err := yaml.Marshal(map[string]map[string]GeneralizedTable{"generalized_tables": proposals})
+
+	if err != nil {
+		panic(err)
+	}
+
+	return proposalSection
+}
+
+//getGeneralizedTablesByTolerance returns all generalized tables of a root table, sorted from the lowest to the highest tolerance
+func (m *mappingParser) getGeneralizedTablesByTolerance(tableName string) []string {
+
+	genTables := make([]string, 0)
+
+	for genTableName := range m.mappingRoot.GeneralizedTables {
+		if m.GetGeneralizedRootSourceTable(genTableName) == tableName {
+			genTables = append(genTables, genTableName)
+		}
+	}
+
+	sort.Slice(genTables, func(a, b int) bool {
+		toleranceA := m.mappingRoot.GeneralizedTables[genTables[a]].Tolerance
+		toleranceB := m.mappingRoot.GeneralizedTables[genTables[b]].Tolerance
+
+		if toleranceA == toleranceB {
+			return genTables[a] < genTables[b]
+		}
+
+		return toleranceA < toleranceB
+	})
+
+	return genTables
+}
+
+//nextGeneralizedTableIndex returns the next free index for the generalized tables of a root table
+func (m *mappingParser) nextGeneralizedTableIndex(tableName string) int {
+
+	index := 0
+
+	for genTableName := range m.mappingRoot.GeneralizedTables {
+		match := generalizedTableIndex.FindStringSubmatch(genTableName)
+
+		if match == nil || m.GetGeneralizedRootSourceTable(genTableName) != tableName {
+			continue
+		}
+
+		if number, err := strconv.Atoi(match[1]); err == nil && number >= index {
+			index = number + 1
+		}
+	}
+
+	return index
+}
@@ -0,0 +1,261 @@
+package mapping
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//anyValue matches every value of a key in an imposm3 table filter
+const anyValue = "__any__"
+
+//buildTableFilter translates the values of each filtered column, which can reach a symbolizer, into imposm3 table filters.
+//Only columns, whose value is the unchanged value of a single tag, are translated.
+//Positive mapping value sets are not translated, they are handled by buildMappingValueList
+func buildTableFilter(rootTable Table, newTable *Table, columnValueSets map[string]sld.ValueSet) {
+
+	newTable.Filter = copyTableFilter(rootTable.Filter)
+
+	for _, column := range rootTable.Columns {
+
+		valueSet, found := columnValueSets[column.Name]
+
+		//only exact sets describe the rendered values precisely. The listed values come from case sensitive comparisons,
+		//case insensitive comparisons are "(?i)" patterns and become regexp filters
+		if !found || !valueSet.Exact {
+			continue
+		}
+
+		key := ""
+
+		switch column.Type {
+		case "string":
+			key = column.Key
+		case "mapping_value":
+			if !valueSet.Negated {
+				continue
+			}
+
+			//rejecting a mapping value is only sound, if the table uses a single mapping key
+			mappingKeys := getMappingKeys(rootTable)
+
+			if len(mappingKeys) == 1 {
+				key = mappingKeys[0]
+			}
+		}
+
+		if key == "" {
+			continue
+		}
+
+		containsNull := functions.StringInSlice(sld.NullValue, valueSet.Values)
+
+		//a missing tag must not be rejected, if NULL values are rendered
+		if containsNull && !valueSet.Negated {
+			continue
+		}
+
+		values := make([]string, 0)
+
+		for _, value := range valueSet.Values {
+			if value != sld.NullValue {
+				values = append(values, value)
+			}
+		}
+
+		if newTable.Filter == nil {
+			newTable.Filter = new(TableFilter)
+		}
+
+		if !valueSet.Negated {
+			//imposm3 requires all filters of a key to match, therefore values and patterns are combined into one expression
+			if len(valueSet.Patterns) > 0 {
+				addRequireRegexpFilter(newTable.Filter, key, combineRegexp(values, valueSet.Patterns))
+			} else {
+				addRequireFilter(newTable.Filter, key, values)
+			}
+		} else {
+			if len(values) > 0 {
+				addRejectFilter(newTable.Filter, key, values)
+			}
+
+			if len(valueSet.Patterns) > 0 {
+				addRejectRegexpFilter(newTable.Filter, key, combineRegexp(nil, valueSet.Patterns))
+			}
+
+			if containsNull {
+				addRequireFilter(newTable.Filter, key, []string{anyValue})
+			}
+		}
+	}
+}
+
+//combineRegexp combines values and anchored patterns to a single regular expression
+func combineRegexp(values []string, patterns []string) string {
+
+	alternatives := make([]string, 0)
+
+	if len(values) > 0 {
+		quotedValues := make([]string, 0)
+
+		for _, value := range values {
+			quotedValues = append(quotedValues, regexp.QuoteMeta(value))
+		}
+
+		alternatives = append(alternatives, "^(?:"+strings.Join(quotedValues, "|")+")$")
+	}
+
+	for _, pattern := range patterns {
+		if len(patterns) == 1 && len(values) == 0 {
+			return pattern
+		}
+
+		alternatives = append(alternatives, "(?:"+pattern+")")
+	}
+
+	return strings.Join(alternatives, "|")
+}
+
+//addRequireRegexpFilter adds a require_regexp filter, two expressions cannot be intersected, so an existing expression is kept
+func addRequireRegexpFilter(filter *TableFilter, key string, expression string) {
+
+	if filter.RequireRegexp == nil {
+		filter.RequireRegexp = make(map[string]string)
+	}
+
+	if oldExpression, found := filter.RequireRegexp[key]; found {
+		fmt.Println(`- Table filter "require_regexp" for key "` + key + `" already exists and is kept: ` + oldExpression)
+		return
+	}
+
+	filter.RequireRegexp[key] = expression
+	fmt.Println(`- Table filter "require_regexp" set for key "` + key + `": ` + expression)
+}
+
+//addRejectRegexpFilter adds an expression to the reject_regexp filter of the key
+func addRejectRegexpFilter(filter *TableFilter, key string, expression string) {
+
+	if filter.RejectRegexp == nil {
+		filter.RejectRegexp = make(map[string]string)
+	}
+
+	if oldExpression, found := filter.RejectRegexp[key]; found && oldExpression != expression {
+		expression = "(?:" + oldExpression + ")|(?:" + expression + ")"
+	}
+
+	filter.RejectRegexp[key] = expression
+	fmt.Println(`- Table filter "reject_regexp" set for key "` + key + `": ` + expression)
+}
+
+//addRequireFilter adds a require filter, an already existing require filter of the key is narrowed down
+func addRequireFilter(filter *TableFilter, key string, values []string) {
+
+	if filter.Require == nil {
+		filter.Require = make(map[string][]string)
+	}
+
+	oldValues, found := filter.Require[key]
+
+	if found && !functions.StringInSlice(anyValue, oldValues) {
+		if functions.StringInSlice(anyValue, values) {
+			return
+		}
+
+		narrowedValues := make([]string, 0)
+
+		for _, value := range oldValues {
+			if functions.StringInSlice(value, values) {
+				narrowedValues = append(narrowedValues, value)
+			}
+		}
+
+		values = narrowedValues
+	}
+
+	filter.Require[key] = values
+	fmt.Println(`- Table filter "require" set for key "`+key+`":`, values)
+}
+
+//addRejectFilter adds all values to the reject filter of the key
+func addRejectFilter(filter *TableFilter, key string, values []string) {
+
+	if filter.Reject == nil {
+		filter.Reject = make(map[string][]string)
+	}
+
+	for _, value := range values {
+		if !functions.StringInSlice(value, filter.Reject[key]) {
+			filter.Reject[key] = append(filter.Reject[key], value)
+		}
+	}
+
+	fmt.Println(`- Table filter "reject" set for key "`+key+`":`, filter.Reject[key])
+}
+
+//copyTableFilter creates a deep copy of a table filter, so that the filter of the source table stays untouched
+func copyTableFilter(filter *TableFilter) *TableFilter {
+
+	if filter == nil {
+		return nil
+	}
+
+	newFilter := new(TableFilter)
+
+	if filter.Require != nil {
+		newFilter.Require = make(map[string][]string)
+
+		for key, values := range filter.Require {
+			newFilter.Require[key] = append([]string{}, values...)
+		}
+	}
+
+	if filter.Reject != nil {
+		newFilter.Reject = make(map[string][]string)
+
+		for key, values := range filter.Reject {
+			newFilter.Reject[key] = append([]string{}, values...)
+		}
+	}
+
+	if filter.RequireRegexp != nil {
+		newFilter.RequireRegexp = make(map[string]string)
+
+		for key, expression := range filter.RequireRegexp {
+			newFilter.RequireRegexp[key] = expression
+		}
+	}
+
+	if filter.RejectRegexp != nil {
+		newFilter.RejectRegexp = make(map[string]string)
+
+		for key, expression := range filter.RejectRegexp {
+			newFilter.RejectRegexp[key] = expression
+		}
+	}
+
+	return newFilter
+}
+
+//getMappingKeys returns all keys of the mapping of a table
+func getMappingKeys(table Table) []string {
+
+	keys := make([]string, 0)
+
+	for key := range table.Mapping {
+		if !functions.StringInSlice(key, keys) {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, subMapping := range table.Mappings {
+		for key := range subMapping.Mapping {
+			if !functions.StringInSlice(key, keys) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,73 @@
+package mapping
+
+import (
+	"Imposm_Optimizer/sld"
+	"reflect"
+	"testing"
+)
+
+func TestBuildTableFilter(t *testing.T) {
+
+	columns := []TableColumn{{"mapping_value", "type", "", nil, false, nil}, {"string", "surface", "surface", nil, false, nil}}
+	singleKey := map[string][]string{"highway": {"track", "path"}}
+	twoKeys := map[string][]string{"highway": {"track"}, "railway": {"rail"}}
+
+	equalTo := func(column string, value string) sld.FilterNode {
+		return sld.ComparisonFilter{Name: "PropertyIsEqualTo", Left: sld.PropertyNameExpression{Name: column}, Right: sld.LiteralExpression{Value: value}, MatchCase: true}
+	}
+	like := func(column string, pattern string) sld.FilterNode {
+		return sld.LikeFilter{Expression: sld.PropertyNameExpression{Name: column}, Pattern: pattern, WildCard: "%", SingleChar: "_", EscapeChar: "\\", MatchCase: true}
+	}
+	or := func(children ...sld.FilterNode) sld.FilterNode {
+		return sld.LogicalFilter{Name: "Or", Children: children}
+	}
+	isNull := sld.NullFilter{Name: "PropertyIsNull", Expression: sld.PropertyNameExpression{Name: "surface"}}
+
+	tests := []struct {
+		name     string
+		mapping  map[string][]string
+		filter   *TableFilter
+		rule     sld.FilterNode
+		expected *TableFilter
+	}{
+		{"not or", singleKey, nil, sld.NotFilter{Child: or(equalTo("surface", "paved"), equalTo("surface", "asphalt"))},
+			&TableFilter{nil, map[string][]string{"surface": {"paved", "asphalt"}}, nil, nil, nil}},
+		{"or", singleKey, nil, or(equalTo("surface", "paved"), equalTo("surface", "asphalt")),
+			&TableFilter{map[string][]string{"surface": {"paved", "asphalt"}}, nil, nil, nil, nil}},
+		{"narrowed require filter", singleKey, &TableFilter{map[string][]string{"surface": {"paved", "gravel"}}, nil, nil, nil, nil}, or(equalTo("surface", "paved"), equalTo("surface", "asphalt")),
+			&TableFilter{map[string][]string{"surface": {"paved"}}, nil, nil, nil, nil}},
+		{"like", singleKey, nil, like("surface", "grav%"),
+			&TableFilter{nil, nil, map[string]string{"surface": "^grav.*$"}, nil, nil}},
+		{"or with like", singleKey, nil, or(equalTo("surface", "paved"), like("surface", "grav%")),
+			&TableFilter{nil, nil, map[string]string{"surface": "^(?:paved)$|(?:^grav.*$)"}, nil, nil}},
+		{"not like", singleKey, nil, sld.NotFilter{Child: like("surface", "grav%")},
+			&TableFilter{nil, nil, nil, map[string]string{"surface": "^grav.*$"}, nil}},
+		{"like without wildcard", singleKey, nil, sld.NotFilter{Child: like("surface", `100\%`)},
+			&TableFilter{nil, map[string][]string{"surface": {"100%"}}, nil, nil, nil}},
+		{"or with null", singleKey, nil, or(equalTo("surface", "paved"), isNull), nil},
+		{"not null", singleKey, nil, sld.NotFilter{Child: isNull},
+			&TableFilter{map[string][]string{"surface": {anyValue}}, nil, nil, nil, nil}},
+		{"or with other column", singleKey, nil, or(equalTo("surface", "paved"), equalTo("name", "x")), nil},
+		{"mapping value", singleKey, nil, equalTo("type", "track"), nil},
+		{"not mapping value", singleKey, nil, sld.NotFilter{Child: equalTo("type", "track")},
+			&TableFilter{nil, map[string][]string{"highway": {"track"}}, nil, nil, nil}},
+		{"not mapping value of several keys", twoKeys, nil, sld.NotFilter{Child: equalTo("type", "track")}, nil}}
+
+	for _, test := range tests {
+		rootTable := Table{"linestring", columns, test.mapping, nil, nil, nil, copyTableFilter(test.filter), false, nil}
+		newTable := Table{}
+		columnValueSets := map[string]sld.ValueSet{
+			"type":    sld.ColumnValueSet(test.rule, "type"),
+			"surface": sld.ColumnValueSet(test.rule, "surface")}
+
+		buildTableFilter(rootTable, &newTable, columnValueSets)
+
+		if !reflect.DeepEqual(newTable.Filter, test.expected) {
+			t.Errorf("%s: %+v, expected %+v", test.name, newTable.Filter, test.expected)
+		}
+
+		if !reflect.DeepEqual(rootTable.Filter, test.filter) {
+			t.Errorf("%s: the filter of the root table was changed to %+v", test.name, rootTable.Filter)
+		}
+	}
+}
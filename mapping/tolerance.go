@@ -0,0 +1,118 @@
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+//standardPixelSize is the size of a pixel in meters, which is defined by the OGC for the calculation of scale denominators
+const standardPixelSize = 0.00028
+
+//metersPerDegree is the length of a degree at the equator of the WGS84 ellipsoid
+const metersPerDegree = 2 * math.Pi * 6378137 / 360
+
+//ToleranceSettings contains the informations for the calculation of the tolerance of generalized tables in map units.
+//SRID = target projection, 3857 and 4326 are corrected by the reference latitude, every other projection is handled as meters
+//PixelTolerance = the tolerance in pixels, a missing value is one pixel
+//ReferenceLatitude = the latitude at which the scale denominators are true
+type ToleranceSettings struct {
+	SRID              int     `json:"srid"`
+	PixelTolerance    float64 `json:"pixel_tolerance,omitempty"`
+	ReferenceLatitude float64 `json:"reference_latitude,omitempty"`
+}
+
+//Validate checks if a tolerance can be calculated with the settings
+func (t ToleranceSettings) Validate() error {
+
+	if t.PixelTolerance < 0 {
+		return errors.New("the pixel tolerance must not be negative")
+	}
+
+	if (t.SRID == 3857 || t.SRID == 4326) && math.Abs(t.ReferenceLatitude) >= 85 {
+		return errors.New("the reference latitude must be between -85 and 85 degrees for EPSG:" + strconv.Itoa(t.SRID))
+	}
+
+	return nil
+}
+
+//Pixels returns the tolerance in pixels, a missing pixel tolerance is one pixel
+func (t ToleranceSettings) Pixels() float64 {
+	if t.PixelTolerance == 0 {
+		return 1
+	}
+
+	return t.PixelTolerance
+}
+
+//MapUnitsPerScale returns the tolerance in map units for a scale denominator of one
+func (t ToleranceSettings) MapUnitsPerScale() float64 {
+
+	meters := standardPixelSize * t.Pixels()
+	latitudeFactor := math.Cos(t.ReferenceLatitude * math.Pi / 180)
+
+	switch t.SRID {
+	case 3857:
+		//web mercator stretches all lengths by 1/cos(latitude)
+		return meters / latitudeFactor
+	case 4326:
+		//a degree of longitude gets shorter by cos(latitude)
+		return meters / (metersPerDegree * latitudeFactor)
+	}
+
+	return meters
+}
+
+//Tolerance calculates the tolerance in map units for a scale denominator and returns the derivation as text
+func (t ToleranceSettings) Tolerance(scale int) (float64, string) {
+
+	meters := float64(scale) * standardPixelSize * t.Pixels()
+	tolerance := float64(scale) * t.MapUnitsPerScale()
+
+	derivation := fmt.Sprintf("1:%d × %g m/px × %g px = %g m", scale, standardPixelSize, t.Pixels(), meters)
+
+	switch t.SRID {
+	case 3857:
+		derivation += fmt.Sprintf(", EPSG:3857 at %g°: %g m / cos(%g°) = %g map units", t.ReferenceLatitude, meters, t.ReferenceLatitude, tolerance)
+	case 4326:
+		derivation += fmt.Sprintf(", EPSG:4326 at %g°: %g m / (%g m/° × cos(%g°)) = %g°", t.ReferenceLatitude, meters, metersPerDegree, t.ReferenceLatitude, tolerance)
+	default:
+		derivation += fmt.Sprintf(", EPSG:%d is handled as meters = %g map units", t.SRID, tolerance)
+	}
+
+	return tolerance, derivation
+}
+
+//tolerance calculates the tolerance of a generalized table from its minimum scale denominator and prints the derivation.
+//Without tolerance settings the tolerance scaling is used
+func (m *mappingParser) tolerance(scale int) float64 {
+
+	if m.toleranceSettings == nil {
+		//the percentage is a decimal fraction, rounding removes binary artifacts like 35.00000000000001
+		tolerance := math.Round(float64(scale)*m.toleranceScaling/100.0*1e6) / 1e6
+		fmt.Println("- Tolerance:", tolerance)
+
+		return tolerance
+	}
+
+	tolerance, derivation := m.toleranceSettings.Tolerance(scale)
+	fmt.Println("- Tolerance: " + derivation)
+
+	return tolerance
+}
+
+//toleranceScale calculates the minimum scale denominator of a generalized table from its tolerance, it is the inverse of the tolerance calculation
+func (m *mappingParser) toleranceScale(tolerance float64) int {
+
+	if m.toleranceSettings == nil {
+		return int(math.Round(tolerance * 100.0 / m.toleranceScaling))
+	}
+
+	return int(math.Round(tolerance / m.toleranceSettings.MapUnitsPerScale()))
+}
+
+//canCalculateTolerance checks if the tolerance settings or the tolerance scaling are set
+func (m *mappingParser) canCalculateTolerance() bool {
+	return m.toleranceSettings != nil || m.toleranceScaling > 0
+}
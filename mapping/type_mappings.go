@@ -0,0 +1,85 @@
+package mapping
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"fmt"
+)
+
+//buildTypeMappings prunes the mapping of every geometry type of a geometry table separately.
+//A mapping value is kept for a geometry type, if a rule which uses the value has a symbolizer that draws the geometry type
+func buildTypeMappings(typeMappings TypeMappings, requirements sld.TableRequirements, forceFiltering bool) *TypeMappings {
+
+	newTypeMappings := TypeMappings{}
+	newTypeMappings.Extra = typeMappings.Extra
+
+	newTypeMappings.Points = buildGeometryTypeMapping("points", "point", typeMappings.Points, requirements, forceFiltering)
+	newTypeMappings.LineStrings = buildGeometryTypeMapping("linestrings", "linestring", typeMappings.LineStrings, requirements, forceFiltering)
+	newTypeMappings.Polygons = buildGeometryTypeMapping("polygons", "polygon", typeMappings.Polygons, requirements, forceFiltering)
+
+	return &newTypeMappings
+}
+
+//buildGeometryTypeMapping prunes the mapping of a single geometry type, a mapping without values is removed
+func buildGeometryTypeMapping(name string, geometryType string, mapping map[string][]string, requirements sld.TableRequirements, forceFiltering bool) map[string][]string {
+
+	if len(mapping) == 0 {
+		return mapping
+	}
+
+	//rules which use all mapping values keep the whole mapping, if they draw the geometry type
+	if !forceFiltering && sld.DrawsGeometry(requirements.UnfilteredKinds, geometryType) {
+		return mapping
+	}
+
+	requiredValues := make([]string, 0)
+
+	for _, value := range requirements.RequiredMappingValues {
+		if sld.DrawsGeometry(requirements.MappingValueKinds[value], geometryType) {
+			requiredValues = append(requiredValues, value)
+		}
+	}
+
+	requiredPatterns := make([]string, 0)
+
+	for _, pattern := range requirements.RequiredMappingPatterns {
+		if sld.DrawsGeometry(requirements.MappingPatternKinds[pattern], geometryType) {
+			requiredPatterns = append(requiredPatterns, pattern)
+		}
+	}
+
+	newMapping := make(map[string][]string)
+	usedRequiredMappingTypes := make([]string, 0)
+
+	for class, keyList := range mapping {
+
+		keptKeys, excludedKeys := filterMappingValues(keyList, requiredValues, requiredPatterns, &usedRequiredMappingTypes)
+
+		if len(keptKeys) > 0 {
+			newMapping[class] = keptKeys
+		}
+
+		for _, key := range excludedKeys {
+			fmt.Println(`- Mapping value excluded in type mapping "` + name + `:` + class + `:` + key + `"`)
+		}
+	}
+
+	if len(newMapping) == 0 {
+		fmt.Println(`- Type mapping "` + name + `" removed, no symbolizer draws its features`)
+		return nil
+	}
+
+	return newMapping
+}
+
+//appendKinds adds all missing symbolizer kinds to the list
+func appendKinds(kinds []string, newKinds []string) []string {
+
+	for _, kind := range newKinds {
+		if !functions.StringInSlice(kind, kinds) {
+			kinds = append(kinds, kind)
+		}
+	}
+
+	return kinds
+}
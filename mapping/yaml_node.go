@@ -0,0 +1,611 @@
+package mapping
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+//mergeKey is the YAML key, which merges the entries of other mappings into a mapping
+const mergeKey = "<<"
+
+//blockKeyLine matches a line with a mapping key, whose value starts in the next line
+var blockKeyLine = regexp.MustCompile(`^((?:- )*)[^\s#'"][^#]*:( #.*)?$`)
+
+//mappingFormat contains the formatting of the original mapping file, which cannot be stored in the node tree
+//Indent = number of spaces of an indentation
+//CompactSequences = sequences in mappings are not indented
+//Lines = lines of the original file, unchanged nodes are copied from them
+type mappingFormat struct {
+	Indent           int
+	CompactSequences bool
+	Lines            []string
+}
+
+//parseMappingNode parses a YAML mapping file into a node tree, which keeps comments, key order and anchors
+func parseMappingNode(content []byte) (*yamlv3.Node, mappingFormat, error) {
+
+	root := new(yamlv3.Node)
+
+	err := yamlv3.Unmarshal(content, root)
+
+	if err != nil {
+		return nil, mappingFormat{}, err
+	}
+
+	format := detectFormat(content)
+	format.Lines = strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+
+	return root, format, nil
+}
+
+//buildMappingNode writes the new mapping structure into a copy of the node tree of the original file.
+//Only nodes, whose values have changed, are edited and written again, so that the new file is a minimal diff of the original file
+func buildMappingNode(original *yamlv3.Node, newMapping Mapping, format mappingFormat) ([]byte, error) {
+
+	clones := make(map[*yamlv3.Node]*yamlv3.Node)
+	root := cloneNode(original, clones)
+
+	if root.Kind != yamlv3.DocumentNode || len(root.Content) == 0 {
+		return nil, fmt.Errorf("the mapping file contains no YAML document")
+	}
+
+	updateNode(root, root.Content[0], reflect.ValueOf(newMapping))
+
+	if len(format.Lines) > 0 {
+		origins := make(map[*yamlv3.Node]*yamlv3.Node)
+
+		for originalNode, clone := range clones {
+			origins[clone] = originalNode
+		}
+
+		splicer := mappingSplicer{format.Lines, origins, format, make([]string, 0)}
+		written, err := splicer.writeCollection(root.Content[0], original.Content[0], lineRange{1, len(format.Lines)})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if written {
+			return []byte(strings.Join(splicer.output, "\n") + "\n"), nil
+		}
+	}
+
+	//documents, which are no block mapping, are written completely
+	return encodeYAML(root, format.Indent, format.CompactSequences)
+}
+
+//encodeYAML writes a node tree with the indentation and sequence format
+func encodeYAML(node *yamlv3.Node, indent int, compact bool) ([]byte, error) {
+
+	//the encoder writes an explicit tag for merge keys, which is not part of the original file
+	walkNodes(node, func(child *yamlv3.Node) {
+		if child.Kind == yamlv3.ScalarNode && child.Tag == "!!merge" {
+			child.Tag = ""
+		}
+	})
+
+	var buffer bytes.Buffer
+
+	encoder := yamlv3.NewEncoder(&buffer)
+	encoder.SetIndent(indent)
+
+	err := encoder.Encode(node)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if compact {
+		return compactSequences(buffer.Bytes(), indent), nil
+	}
+
+	return buffer.Bytes(), nil
+}
+
+//updateNode edits the node, so that it contains the value. Unchanged nodes are not touched
+func updateNode(root *yamlv3.Node, node *yamlv3.Node, value reflect.Value) {
+
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			break
+		}
+
+		value = value.Elem()
+	}
+
+	if nodeEquals(node, value) {
+		return
+	}
+
+	makeEditable(root, node)
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if node.Kind == yamlv3.MappingNode {
+			updateStructNode(root, node, value)
+			return
+		}
+	case reflect.Map:
+		if node.Kind == yamlv3.MappingNode {
+			updateMapNode(root, node, value)
+			return
+		}
+	case reflect.Slice:
+		if node.Kind == yamlv3.SequenceNode {
+			updateSequenceNode(node, value)
+			return
+		}
+	}
+
+	replaceNode(node, encodeNode(value))
+}
+
+//updateStructNode edits all keys of a mapping node, which belong to a struct field or its inline map. Unknown keys are kept
+func updateStructNode(root *yamlv3.Node, node *yamlv3.Node, value reflect.Value) {
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name, omitEmpty, inline := yamlFieldName(field)
+
+		if name == "-" {
+			continue
+		}
+
+		//the keys of an inline map are keys of the mapping node itself
+		if inline && field.Type.Kind() == reflect.Map {
+			keys := value.Field(i).MapKeys()
+
+			sort.Slice(keys, func(a, b int) bool {
+				return fmt.Sprint(keys[a].Interface()) < fmt.Sprint(keys[b].Interface())
+			})
+
+			for _, key := range keys {
+				updateStructKey(root, node, fmt.Sprint(key.Interface()), value.Field(i).MapIndex(key), false)
+			}
+			continue
+		}
+
+		updateStructKey(root, node, name, value.Field(i), omitEmpty)
+	}
+}
+
+//updateStructKey edits the value of a single key of a mapping node
+func updateStructKey(root *yamlv3.Node, node *yamlv3.Node, name string, fieldValue reflect.Value, omitEmpty bool) {
+
+	index := findKey(node, name)
+
+	//merged keys are only written into the node, if their value has changed
+	if index < 0 {
+		for _, entry := range mergedEntries(node) {
+			if entry[0].Value == name && !nodeEquals(entry[1], fieldValue) {
+				materializeMerges(node)
+				index = findKey(node, name)
+			}
+		}
+
+		if index < 0 && entryInList(name, mergedEntries(node)) {
+			return
+		}
+	}
+
+	//zero values are never added, omitted values are removed
+	if isEmptyValue(fieldValue) && (omitEmpty || index < 0) {
+		if index >= 0 {
+			node.Content = append(node.Content[:index], node.Content[index+2:]...)
+		}
+		return
+	}
+
+	if index >= 0 {
+		updateNode(root, node.Content[index+1], fieldValue)
+	} else {
+		node.Content = append(node.Content, keyNode(name), encodeNode(fieldValue))
+	}
+}
+
+//updateMapNode edits the entries of a mapping node, entries which are not part of the map are removed
+func updateMapNode(root *yamlv3.Node, node *yamlv3.Node, value reflect.Value) {
+
+	materializeMerges(node)
+
+	mapValues := make(map[string]reflect.Value)
+
+	for _, key := range value.MapKeys() {
+		mapValues[fmt.Sprint(key.Interface())] = value.MapIndex(key)
+	}
+
+	content := make([]*yamlv3.Node, 0)
+	foundKeys := make(map[string]bool)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		mapValue, found := mapValues[key]
+
+		if !found {
+			continue
+		}
+
+		updateNode(root, node.Content[i+1], mapValue)
+
+		content = append(content, node.Content[i], node.Content[i+1])
+		foundKeys[key] = true
+	}
+
+	newKeys := make([]string, 0)
+
+	for key := range mapValues {
+		if !foundKeys[key] {
+			newKeys = append(newKeys, key)
+		}
+	}
+
+	sort.Strings(newKeys)
+
+	for _, key := range newKeys {
+		content = append(content, keyNode(key), encodeNode(mapValues[key]))
+	}
+
+	node.Content = content
+}
+
+//updateSequenceNode replaces the items of a sequence node, unchanged items are reused with their comments
+func updateSequenceNode(node *yamlv3.Node, value reflect.Value) {
+
+	used := make([]bool, len(node.Content))
+	content := make([]*yamlv3.Node, 0)
+
+	for i := 0; i < value.Len(); i++ {
+		item := value.Index(i)
+		reused := false
+
+		for j, oldItem := range node.Content {
+			if !used[j] && nodeEquals(oldItem, item) {
+				used[j] = true
+				reused = true
+				content = append(content, oldItem)
+				break
+			}
+		}
+
+		if !reused {
+			content = append(content, encodeNode(item))
+		}
+	}
+
+	node.Content = content
+}
+
+//nodeEquals checks if the node contains the value
+func nodeEquals(node *yamlv3.Node, value reflect.Value) bool {
+
+	if !value.IsValid() {
+		return node.Tag == "!!null"
+	}
+
+	decoded := reflect.New(value.Type())
+
+	if err := node.Decode(decoded.Interface()); err == nil && reflect.DeepEqual(decoded.Elem().Interface(), value.Interface()) {
+		return true
+	}
+
+	//nested maps of interface values are decoded differently by yaml.v2, therefore both sides are compared as generic values
+	var oldValue, newValue interface{}
+
+	if err := node.Decode(&oldValue); err != nil {
+		return false
+	}
+
+	if err := encodeNode(value).Decode(&newValue); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(oldValue, newValue)
+}
+
+//makeEditable makes sure, that editing the node changes no other part of the document.
+//An alias is replaced by a copy of its anchor and all aliases of an anchor are replaced by copies before the anchor is edited
+func makeEditable(root *yamlv3.Node, node *yamlv3.Node) {
+
+	if node.Kind == yamlv3.AliasNode {
+		*node = *copyNode(node.Alias)
+	}
+
+	if node.Anchor == "" {
+		return
+	}
+
+	walkNodes(root, func(aliasNode *yamlv3.Node) {
+		if aliasNode.Kind == yamlv3.AliasNode && aliasNode.Alias == node {
+			*aliasNode = *copyNode(node)
+		}
+	})
+
+	node.Anchor = ""
+}
+
+//materializeMerges replaces all merge keys of a mapping node with the merged entries, explicit keys keep their priority.
+//The merged mappings are not changed
+func materializeMerges(node *yamlv3.Node) {
+
+	if findKey(node, mergeKey) < 0 {
+		return
+	}
+
+	content := make([]*yamlv3.Node, 0)
+
+	for _, entry := range mergedEntries(node) {
+		content = append(content, copyNode(entry[0]), copyNode(entry[1]))
+	}
+
+	node.Content = content
+}
+
+//mergedEntries returns all key value pairs of a mapping node including the merged entries
+func mergedEntries(node *yamlv3.Node) [][2]*yamlv3.Node {
+
+	entries := make([][2]*yamlv3.Node, 0)
+	mergedNodes := make([]*yamlv3.Node, 0)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != mergeKey {
+			entries = append(entries, [2]*yamlv3.Node{node.Content[i], node.Content[i+1]})
+			continue
+		}
+
+		mergeValue := resolveAlias(node.Content[i+1])
+
+		if mergeValue.Kind == yamlv3.SequenceNode {
+			mergedNodes = append(mergedNodes, mergeValue.Content...)
+		} else {
+			mergedNodes = append(mergedNodes, mergeValue)
+		}
+	}
+
+	for _, mergedNode := range mergedNodes {
+		for _, entry := range mergedEntries(resolveAlias(mergedNode)) {
+			if !entryInList(entry[0].Value, entries) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return entries
+}
+
+func entryInList(key string, entries [][2]*yamlv3.Node) bool {
+	for _, entry := range entries {
+		if entry[0].Value == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+//replaceNode replaces the content of a node and keeps its comments
+func replaceNode(node *yamlv3.Node, newNode *yamlv3.Node) {
+
+	newNode.HeadComment = node.HeadComment
+	newNode.LineComment = node.LineComment
+	newNode.FootComment = node.FootComment
+
+	*node = *newNode
+}
+
+//findKey returns the index of the key node in a mapping node, -1 if the key is missing
+func findKey(node *yamlv3.Node, key string) int {
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+//encodeNode creates a new node tree from a value
+func encodeNode(value reflect.Value) *yamlv3.Node {
+
+	node := new(yamlv3.Node)
+
+	var err error
+
+	if value.IsValid() {
+		err = node.Encode(value.Interface())
+	} else {
+		err = node.Encode(nil)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return node
+}
+
+func keyNode(key string) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: key}
+}
+
+func resolveAlias(node *yamlv3.Node) *yamlv3.Node {
+	for node.Kind == yamlv3.AliasNode {
+		node = node.Alias
+	}
+
+	return node
+}
+
+//copyNode creates a deep copy of a node without anchors, aliases inside of the copy are kept
+func copyNode(node *yamlv3.Node) *yamlv3.Node {
+
+	newNode := *node
+	newNode.Anchor = ""
+	newNode.Content = make([]*yamlv3.Node, len(node.Content))
+
+	for i, child := range node.Content {
+		newNode.Content[i] = copyNode(child)
+	}
+
+	return &newNode
+}
+
+//cloneNode creates a deep copy of a node tree, the aliases of the copy point to the anchors of the copy
+func cloneNode(node *yamlv3.Node, clones map[*yamlv3.Node]*yamlv3.Node) *yamlv3.Node {
+
+	newNode := *node
+	clones[node] = &newNode
+
+	if node.Alias != nil {
+		if clonedAlias, found := clones[node.Alias]; found {
+			newNode.Alias = clonedAlias
+		}
+	}
+
+	newNode.Content = make([]*yamlv3.Node, len(node.Content))
+
+	for i, child := range node.Content {
+		newNode.Content[i] = cloneNode(child, clones)
+	}
+
+	return &newNode
+}
+
+func walkNodes(node *yamlv3.Node, fn func(*yamlv3.Node)) {
+	fn(node)
+
+	for _, child := range node.Content {
+		walkNodes(child, fn)
+	}
+}
+
+//yamlFieldName returns the YAML key of a struct field, if the field is omitted when empty and if the field is inline
+func yamlFieldName(field reflect.StructField) (string, bool, bool) {
+
+	options := strings.Split(field.Tag.Get("yaml"), ",")
+	name := options[0]
+	omitEmpty := false
+	inline := false
+
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	for _, option := range options[1:] {
+		switch option {
+		case "omitempty":
+			omitEmpty = true
+		case "inline":
+			inline = true
+		}
+	}
+
+	return name, omitEmpty, inline
+}
+
+func isEmptyValue(value reflect.Value) bool {
+
+	switch value.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+
+	return value.IsZero()
+}
+
+//detectFormat returns the indentation of the first indented line and if sequences in mappings are indented.
+//The default is an indentation of two spaces
+func detectFormat(content []byte) mappingFormat {
+
+	format := mappingFormat{2, false, nil}
+	lines := strings.Split(string(content), "\n")
+	foundIndent := false
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimLeft(line, " ")
+
+		if trimmedLine == "" || strings.HasPrefix(trimmedLine, "#") {
+			continue
+		}
+
+		if !foundIndent && len(trimmedLine) < len(line) {
+			format.Indent = len(line) - len(trimmedLine)
+			foundIndent = true
+		}
+
+		//a sequence at the indentation of its mapping key is compact
+		if blockKeyLine.MatchString(trimmedLine) && i+1 < len(lines) {
+			nextLine := lines[i+1]
+
+			if strings.HasPrefix(nextLine, strings.Repeat(" ", keyColumn(line))+"- ") {
+				format.CompactSequences = true
+			}
+		}
+	}
+
+	return format
+}
+
+//compactSequences removes the indentation of all sequences, which are the value of a mapping key
+func compactSequences(content []byte, indent int) []byte {
+
+	lines := strings.Split(string(content), "\n")
+
+	for i := 0; i+1 < len(lines); i++ {
+		if !blockKeyLine.MatchString(strings.TrimLeft(lines[i], " ")) {
+			continue
+		}
+
+		column := keyColumn(lines[i])
+
+		if !strings.HasPrefix(lines[i+1], strings.Repeat(" ", column+indent)+"- ") {
+			continue
+		}
+
+		//all following lines, which are indented deeper than the key, belong to the sequence
+		for j := i + 1; j < len(lines); j++ {
+			trimmedLine := strings.TrimLeft(lines[j], " ")
+
+			if trimmedLine != "" && len(lines[j])-len(trimmedLine) < column+indent {
+				break
+			}
+
+			if len(lines[j]) >= indent {
+				lines[j] = lines[j][indent:]
+			}
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
+
+//keyColumn returns the column of the mapping key of a line, sequence indicators in front of the key are skipped
+func keyColumn(line string) int {
+
+	column := len(line) - len(strings.TrimLeft(line, " "))
+
+	for strings.HasPrefix(line[column:], "- ") {
+		column += 2
+	}
+
+	return column
+}
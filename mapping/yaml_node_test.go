@@ -0,0 +1,80 @@
+package mapping
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+//readTestMapping parses a mapping file of testdata into the mapping structure and the node tree
+func readTestMapping(t *testing.T, fileName string) (Mapping, []byte, func(Mapping) []byte) {
+
+	content, err := ioutil.ReadFile(filepath.Join("testdata", fileName))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapping := Mapping{}
+
+	if err := yaml.Unmarshal(content, &mapping); err != nil {
+		t.Fatal(err)
+	}
+
+	node, format, err := parseMappingNode(content)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	build := func(newMapping Mapping) []byte {
+		newContent, err := buildMappingNode(node, newMapping, format)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return newContent
+	}
+
+	return mapping, content, build
+}
+
+func TestBuildMappingNodeUnchanged(t *testing.T) {
+
+	mapping, content, build := readTestMapping(t, "mixed_indentation.yaml")
+
+	if newContent := build(mapping); string(newContent) != string(content) {
+		t.Errorf("the unchanged mapping was rewritten:\n%s", newContent)
+	}
+}
+
+func TestBuildMappingNodeGolden(t *testing.T) {
+
+	mapping, _, build := readTestMapping(t, "mixed_indentation.yaml")
+
+	roads := mapping.Tables["roads"]
+	roads.Columns = roads.Columns[:2]
+	roads.Mapping["highway"] = []string{"primary", "secondary"}
+	mapping.Tables["roads"] = roads
+
+	places := mapping.Tables["places"]
+	places.Mapping["place"] = []string{"city"}
+
+	generalizedTable := mapping.GeneralizedTables["roads_gen"]
+	generalizedTable.Tolerance = 100
+	mapping.GeneralizedTables["roads_gen"] = generalizedTable
+
+	golden, err := ioutil.ReadFile(filepath.Join("testdata", "mixed_indentation.golden.yaml"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//only the changed nodes are written again, all other lines keep their indentation and comments
+	if newContent := build(mapping); string(newContent) != string(golden) {
+		t.Errorf("the mapping differs from the golden file:\n%s", newContent)
+	}
+}
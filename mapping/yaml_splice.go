@@ -0,0 +1,300 @@
+package mapping
+
+import (
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+//lineRange is a range of lines of the original file, the first and the last line are included and counted from 1
+type lineRange struct {
+	first int
+	last  int
+}
+
+//mappingSplicer writes an edited node tree into the text of the original file.
+//Unchanged entries and items are copied from the original lines, only changed nodes are encoded again
+//at the column and with the indentation of the original node
+type mappingSplicer struct {
+	lines   []string
+	origins map[*yamlv3.Node]*yamlv3.Node
+	format  mappingFormat
+	output  []string
+}
+
+//writeCollection writes a block mapping or block sequence, which was parsed from the lines of the range.
+//False is returned, if the original text has a layout, which cannot be spliced
+func (s *mappingSplicer) writeCollection(node *yamlv3.Node, original *yamlv3.Node, lines lineRange) (bool, error) {
+
+	if node.Kind != original.Kind || (node.Kind != yamlv3.MappingNode && node.Kind != yamlv3.SequenceNode) {
+		return false, nil
+	}
+
+	if node.Style&yamlv3.FlowStyle != 0 || original.Style&yamlv3.FlowStyle != 0 || len(node.Content) == 0 {
+		return false, nil
+	}
+
+	childRanges, column, ok := s.childRanges(original, lines)
+
+	if !ok {
+		return false, nil
+	}
+
+	//a mapping entry consists of the key and the value node
+	step := 1
+
+	if node.Kind == yamlv3.MappingNode {
+		step = 2
+	}
+
+	outputLength := len(s.output)
+
+	s.copyLines(lineRange{lines.first, childRanges[0].first - 1})
+
+	for i := 0; i+step <= len(node.Content); i += step {
+		index := -1
+
+		for j := 0; j+step <= len(original.Content); j += step {
+			if s.origins[node.Content[i]] == original.Content[j] {
+				index = j
+			}
+		}
+
+		if index < 0 {
+			err := s.writeEncoded(node.Kind, node.Content[i:i+step], column, s.childIndent(nil, column), s.format.CompactSequences, false)
+
+			if err != nil {
+				s.output = s.output[:outputLength]
+				return false, err
+			}
+			continue
+		}
+
+		childRange := childRanges[index/step]
+
+		if s.unchanged(node.Content[i:i+step], original.Content[index:index+step]) {
+			s.copyLines(childRange)
+			continue
+		}
+
+		compact := s.format.CompactSequences
+		indent := s.format.Indent
+
+		if node.Kind == yamlv3.MappingNode {
+			value, originalValue := node.Content[i+1], original.Content[index+1]
+
+			//the key line is kept and only the changed entries of a nested collection are written
+			if s.unchanged(node.Content[i:i+1], original.Content[index:index+1]) && s.origins[value] == originalValue && originalValue.Line > original.Content[index].Line {
+				written, err := s.writeCollection(value, originalValue, childRange)
+
+				if err != nil {
+					s.output = s.output[:outputLength]
+					return false, err
+				}
+
+				if written {
+					continue
+				}
+			}
+
+			compact = s.compactSequence(originalValue, column)
+			indent = s.childIndent(originalValue, column)
+		}
+
+		//the comment lines in front of the entry are kept, they are the head comment of the entry
+		keyLine := original.Content[index].Line
+
+		s.copyLines(lineRange{childRange.first, keyLine - 1})
+
+		err := s.writeEncoded(node.Kind, node.Content[i:i+step], column, indent, compact, keyLine > childRange.first)
+
+		if err != nil {
+			s.output = s.output[:outputLength]
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+//childRanges splits the lines of a collection into the lines of its entries or items and returns their column.
+//Comment and empty lines in front of an entry at the column of the entry belong to the entry
+func (s *mappingSplicer) childRanges(original *yamlv3.Node, lines lineRange) ([]lineRange, int, bool) {
+
+	step := 1
+
+	if original.Kind == yamlv3.MappingNode {
+		step = 2
+	}
+
+	childLines := make([]int, 0)
+	column := -1
+
+	for i := 0; i+step <= len(original.Content); i += step {
+		child := original.Content[i]
+
+		if child.Line < lines.first || child.Line > lines.last || (len(childLines) > 0 && child.Line <= childLines[len(childLines)-1]) {
+			return nil, 0, false
+		}
+
+		line := s.lines[child.Line-1]
+		childColumn := child.Column - 1
+
+		if original.Kind == yamlv3.SequenceNode {
+			childColumn = len(line) - len(strings.TrimLeft(line, " "))
+
+			//the item must follow the sequence indicator, nested sequences in a single line are not spliced
+			if childColumn >= len(line) || line[childColumn] != '-' || child.Column-1 > len(line) || child.Column-1 <= childColumn || strings.TrimSpace(line[childColumn+1:child.Column-1]) != "" {
+				return nil, 0, false
+			}
+		} else if childColumn > len(line) || strings.TrimSpace(line[:childColumn]) != "" {
+			return nil, 0, false
+		}
+
+		if column >= 0 && childColumn != column {
+			return nil, 0, false
+		}
+
+		column = childColumn
+		childLines = append(childLines, child.Line)
+	}
+
+	if len(childLines) == 0 {
+		return nil, 0, false
+	}
+
+	childRanges := make([]lineRange, len(childLines))
+
+	for i, childLine := range childLines {
+		first := childLine
+		previousLine := lines.first - 1
+
+		if i > 0 {
+			previousLine = childLines[i-1]
+		}
+
+		for first-1 > previousLine && isHeadCommentLine(s.lines[first-2], column) {
+			first--
+		}
+
+		childRanges[i].first = first
+
+		if i > 0 {
+			childRanges[i-1].last = first - 1
+		}
+	}
+
+	childRanges[len(childRanges)-1].last = lines.last
+
+	return childRanges, column, true
+}
+
+//unchanged checks if the edited nodes are the unchanged copies of the original nodes
+func (s *mappingSplicer) unchanged(nodes []*yamlv3.Node, originals []*yamlv3.Node) bool {
+
+	for i, node := range nodes {
+		original := originals[i]
+
+		if s.origins[node] != original || s.origins[node.Alias] != original.Alias {
+			return false
+		}
+
+		if node.Kind != original.Kind || node.Style != original.Style || node.Tag != original.Tag || node.Value != original.Value || node.Anchor != original.Anchor {
+			return false
+		}
+
+		if node.HeadComment != original.HeadComment || node.LineComment != original.LineComment || node.FootComment != original.FootComment {
+			return false
+		}
+
+		if node.Line != original.Line || node.Column != original.Column || len(node.Content) != len(original.Content) {
+			return false
+		}
+
+		if !s.unchanged(node.Content, original.Content) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//compactSequence checks if the original value is a sequence at the column of its key.
+//Values, which are no block sequences, keep the format of the file
+func (s *mappingSplicer) compactSequence(originalValue *yamlv3.Node, keyColumn int) bool {
+
+	if originalValue.Kind != yamlv3.SequenceNode || originalValue.Style&yamlv3.FlowStyle != 0 || len(originalValue.Content) == 0 {
+		return s.format.CompactSequences
+	}
+
+	line := s.lines[originalValue.Content[0].Line-1]
+
+	return len(line)-len(strings.TrimLeft(line, " ")) == keyColumn
+}
+
+//childIndent returns the indentation of the original value relative to its key, the indentation of the file is the default
+func (s *mappingSplicer) childIndent(originalValue *yamlv3.Node, keyColumn int) int {
+
+	if originalValue == nil || originalValue.Style&yamlv3.FlowStyle != 0 || len(originalValue.Content) == 0 {
+		return s.format.Indent
+	}
+
+	line := s.lines[originalValue.Content[0].Line-1]
+	indent := len(line) - len(strings.TrimLeft(line, " ")) - keyColumn
+
+	if originalValue.Kind == yamlv3.MappingNode && indent >= 2 {
+		return indent
+	}
+
+	if originalValue.Kind == yamlv3.SequenceNode && indent >= 2 && indent <= 9 {
+		return indent
+	}
+
+	return s.format.Indent
+}
+
+//writeEncoded encodes a mapping entry or a sequence item at the column.
+//The head comment is skipped, if the original comment lines are already written
+func (s *mappingSplicer) writeEncoded(kind yamlv3.Kind, entry []*yamlv3.Node, column int, indent int, compact bool, skipHeadComment bool) error {
+
+	container := &yamlv3.Node{Kind: kind}
+
+	for _, node := range entry {
+		container.Content = append(container.Content, node)
+	}
+
+	if skipHeadComment {
+		first := *entry[0]
+		first.HeadComment = ""
+		container.Content[0] = &first
+	}
+
+	content, err := encodeYAML(container, indent, compact)
+
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+		if line != "" {
+			line = strings.Repeat(" ", column) + line
+		}
+
+		s.output = append(s.output, line)
+	}
+
+	return nil
+}
+
+func (s *mappingSplicer) copyLines(lines lineRange) {
+	for line := lines.first; line <= lines.last; line++ {
+		s.output = append(s.output, s.lines[line-1])
+	}
+}
+
+func isHeadCommentLine(line string, column int) bool {
+
+	trimmedLine := strings.TrimLeft(line, " ")
+
+	return trimmedLine == "" || (strings.HasPrefix(trimmedLine, "#") && len(line)-len(trimmedLine) == column)
+}
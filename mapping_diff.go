@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//diffContext is the number of unchanged lines around the changes of the diff
+const diffContext = 3
+
+//diffLine is a line of the diff, kind = " " unchanged, "-" removed or "+" added
+type diffLine struct {
+	kind string
+	text string
+}
+
+//printMappingDiff prints the changes between the original and the optimized mapping file as unified diff.
+//True is returned, if the files differ
+func printMappingDiff(fileName string, original []byte, optimized []byte) bool {
+
+	lines := diffLines(splitLines(original), splitLines(optimized))
+	changed := false
+
+	for _, line := range lines {
+		changed = changed || line.kind != " "
+	}
+
+	if !changed {
+		fmt.Println(`- no changes of the mapping file "` + fileName + `"`)
+		return false
+	}
+
+	fmt.Println("--- " + fileName)
+	fmt.Println("+++ " + fileName + " (optimized)")
+
+	originalLine, optimizedLine := 1, 1
+
+	for start := 0; start < len(lines); {
+		if lines[start].kind == " " {
+			originalLine++
+			optimizedLine++
+			start++
+			continue
+		}
+
+		//a hunk contains the changes, which are separated by less than two contexts of unchanged lines
+		hunkStart := start - diffContext
+
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+
+		hunkEnd := start
+
+		for unchanged := 0; hunkEnd < len(lines) && unchanged <= 2*diffContext; hunkEnd++ {
+			if lines[hunkEnd].kind == " " {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+
+		//the trailing context is cut after the last change
+		for hunkEnd > start && lines[hunkEnd-1].kind == " " {
+			hunkEnd--
+		}
+
+		if hunkEnd+diffContext < len(lines) {
+			hunkEnd += diffContext
+		} else {
+			hunkEnd = len(lines)
+		}
+
+		hunkOriginalStart := originalLine - (start - hunkStart)
+		hunkOptimizedStart := optimizedLine - (start - hunkStart)
+		originalCount, optimizedCount := 0, 0
+
+		for _, line := range lines[hunkStart:hunkEnd] {
+			if line.kind != "+" {
+				originalCount++
+			}
+
+			if line.kind != "-" {
+				optimizedCount++
+			}
+		}
+
+		fmt.Println("@@ -" + hunkRange(hunkOriginalStart, originalCount) + " +" + hunkRange(hunkOptimizedStart, optimizedCount) + " @@")
+
+		for _, line := range lines[hunkStart:hunkEnd] {
+			fmt.Println(line.kind + line.text)
+		}
+
+		originalLine = hunkOriginalStart + originalCount
+		optimizedLine = hunkOptimizedStart + optimizedCount
+		start = hunkEnd
+	}
+
+	return true
+}
+
+//diffLines compares the lines with the longest common subsequence and returns the unchanged, removed and added lines
+func diffLines(original []string, optimized []string) []diffLine {
+
+	//common[i][j] = length of the longest common subsequence of original[i:] and optimized[j:]
+	common := make([][]int, len(original)+1)
+
+	for i := range common {
+		common[i] = make([]int, len(optimized)+1)
+	}
+
+	for i := len(original) - 1; i >= 0; i-- {
+		for j := len(optimized) - 1; j >= 0; j-- {
+			if original[i] == optimized[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	lines := make([]diffLine, 0, len(original)+len(optimized))
+	i, j := 0, 0
+
+	for i < len(original) || j < len(optimized) {
+		switch {
+		case i < len(original) && j < len(optimized) && original[i] == optimized[j]:
+			lines = append(lines, diffLine{" ", original[i]})
+			i++
+			j++
+		case j == len(optimized) || (i < len(original) && common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, diffLine{"-", original[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{"+", optimized[j]})
+			j++
+		}
+	}
+
+	return lines
+}
+
+func splitLines(data []byte) []string {
+
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	if text == "" {
+		return []string{}
+	}
+
+	return strings.Split(text, "\n")
+}
+
+//hunkRange returns the line range of a hunk like "12,7", an empty range starts before its first line
+func hunkRange(start int, count int) string {
+
+	if count == 0 {
+		start--
+	}
+
+	return strconv.Itoa(start) + "," + strconv.Itoa(count)
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+//geometryDimensions contains the PostGIS dimension of every imposm3 geometry type
+var geometryDimensions = map[string]int{"point": 0, "linestring": 1, "polygon": 2}
+
+//Settings contains the connection to the database of an existing imposm3 import.
+//Schema = schema of the imported tables, the imposm3 default is "import"
+//TablePrefix = prefix of the imported tables, the imposm3 default is "osm_"
+type Settings struct {
+	Connection  string `json:"connection"`
+	Schema      string `json:"schema,omitempty"`
+	TablePrefix string `json:"table_prefix,omitempty"`
+}
+
+//Database is a connection to the database of an existing imposm3 import
+type Database struct {
+	db          *sql.DB
+	schema      string
+	tablePrefix string
+}
+
+//Connect opens the connection to the database and checks if it is reachable
+func Connect(settings Settings) (*Database, error) {
+
+	db, err := sql.Open("postgres", settings.Connection)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Ping()
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	schema := settings.Schema
+
+	if schema == "" {
+		schema = "import"
+	}
+
+	tablePrefix := settings.TablePrefix
+
+	if tablePrefix == "" {
+		tablePrefix = "osm_"
+	}
+
+	return &Database{db, schema, tablePrefix}, nil
+}
+
+//CountRows counts the rows of an imported table, whose geometry has one of the geometry types (point, linestring or polygon)
+func (d *Database) CountRows(tableName string, geometryColumn string, geometryTypes []string) (int64, error) {
+
+	dimensions := make([]string, 0)
+
+	for _, geometryType := range geometryTypes {
+		if dimension, found := geometryDimensions[geometryType]; found {
+			dimensions = append(dimensions, strconv.Itoa(dimension))
+		}
+	}
+
+	if len(dimensions) == 0 {
+		return 0, nil
+	}
+
+	query := "SELECT count(*) FROM " + pq.QuoteIdentifier(d.schema) + "." + pq.QuoteIdentifier(d.tablePrefix+tableName) +
+		" WHERE ST_Dimension(" + pq.QuoteIdentifier(geometryColumn) + ") IN (" + strings.Join(dimensions, ", ") + ")"
+
+	var count int64
+
+	err := d.db.QueryRow(query).Scan(&count)
+
+	return count, err
+}
+
+//Close closes the connection to the database
+func (d *Database) Close() error {
+	return d.db.Close()
+}
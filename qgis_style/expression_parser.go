@@ -0,0 +1,655 @@
+package qgis
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+//token kinds of a QGIS expression
+const (
+	fieldToken = iota
+	stringToken
+	numberToken
+	wordToken
+	operatorToken
+)
+
+type token struct {
+	kind  int
+	value string
+}
+
+//expression is a node of a parsed QGIS expression
+//kind = field, literal, variable, function, binary, unary, in, is, between, like or case
+type expression struct {
+	kind     string
+	value    string
+	children []expression
+}
+
+//expressionKeywords are the words of an expression, which are no field names
+var expressionKeywords = []string{"and", "or", "not", "in", "is", "between", "null", "like", "ilike", "true", "false", "case", "when", "then", "else", "end"}
+
+//binaryPrecedence of the binary operators, higher values bind stronger
+var binaryPrecedence = map[string]int{
+	"or":  1,
+	"and": 2,
+	"=":   4, "<>": 4, "!=": 4, "<": 4, "<=": 4, ">": 4, ">=": 4, "~": 4, "like": 4, "ilike": 4, "is": 4, "in": 4, "between": 4,
+	"||": 5,
+	"+":  6, "-": 6,
+	"*": 7, "/": 7, "//": 7, "%": 7,
+	"^": 8}
+
+//comparisonOperators maps the QGIS comparison operators to the OGC comparison operators
+var comparisonOperators = map[string]string{
+	"=":  "PropertyIsEqualTo",
+	"<>": "PropertyIsNotEqualTo",
+	"!=": "PropertyIsNotEqualTo",
+	"<":  "PropertyIsLessThan",
+	"<=": "PropertyIsLessThanOrEqualTo",
+	">":  "PropertyIsGreaterThan",
+	">=": "PropertyIsGreaterThanOrEqualTo"}
+
+//arithmeticOperators maps the QGIS arithmetic operators to the OGC arithmetic operators
+var arithmeticOperators = map[string]string{
+	"+": "Add",
+	"-": "Sub",
+	"*": "Mul",
+	"/": "Div"}
+
+//ExpressionColumns returns the fields used in a QGIS expression like "name" || ' ' || "ref".
+//Texts, which are no valid expression, are treated as a single field name
+func ExpressionColumns(text string) []string {
+
+	tokens, err := tokenize(text)
+
+	if err != nil {
+		return []string{strings.Trim(strings.TrimSpace(text), `"`)}
+	}
+
+	columns := make([]string, 0)
+
+	for i, t := range tokens {
+		isFunction := i+1 < len(tokens) && tokens[i+1].kind == operatorToken && tokens[i+1].value == "("
+		isVariable := strings.HasPrefix(t.value, "@") || strings.HasPrefix(t.value, "$")
+
+		if t.kind == fieldToken || (t.kind == wordToken && !isFunction && !isVariable && !containsFold(expressionKeywords, t.value)) {
+			columns = functions.AppendUnique(columns, t.value)
+		}
+	}
+
+	return columns
+}
+
+//ParseFilter converts a QGIS filter expression like "highway" IN ('primary', 'secondary') AND "tunnel" IS NULL
+//into a filter tree. Parts of the expression, which can not be represented as filter, are returned as unknown filter
+func ParseFilter(text string) (sld.FilterNode, error) {
+
+	parsed, err := parseExpression(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed.filter(), nil
+}
+
+func parseExpression(text string) (expression, error) {
+
+	tokens, err := tokenize(text)
+
+	if err != nil {
+		return expression{}, err
+	}
+
+	parser := expressionParser{tokens, 0}
+	parsed, err := parser.parseBinary(0)
+
+	if err != nil {
+		return expression{}, err
+	}
+
+	if parser.position < len(parser.tokens) {
+		return expression{}, errors.New(`unexpected "` + parser.tokens[parser.position].value + `" in expression "` + text + `"`)
+	}
+
+	return parsed, nil
+}
+
+func tokenize(text string) ([]token, error) {
+
+	tokens := make([]token, 0)
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '"' || r == '\'':
+			//quotes are escaped by doubling them
+			value := ""
+			end := i + 1
+
+			for ; end < len(runes); end++ {
+				if runes[end] == r {
+					if end+1 < len(runes) && runes[end+1] == r {
+						end++
+					} else {
+						break
+					}
+				} else if runes[end] == '\\' && r == '\'' && end+1 < len(runes) {
+					end++
+				}
+				value += string(runes[end])
+			}
+
+			if end == len(runes) {
+				return nil, errors.New(`unclosed quote in "` + text + `"`)
+			}
+
+			kind := stringToken
+
+			if r == '"' {
+				kind = fieldToken
+			}
+
+			tokens = append(tokens, token{kind, value})
+			i = end + 1
+
+		case unicode.IsDigit(r):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.' || runes[end] == 'e' || runes[end] == 'E') {
+				end++
+			}
+
+			tokens = append(tokens, token{numberToken, string(runes[i:end])})
+			i = end
+
+		case unicode.IsLetter(r) || r == '_' || r == '@' || r == '$':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+
+			tokens = append(tokens, token{wordToken, string(runes[i:end])})
+			i = end
+
+		default:
+			operator := string(r)
+
+			if i+1 < len(runes) {
+				if pair := string(runes[i : i+2]); containsFold([]string{"<>", "!=", "<=", ">=", "||", "//"}, pair) {
+					operator = pair
+				}
+			}
+
+			if !strings.Contains("=!<>|+-*/%^~(),[]", string(r)) {
+				return nil, errors.New(`unknown character "` + string(r) + `" in "` + text + `"`)
+			}
+
+			tokens = append(tokens, token{operatorToken, operator})
+			i += len([]rune(operator))
+		}
+	}
+
+	return tokens, nil
+}
+
+type expressionParser struct {
+	tokens   []token
+	position int
+}
+
+func (p *expressionParser) peek() (token, bool) {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position], true
+	}
+
+	return token{}, false
+}
+
+//isWord checks if the next token is the keyword
+func (p *expressionParser) isWord(word string) bool {
+	next, ok := p.peek()
+	return ok && next.kind == wordToken && strings.EqualFold(next.value, word)
+}
+
+//isOperator checks if the next token is the operator
+func (p *expressionParser) isOperator(operator string) bool {
+	next, ok := p.peek()
+	return ok && next.kind == operatorToken && next.value == operator
+}
+
+func (p *expressionParser) expectOperator(operator string) error {
+
+	if !p.isOperator(operator) {
+		return errors.New(`"` + operator + `" expected`)
+	}
+
+	p.position++
+	return nil
+}
+
+//binaryOperator returns the operator of the next token, "NOT IN", "NOT LIKE", "NOT BETWEEN" and "IS NOT" are returned as negated operators
+func (p *expressionParser) binaryOperator() (string, bool, bool) {
+
+	next, ok := p.peek()
+
+	if !ok || (next.kind != operatorToken && next.kind != wordToken) {
+		return "", false, false
+	}
+
+	operator := strings.ToLower(next.value)
+
+	if operator == "not" && p.position+1 < len(p.tokens) {
+		following := strings.ToLower(p.tokens[p.position+1].value)
+
+		if p.tokens[p.position+1].kind == wordToken && (following == "in" || following == "like" || following == "ilike" || following == "between") {
+			return following, true, true
+		}
+	}
+
+	_, known := binaryPrecedence[operator]
+
+	if next.kind == wordToken && !containsFold(expressionKeywords, operator) {
+		return "", false, false
+	}
+
+	return operator, false, known
+}
+
+//parseBinary parses binary operators by precedence climbing
+func (p *expressionParser) parseBinary(minPrecedence int) (expression, error) {
+
+	left, err := p.parseUnary()
+
+	if err != nil {
+		return expression{}, err
+	}
+
+	for {
+		operator, negated, ok := p.binaryOperator()
+
+		if !ok || binaryPrecedence[operator] <= minPrecedence {
+			return left, nil
+		}
+
+		p.position++
+
+		if negated {
+			p.position++
+		}
+
+		var parsed expression
+
+		switch operator {
+		case "in":
+			parsed, err = p.parseInList(left)
+		case "is":
+			parsed, err = p.parseIs(left)
+		case "between":
+			parsed, err = p.parseBetween(left)
+		default:
+			var right expression
+			right, err = p.parseBinary(binaryPrecedence[operator])
+			parsed = expression{"binary", operator, []expression{left, right}}
+		}
+
+		if err != nil {
+			return expression{}, err
+		}
+
+		if negated {
+			parsed = expression{"unary", "not", []expression{parsed}}
+		}
+
+		left = parsed
+	}
+}
+
+//parseInList parses the value list of an IN operator
+func (p *expressionParser) parseInList(left expression) (expression, error) {
+
+	arguments, err := p.parseArguments()
+
+	if err != nil {
+		return expression{}, err
+	}
+
+	return expression{"in", "", append([]expression{left}, arguments...)}, nil
+}
+
+//parseIs parses "IS NULL", "IS NOT NULL" and the comparisons "IS value"
+func (p *expressionParser) parseIs(left expression) (expression, error) {
+
+	negated := p.isWord("not")
+
+	if negated {
+		p.position++
+	}
+
+	right, err := p.parseBinary(binaryPrecedence["is"])
+
+	if err != nil {
+		return expression{}, err
+	}
+
+	parsed := expression{"is", "", []expression{left, right}}
+
+	if negated {
+		return expression{"unary", "not", []expression{parsed}}, nil
+	}
+
+	return parsed, nil
+}
+
+//parseBetween parses the boundaries of "BETWEEN lower AND upper", the AND binds weaker than the boundaries
+func (p *expressionParser) parseBetween(left expression) (expression, error) {
+
+	lower, err := p.parseBinary(binaryPrecedence["between"])
+
+	if err != nil {
+		return expression{}, err
+	}
+
+	if !p.isWord("and") {
+		return expression{}, errors.New(`"AND" expected after the lower boundary of BETWEEN`)
+	}
+
+	p.position++
+
+	upper, err := p.parseBinary(binaryPrecedence["between"])
+
+	if err != nil {
+		return expression{}, err
+	}
+
+	return expression{"between", "", []expression{left, lower, upper}}, nil
+}
+
+func (p *expressionParser) parseUnary() (expression, error) {
+
+	if p.isWord("not") {
+		p.position++
+
+		//NOT binds weaker than the comparisons
+		operand, err := p.parseBinary(binaryPrecedence["and"])
+
+		if err != nil {
+			return expression{}, err
+		}
+
+		return expression{"unary", "not", []expression{operand}}, nil
+	}
+
+	if p.isOperator("-") {
+		p.position++
+
+		operand, err := p.parseUnary()
+
+		if err != nil {
+			return expression{}, err
+		}
+
+		return expression{"unary", "-", []expression{operand}}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (expression, error) {
+
+	next, ok := p.peek()
+
+	if !ok {
+		return expression{}, errors.New("unexpected end of expression")
+	}
+
+	p.position++
+
+	switch next.kind {
+	case fieldToken:
+		return expression{"field", next.value, nil}, nil
+
+	case stringToken, numberToken:
+		return expression{"literal", next.value, nil}, nil
+
+	case wordToken:
+		lower := strings.ToLower(next.value)
+
+		switch {
+		case p.isOperator("("):
+			arguments, err := p.parseArguments()
+
+			if err != nil {
+				return expression{}, err
+			}
+
+			return expression{"function", lower, arguments}, nil
+
+		case lower == "case":
+			return p.parseCase()
+
+		case lower == "null" || lower == "true" || lower == "false":
+			return expression{"literal", lower, nil}, nil
+
+		case strings.HasPrefix(next.value, "@") || strings.HasPrefix(next.value, "$"):
+			return expression{"variable", next.value, nil}, nil
+		}
+
+		//field names without quotes
+		return expression{"field", next.value, nil}, nil
+
+	case operatorToken:
+		if next.value == "(" {
+			inner, err := p.parseBinary(0)
+
+			if err != nil {
+				return expression{}, err
+			}
+
+			return inner, p.expectOperator(")")
+		}
+
+		//array literals like array('a', 'b') are functions, brackets are not supported
+	}
+
+	return expression{}, errors.New(`unexpected "` + next.value + `"`)
+}
+
+//parseCase parses a CASE WHEN condition THEN value ... ELSE value END expression
+func (p *expressionParser) parseCase() (expression, error) {
+
+	children := make([]expression, 0)
+
+	for !p.isWord("end") {
+		if _, ok := p.peek(); !ok {
+			return expression{}, errors.New(`"END" expected`)
+		}
+
+		if p.isWord("when") || p.isWord("then") || p.isWord("else") {
+			p.position++
+			continue
+		}
+
+		child, err := p.parseBinary(0)
+
+		if err != nil {
+			return expression{}, err
+		}
+
+		children = append(children, child)
+	}
+
+	p.position++
+
+	return expression{"case", "", children}, nil
+}
+
+func (p *expressionParser) parseArguments() ([]expression, error) {
+
+	if err := p.expectOperator("("); err != nil {
+		return nil, err
+	}
+
+	arguments := make([]expression, 0)
+
+	if p.isOperator(")") {
+		p.position++
+		return arguments, nil
+	}
+
+	for {
+		argument, err := p.parseBinary(0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		arguments = append(arguments, argument)
+
+		if p.isOperator(",") {
+			p.position++
+			continue
+		}
+
+		return arguments, p.expectOperator(")")
+	}
+}
+
+//filter converts the parsed expression into a filter tree
+func (e expression) filter() sld.FilterNode {
+
+	switch e.kind {
+	case "binary":
+		switch e.value {
+		case "and":
+			return sld.LogicalFilter{Name: "And", Children: append(e.children[0].logicalChildren("And"), e.children[1].logicalChildren("And")...)}
+		case "or":
+			return sld.LogicalFilter{Name: "Or", Children: append(e.children[0].logicalChildren("Or"), e.children[1].logicalChildren("Or")...)}
+		case "like", "ilike":
+			if e.children[0].kind == "field" && e.children[1].kind == "literal" {
+				return sld.LikeFilter{Expression: e.children[0].expression(), Pattern: e.children[1].value, WildCard: "%", SingleChar: "_", EscapeChar: "\\", MatchCase: e.value == "like"}
+			}
+		case "~":
+			return regexpFilter(e.children[0], e.children[1])
+		}
+
+		if operator, isComparison := comparisonOperators[e.value]; isComparison {
+			//comparisons with NULL are never true
+			if e.children[0].isNull() || e.children[1].isNull() {
+				break
+			}
+
+			return sld.ComparisonFilter{Name: operator, Left: e.children[0].expression(), Right: e.children[1].expression(), MatchCase: true}
+		}
+
+	case "is":
+		if e.children[1].isNull() {
+			return sld.NullFilter{Name: "PropertyIsNull", Expression: e.children[0].expression()}
+		}
+
+		return sld.ComparisonFilter{Name: "PropertyIsEqualTo", Left: e.children[0].expression(), Right: e.children[1].expression(), MatchCase: true}
+
+	case "in":
+		children := make([]sld.FilterNode, 0, len(e.children)-1)
+
+		for _, value := range e.children[1:] {
+			children = append(children, sld.ComparisonFilter{Name: "PropertyIsEqualTo", Left: e.children[0].expression(), Right: value.expression(), MatchCase: true})
+		}
+
+		return sld.LogicalFilter{Name: "Or", Children: children}
+
+	case "between":
+		//the boundaries are included like in PropertyIsBetween, NULL boundaries are never true
+		if e.children[1].isNull() || e.children[2].isNull() {
+			break
+		}
+
+		return sld.BetweenFilter{Expression: e.children[0].expression(), LowerBoundary: e.children[1].expression(), UpperBoundary: e.children[2].expression()}
+
+	case "unary":
+		if e.value == "not" {
+			return sld.NotFilter{Child: e.children[0].filter()}
+		}
+
+	case "function":
+		if e.value == "regexp_match" && len(e.children) == 2 {
+			return regexpFilter(e.children[0], e.children[1])
+		}
+	}
+
+	return sld.UnknownFilter{Name: "qgis:" + e.kind}
+}
+
+//regexpFilter returns a filter, which searches the regular expression in the value of a field
+func regexpFilter(field expression, pattern expression) sld.FilterNode {
+
+	if field.kind == "field" && pattern.kind == "literal" {
+		anchored := "^.*(?:" + pattern.value + ").*$"
+
+		if _, err := regexp.Compile(anchored); err == nil {
+			return sld.RegexpFilter{Expression: field.expression(), Pattern: anchored}
+		}
+	}
+
+	return sld.UnknownFilter{Name: "qgis:regexp"}
+}
+
+//logicalChildren flattens nested logical operators of the same kind
+func (e expression) logicalChildren(name string) []sld.FilterNode {
+
+	child := e.filter()
+
+	if logical, ok := child.(sld.LogicalFilter); ok && logical.Name == name {
+		return logical.Children
+	}
+
+	return []sld.FilterNode{child}
+}
+
+//expression converts the parsed expression into an OGC expression
+func (e expression) expression() sld.Expression {
+
+	switch e.kind {
+	case "field":
+		return sld.PropertyNameExpression{Name: e.value}
+
+	case "literal":
+		return sld.LiteralExpression{Value: e.value}
+
+	case "binary":
+		if operator, isArithmetic := arithmeticOperators[e.value]; isArithmetic {
+			return sld.ArithmeticExpression{Name: operator, Left: e.children[0].expression(), Right: e.children[1].expression()}
+		}
+	}
+
+	arguments := make([]sld.Expression, 0)
+
+	for _, child := range e.children {
+		arguments = append(arguments, child.expression())
+	}
+
+	return sld.FunctionExpression{Name: e.kind + ":" + e.value, Arguments: arguments}
+}
+
+func (e expression) isNull() bool {
+	return e.kind == "literal" && e.value == "null"
+}
+
+func containsFold(list []string, value string) bool {
+	for _, entry := range list {
+		if strings.EqualFold(entry, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,839 @@
+package qgis
+
+import (
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//symbolKinds maps the symbol types to the symbolizer kinds of the sld package
+var symbolKinds = map[string]string{
+	"marker": sld.PointKind,
+	"line":   sld.LineKind,
+	"fill":   sld.PolygonKind}
+
+//mapUnits are the symbol units, which are measured on the ground
+var mapUnits = []string{"MapUnit", "MetersInMapUnits", "RenderMetersInMapUnits"}
+
+//datasourceTables finds the tables of a PostgreSQL (table="public"."osm_roads") or OGR (|layername=roads) datasource
+var datasourceTables = regexp.MustCompile(`(?:table=(?:"[^"]*"\.)?"?|layername=)([^"\s|]+)`)
+
+//Parser class
+type Parser struct {
+	filePath          string
+	successfullPasing bool
+	document          Document
+	tableNames        []string
+	tablePrefix       string
+	usedLayers        []string
+}
+
+//nestedRule is a rule of a rule-based renderer or a rule-based labeling, the child rules are only used if their parent matches
+type nestedRule struct {
+	name          string
+	filter        string
+	active        string
+	scaleMinDenom string
+	scaleMaxDenom string
+	symbol        string
+	settings      *LabelSettings
+	children      []nestedRule
+}
+
+//ruleContext contains the filters and the scale range, which a rule inherits from its layer and its parent rules
+type ruleContext struct {
+	filters     []sld.FilterNode
+	filterTexts []string
+	scale       scaleRange
+	layerIndex  int
+}
+
+//New QGIS style parser instance for a .qml style or a .qgs/.qgz project
+func New(filePath string) Parser {
+	s := Parser{filePath, false, Document{}, nil, "", make([]string, 0)}
+	return s
+}
+
+func (s *Parser) loadStyleFile() error {
+
+	if s.successfullPasing {
+		return nil
+	}
+
+	var content []byte
+	var err error
+
+	switch filepath.Ext(s.filePath) {
+	case ".qml", ".qgs":
+		content, err = ioutil.ReadFile(s.filePath)
+	case ".qgz":
+		content, err = readProjectArchive(s.filePath)
+	default:
+		return errors.New(`"` + s.filePath + `" must be a .qml, .qgs or .qgz file`)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	err = decoder.Decode(&s.document)
+
+	if err != nil {
+		return errors.New(`"` + s.filePath + `" is no valid QGIS file: ` + err.Error())
+	}
+
+	//a style file is a single layer, which is named like the file
+	if filepath.Ext(s.filePath) == ".qml" {
+		layer := s.document.Layer
+		layer.LayerName = strings.TrimSuffix(filepath.Base(s.filePath), ".qml")
+		s.document.Layers = []Layer{layer}
+	}
+
+	s.successfullPasing = true
+
+	return nil
+}
+
+//readProjectArchive returns the .qgs project of a zipped .qgz project
+func readProjectArchive(filePath string) ([]byte, error) {
+
+	archive, err := zip.OpenReader(filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if filepath.Ext(file.Name) != ".qgs" {
+			continue
+		}
+
+		reader, err := file.Open()
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer reader.Close()
+
+		return ioutil.ReadAll(reader)
+	}
+
+	return nil, errors.New(`"` + filePath + `" contains no .qgs project`)
+}
+
+//SetTableNames restricts the extracted requirements to the layers, which match one of the tables by their name or the table
+//of their datasource. The first table matching at least one layer is used. A style with a single layer like a .qml file
+//is used completely, if no layer matches. Otherwise no layer is used
+func (s *Parser) SetTableNames(tableNames []string, tablePrefix string) {
+	s.tableNames = tableNames
+	s.tablePrefix = tablePrefix
+}
+
+//LayerNames returns the names of all layers and the tables of their datasources
+func (s *Parser) LayerNames() ([]string, error) {
+
+	err := s.loadStyleFile()
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+
+	for _, layer := range s.document.Layers {
+		for _, name := range layerTableNames(layer) {
+			names = functions.AppendUnique(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+//UsedLayers returns the names of the layers, which were used for the requirements of the table.
+//The list is empty, if the whole file was used
+func (s *Parser) UsedLayers() []string {
+	return s.usedLayers
+}
+
+//GetFilePath returns the path to the style or project file
+func (s *Parser) GetFilePath() string {
+	return s.filePath
+}
+
+//ExtractRequirements ( mappingColumns ) will return all required values of the renderers and labelings in form of a ParsedSLD structure.
+//Every rule, category, range and label setting is handled like a SLD rule with the scale range of its layer
+func (s *Parser) ExtractRequirements(mappingColumns sld.MappingColumnNames) (sld.ParsedSLD, error) {
+
+	err := s.loadStyleFile()
+
+	if err != nil {
+		return sld.ParsedSLD{}, err
+	}
+
+	requirements := sld.NewTableRequirements(mappingColumns)
+	ruleList := make([]sld.Rule, 0)
+
+	for layerIndex, layer := range s.selectedLayers() {
+		context := ruleContext{nil, nil, layerScale(layer), layerIndex}
+
+		if context.scale.isEmpty() {
+			continue
+		}
+
+		//the subset of the datasource restricts all features of the layer
+		if subset := datasourceSubset(layer.Datasource); subset != "" {
+			context = context.with(subset, context.scale)
+		}
+
+		if layer.Renderer != nil {
+			ruleList = append(ruleList, rendererRules(*layer.Renderer, context, &requirements)...)
+		}
+
+		if layer.LabelsEnabled != "0" {
+			ruleList = append(ruleList, labelingRules(layer, context, &requirements)...)
+		}
+	}
+
+	scaleDenominator, useAllMappingTypes := sld.CompleteRequirements(ruleList, &requirements)
+
+	return sld.ParsedSLD{
+		FileName:           s.filePath,
+		Requirements:       requirements,
+		Scale:              scaleDenominator,
+		UseAllMappingTypes: useAllMappingTypes,
+		Version:            strings.TrimSpace("qgis " + s.document.Version)}, nil
+}
+
+//selectedLayers returns the layers of the first table, which matches the name or the datasource table of a layer.
+//Without matching table only a style with a single layer is used
+func (s *Parser) selectedLayers() []Layer {
+
+	s.usedLayers = make([]string, 0)
+
+	for _, tableName := range s.tableNames {
+		layers := make([]Layer, 0)
+
+		for _, layer := range s.document.Layers {
+			for _, name := range layerTableNames(layer) {
+				if sld.MatchesTable(name, tableName, s.tablePrefix) {
+					layers = append(layers, layer)
+					s.usedLayers = functions.AppendUnique(s.usedLayers, layer.LayerName)
+					break
+				}
+			}
+		}
+
+		if len(layers) > 0 {
+			return layers
+		}
+	}
+
+	if len(s.document.Layers) <= 1 {
+		return s.document.Layers
+	}
+
+	fmt.Println(`WARNING: no layer of the style file "`+s.filePath+`" matches the tables`, s.tableNames)
+
+	return []Layer{}
+}
+
+//rendererRules converts the symbols of a renderer into SLD rules and adds the columns of its filters and symbols to the requirements
+func rendererRules(renderer Renderer, context ruleContext, requirements *sld.TableRequirements) []sld.Rule {
+
+	//renderers like the point displacement renderer draw the symbols of an embedded renderer
+	for renderer.Embedded != nil {
+		renderer = *renderer.Embedded
+	}
+
+	if renderer.EnableOrderBy == "1" {
+		for _, clause := range renderer.OrderBy {
+			for _, column := range ExpressionColumns(clause) {
+				sld.AddRequiredColumn(requirements, column, []string{}, context.scale.scaleDenominator(), sld.SortUsage)
+			}
+		}
+	}
+
+	ruleList := make([]sld.Rule, 0)
+
+	switch renderer.Type {
+	case "nullSymbol":
+		//features are not drawn
+
+	case "RuleRenderer":
+		rules := make([]nestedRule, 0, len(renderer.Rules))
+
+		for _, rule := range renderer.Rules {
+			rules = append(rules, rendererRule(rule))
+		}
+
+		ruleList = nestedRules(rules, context, renderer.Symbols, requirements)
+
+	case "categorizedSymbol":
+		ruleList = categoryRules(renderer, context, requirements)
+
+	case "graduatedSymbol":
+		for _, valueRange := range renderer.Ranges {
+			if valueRange.Render == "false" {
+				continue
+			}
+
+			filterText := "(" + renderer.Attr + ") >= " + valueRange.Lower + " AND (" + renderer.Attr + ") <= " + valueRange.Upper
+
+			if rule, ok := symbolRule(valueRange.Label, context.with(filterText, context.scale), findSymbol(renderer.Symbols, valueRange.Symbol), requirements); ok {
+				ruleList = append(ruleList, rule)
+			}
+		}
+
+	default:
+		//single symbol renderers and unknown renderers draw all symbols
+		for _, symbol := range renderer.Symbols {
+			if rule, ok := symbolRule(symbol.Name, context, &symbol, requirements); ok {
+				ruleList = append(ruleList, rule)
+			}
+		}
+	}
+
+	return ruleList
+}
+
+//categoryRules converts the categories of a categorized renderer, a category with an empty value matches all other values
+func categoryRules(renderer Renderer, context ruleContext, requirements *sld.TableRequirements) []sld.Rule {
+
+	ruleList := make([]sld.Rule, 0)
+	valueFilters := make([]string, 0)
+	otherCategories := make([]Category, 0)
+
+	for _, category := range renderer.Categories {
+		values := make([]string, 0)
+
+		for _, value := range category.Values {
+			values = append(values, quoteLiteral(value.Value))
+		}
+
+		if len(values) == 0 && category.Value != "" {
+			values = append(values, quoteLiteral(category.Value))
+		}
+
+		if len(values) == 0 {
+			otherCategories = append(otherCategories, category)
+			continue
+		}
+
+		filterText := "(" + renderer.Attr + ") IN (" + strings.Join(values, ", ") + ")"
+		valueFilters = append(valueFilters, filterText)
+
+		if category.Render == "false" {
+			continue
+		}
+
+		if rule, ok := symbolRule(category.Label, context.with(filterText, context.scale), findSymbol(renderer.Symbols, category.Symbol), requirements); ok {
+			ruleList = append(ruleList, rule)
+		}
+	}
+
+	for _, category := range otherCategories {
+		if category.Render == "false" {
+			continue
+		}
+
+		categoryContext := context
+
+		if len(valueFilters) > 0 {
+			categoryContext = context.with("NOT ("+strings.Join(valueFilters, " OR ")+")", context.scale)
+		}
+
+		if rule, ok := symbolRule(category.Label, categoryContext, findSymbol(renderer.Symbols, category.Symbol), requirements); ok {
+			ruleList = append(ruleList, rule)
+		}
+	}
+
+	return ruleList
+}
+
+//labelingRules converts the label settings of a layer into SLD rules with a text symbolizer
+func labelingRules(layer Layer, context ruleContext, requirements *sld.TableRequirements) []sld.Rule {
+
+	//QGIS 2 stores the label settings as custom properties
+	if layer.Labeling == nil {
+		if settings, ok := legacyLabelSettings(layer.CustomProperties); ok {
+			return nestedRules([]nestedRule{{settings: &settings}}, context, nil, requirements)
+		}
+
+		return []sld.Rule{}
+	}
+
+	switch layer.Labeling.Type {
+	case "simple":
+		if layer.Labeling.Settings != nil {
+			return nestedRules([]nestedRule{{settings: layer.Labeling.Settings}}, context, nil, requirements)
+		}
+	case "rule-based":
+		rules := make([]nestedRule, 0, len(layer.Labeling.Rules))
+
+		for _, rule := range layer.Labeling.Rules {
+			rules = append(rules, labelRule(rule))
+		}
+
+		return nestedRules(rules, context, nil, requirements)
+	}
+
+	return []sld.Rule{}
+}
+
+//nestedRules converts the rules of a rule-based renderer or labeling and their child rules.
+//A rule with the filter "ELSE" matches all features, which are not matched by the sibling rules
+func nestedRules(rules []nestedRule, context ruleContext, symbols []Symbol, requirements *sld.TableRequirements) []sld.Rule {
+
+	ruleList := make([]sld.Rule, 0)
+
+	for _, rule := range rules {
+		if rule.active == "0" {
+			continue
+		}
+
+		scale := context.scale.restrict(parseScale(rule.scaleMinDenom), parseScale(rule.scaleMaxDenom))
+
+		if scale.isEmpty() {
+			continue
+		}
+
+		ruleContext := context
+
+		switch rule.filter {
+		case "":
+			ruleContext = context.with("", scale)
+
+		case "ELSE":
+			siblingFilters := make([]string, 0)
+			neverMatches := false
+
+			for _, sibling := range rules {
+				siblingScale := context.scale.restrict(parseScale(sibling.scaleMinDenom), parseScale(sibling.scaleMaxDenom))
+
+				if sibling.filter == "ELSE" || sibling.active == "0" || !siblingScale.covers(scale) {
+					continue
+				}
+
+				if sibling.filter == "" {
+					neverMatches = true
+					break
+				}
+
+				siblingFilters = append(siblingFilters, "("+sibling.filter+")")
+			}
+
+			if neverMatches {
+				fmt.Println(`- ELSE rule "` + rule.name + `" ignored, another rule without filter matches all features`)
+				continue
+			}
+
+			ruleContext = context.with("", scale)
+
+			if len(siblingFilters) > 0 {
+				ruleContext = context.with("NOT ("+strings.Join(siblingFilters, " OR ")+")", scale)
+			}
+
+		default:
+			ruleContext = context.with(rule.filter, scale)
+		}
+
+		if rule.symbol != "" {
+			if converted, ok := symbolRule(rule.name, ruleContext, findSymbol(symbols, rule.symbol), requirements); ok {
+				ruleList = append(ruleList, converted)
+			}
+		}
+
+		if rule.settings != nil {
+			if converted, ok := labelSettingsRule(rule.name, ruleContext, *rule.settings, requirements); ok {
+				ruleList = append(ruleList, converted)
+			}
+		}
+
+		ruleList = append(ruleList, nestedRules(rule.children, ruleContext, symbols, requirements)...)
+	}
+
+	return ruleList
+}
+
+//symbolRule converts a symbol into a SLD rule and adds the columns of its data defined properties to the requirements.
+//False is returned, if the symbol does not exist
+func symbolRule(name string, context ruleContext, symbol *Symbol, requirements *sld.TableRequirements) (sld.Rule, bool) {
+
+	if symbol == nil {
+		return sld.Rule{}, false
+	}
+
+	rule := context.rule(name, requirements)
+	symbolizer := []sld.Symbolizer{{XMLContent: nil, UnitOfMeasure: symbolUnit(symbol.Elements)}}
+
+	switch symbolKinds[symbol.Type] {
+	case sld.PointKind:
+		rule.PointSymbolizer = symbolizer
+	case sld.LineKind:
+		rule.LineSymbolizer = symbolizer
+	case sld.PolygonKind:
+		rule.PolygonSymbolizer = symbolizer
+	}
+
+	for _, column := range dataDefinedColumns(symbol.Elements) {
+		sld.AddRequiredColumn(requirements, column, []string{}, context.scale.scaleDenominator(), sld.StyleUsage)
+	}
+
+	return rule, true
+}
+
+//labelSettingsRule converts label settings into a SLD rule with a text symbolizer and adds the columns of the label text to the requirements.
+//False is returned, if the label is not drawn
+func labelSettingsRule(name string, context ruleContext, settings LabelSettings, requirements *sld.TableRequirements) (sld.Rule, bool) {
+
+	if settings.Rendering.DrawLabels == "0" || settings.TextStyle.FieldName == "" {
+		return sld.Rule{}, false
+	}
+
+	if settings.Rendering.ScaleVisibility == "1" {
+		context.scale = context.scale.restrict(parseScale(settings.Rendering.ScaleMin), parseScale(settings.Rendering.ScaleMax))
+
+		if context.scale.isEmpty() {
+			return sld.Rule{}, false
+		}
+	}
+
+	rule := context.rule(name, requirements)
+	rule.TextSymbolizer = []sld.Symbolizer{{XMLContent: []byte(settings.TextStyle.FieldName), UnitOfMeasure: sld.PixelUnit}}
+
+	columns := []string{settings.TextStyle.FieldName}
+
+	if settings.TextStyle.IsExpression == "1" {
+		columns = ExpressionColumns(settings.TextStyle.FieldName)
+	}
+
+	for _, column := range columns {
+		sld.AddRequiredColumn(requirements, column, []string{}, context.scale.scaleDenominator(), sld.LabelUsage)
+	}
+
+	for _, column := range dataDefinedColumns(settings.DataDefined) {
+		sld.AddRequiredColumn(requirements, column, []string{}, context.scale.scaleDenominator(), sld.StyleUsage)
+	}
+
+	return rule, true
+}
+
+//legacyLabelSettings returns the label settings of the "labeling/..." custom properties of QGIS 2
+func legacyLabelSettings(properties []Property) (LabelSettings, bool) {
+
+	values := make(map[string]string)
+
+	for _, property := range properties {
+		values[strings.TrimPrefix(property.Key, "labeling/")] = property.Value
+	}
+
+	if values["enabled"] != "true" {
+		return LabelSettings{}, false
+	}
+
+	settings := LabelSettings{TextStyle: TextStyle{FieldName: values["fieldName"], IsExpression: "0"}}
+
+	if values["isExpression"] == "true" {
+		settings.TextStyle.IsExpression = "1"
+	}
+
+	if values["scaleVisibility"] == "true" {
+		settings.Rendering = Rendering{ScaleVisibility: "1", ScaleMin: values["scaleMin"], ScaleMax: values["scaleMax"]}
+	}
+
+	return settings, true
+}
+
+//with returns the context of a child rule with an additional filter and a restricted scale range
+func (c ruleContext) with(filterText string, scale scaleRange) ruleContext {
+
+	child := ruleContext{c.filters, c.filterTexts, scale, c.layerIndex}
+
+	if filterText == "" {
+		return child
+	}
+
+	filter, err := ParseFilter(filterText)
+
+	if err != nil {
+		fmt.Println("Parsing Error: " + err.Error())
+		filter = sld.UnknownFilter{Name: "Filter"}
+	}
+
+	child.filters = append(append([]sld.FilterNode{}, c.filters...), filter)
+	child.filterTexts = append(append([]string{}, c.filterTexts...), filterText)
+
+	return child
+}
+
+//rule returns a SLD rule with the combined filters and the scale range of the context and adds the filter columns to the requirements
+func (c ruleContext) rule(name string, requirements *sld.TableRequirements) sld.Rule {
+
+	rule := sld.Rule{Name: name, MinScale: c.scale.min, MaxScale: c.scale.max, FeatureTypeStyle: c.layerIndex}
+
+	switch len(c.filters) {
+	case 0:
+		return rule
+	case 1:
+		rule.FilterTree = c.filters[0]
+	default:
+		rule.FilterTree = sld.LogicalFilter{Name: "And", Children: c.filters}
+	}
+
+	filterText := strings.Join(c.filterTexts, " AND ")
+	rule.Filter = sld.Filter{XMLContent: []byte(filterText)}
+
+	for _, column := range ExpressionColumns(filterText) {
+		sld.AddRequiredColumn(requirements, column, []string{}, c.scale.scaleDenominator(), sld.FilterUsage)
+	}
+
+	return rule
+}
+
+//rendererRule converts a rule of a rule-based renderer into a nested rule
+func rendererRule(rule RendererRule) nestedRule {
+
+	converted := nestedRule{rule.Label, strings.TrimSpace(rule.Filter), rule.Active, rule.ScaleMinDenom, rule.ScaleMaxDenom, rule.Symbol, nil, nil}
+
+	for _, child := range rule.Rules {
+		converted.children = append(converted.children, rendererRule(child))
+	}
+
+	return converted
+}
+
+//labelRule converts a rule of a rule-based labeling into a nested rule
+func labelRule(rule LabelRule) nestedRule {
+
+	converted := nestedRule{rule.Description, strings.TrimSpace(rule.Filter), rule.Active, rule.ScaleMinDenom, rule.ScaleMaxDenom, "", rule.Settings, nil}
+
+	for _, child := range rule.Rules {
+		converted.children = append(converted.children, labelRule(child))
+	}
+
+	return converted
+}
+
+//findSymbol returns the symbol with the name, nil if it does not exist
+func findSymbol(symbols []Symbol, name string) *Symbol {
+
+	for i := range symbols {
+		if symbols[i].Name == name {
+			return &symbols[i]
+		}
+	}
+
+	return nil
+}
+
+//dataDefinedColumns returns the fields and the columns of the expressions, which define properties of symbol layers or labels.
+//QGIS 3 stores them as options of a "properties" map, QGIS 2 as "_dd_" properties of a symbol layer
+func dataDefinedColumns(elements []element) []string {
+
+	columns := make([]string, 0)
+
+	for _, e := range elements {
+		if e.XMLName.Local == "Option" && e.attribute("name") == "properties" {
+			for _, property := range e.Children {
+				options := make(map[string]string)
+
+				for _, option := range property.Children {
+					options[option.attribute("name")] = option.attribute("value")
+				}
+
+				if options["active"] != "true" {
+					continue
+				}
+
+				//type 2 references a field, type 3 an expression
+				switch options["type"] {
+				case "2":
+					columns = functions.AppendUnique(columns, options["field"])
+				case "3":
+					for _, column := range ExpressionColumns(options["expression"]) {
+						columns = functions.AppendUnique(columns, column)
+					}
+				}
+			}
+
+			continue
+		}
+
+		properties := make(map[string]string)
+
+		for _, child := range e.Children {
+			if child.XMLName.Local == "prop" {
+				properties[child.attribute("k")] = child.attribute("v")
+			}
+		}
+
+		for key, value := range properties {
+			if !strings.HasSuffix(key, "_dd_active") || value != "1" {
+				continue
+			}
+
+			property := strings.TrimSuffix(key, "_dd_active")
+
+			if properties[property+"_dd_useexpr"] == "1" {
+				for _, column := range ExpressionColumns(properties[property+"_dd_expression"]) {
+					columns = functions.AppendUnique(columns, column)
+				}
+			} else {
+				columns = functions.AppendUnique(columns, properties[property+"_dd_field"])
+			}
+		}
+
+		for _, column := range dataDefinedColumns(e.Children) {
+			columns = functions.AppendUnique(columns, column)
+		}
+	}
+
+	return columns
+}
+
+//symbolUnit returns the metre unit, if a symbol layer is measured in map units, otherwise the pixel unit
+func symbolUnit(elements []element) string {
+
+	for _, e := range elements {
+		for _, attr := range e.Attrs {
+			if strings.HasSuffix(strings.ToLower(attr.Name.Local), "unit") && containsFold(mapUnits, attr.Value) {
+				return sld.MetreUnit
+			}
+		}
+
+		//QGIS 3 stores the units as options, QGIS 2 as properties
+		name, value := e.attribute("name"), e.attribute("value")
+
+		if e.XMLName.Local == "prop" {
+			name, value = e.attribute("k"), e.attribute("v")
+		}
+
+		if strings.HasSuffix(strings.ToLower(name), "unit") && containsFold(mapUnits, value) {
+			return sld.MetreUnit
+		}
+
+		if symbolUnit(e.Children) == sld.MetreUnit {
+			return sld.MetreUnit
+		}
+	}
+
+	return sld.PixelUnit
+}
+
+//layerScale returns the scale range of a layer with scale based visibility
+func layerScale(layer Layer) scaleRange {
+
+	if layer.HasScaleBasedVisibility != "1" {
+		return scaleRange{0, 0}
+	}
+
+	if layer.MinimumScale != "" || layer.MaximumScale != "" {
+		return scaleRange{0, 0}.restrict(parseScale(layer.MinimumScale), parseScale(layer.MaximumScale))
+	}
+
+	return scaleRange{0, 0}.restrict(parseScale(layer.MaxScale), parseScale(layer.MinScale))
+}
+
+//datasourceSubset returns the subset filter of a PostgreSQL datasource like `table="public"."osm_roads" (geometry) sql="highway" = 'primary'`
+//or an OGR datasource like `roads.gpkg|layername=roads|subset="highway" = 'primary'`
+func datasourceSubset(datasource string) string {
+
+	for _, separator := range []string{" sql=", "|subset="} {
+		if index := strings.Index(datasource, separator); index != -1 {
+			return strings.TrimSpace(datasource[index+len(separator):])
+		}
+	}
+
+	return ""
+}
+
+//layerTableNames returns the name of a layer and the tables of its datasource
+func layerTableNames(layer Layer) []string {
+
+	names := []string{layer.LayerName}
+
+	for _, match := range datasourceTables.FindAllStringSubmatch(layer.Datasource, -1) {
+		names = functions.AppendUnique(names, match[1])
+	}
+
+	return names
+}
+
+//quoteLiteral returns a category value as literal of an expression, numbers are not quoted
+func quoteLiteral(value string) string {
+
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
+
+//scaleRange contains the minimum and maximum scale denominator of a rule, a maximum of 0 is unlimited
+type scaleRange struct {
+	min int
+	max int
+}
+
+//restrict returns the intersection with a scale range, values of 0 do not restrict the range
+func (r scaleRange) restrict(min int, max int) scaleRange {
+
+	if min > r.min {
+		r.min = min
+	}
+
+	if max > 0 && (r.max == 0 || max < r.max) {
+		r.max = max
+	}
+
+	return r
+}
+
+//isEmpty checks if the range contains no scale
+func (r scaleRange) isEmpty() bool {
+	return r.max != 0 && r.min >= r.max
+}
+
+//covers checks if the range contains the other range
+func (r scaleRange) covers(other scaleRange) bool {
+	return r.min <= other.min && (r.max == 0 || (other.max != 0 && other.max <= r.max))
+}
+
+//scaleDenominator returns the range as scale denominator of the sld package
+func (r scaleRange) scaleDenominator() sld.ScaleDenominator {
+
+	if r.max == 0 {
+		return sld.ScaleDenominator{MinScaleDenominator: r.min, MaxScaleDenominator: -2}
+	}
+
+	return sld.ScaleDenominator{MinScaleDenominator: r.min, MaxScaleDenominator: r.max}
+}
+
+//parseScale converts a scale denominator, invalid and empty values are 0
+func parseScale(text string) int {
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+
+	if err != nil || value < 0 {
+		return 0
+	}
+
+	return int(math.Round(value))
+}
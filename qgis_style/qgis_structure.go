@@ -0,0 +1,153 @@
+package qgis
+
+import "encoding/xml"
+
+//########### QGIS style structures ###########//
+
+//Document is the root element of a QML style or a QGS project. A QML style is a single layer,
+//the layers of a project are listed in projectlayers
+type Document struct {
+	Version string  `xml:"version,attr"`
+	Layers  []Layer `xml:"projectlayers>maplayer"`
+	Layer
+}
+
+//Layer contains the renderer and the labeling of a vector layer
+//MinScale/MaxScale = scale denominators of QGIS 3, the layer is visible from MaxScale (zoomed in) to MinScale (zoomed out)
+//MinimumScale/MaximumScale = scale denominators of QGIS 2, the layer is visible from MinimumScale to MaximumScale
+type Layer struct {
+	HasScaleBasedVisibility string     `xml:"hasScaleBasedVisibilityFlag,attr"`
+	MinScale                string     `xml:"minScale,attr"`
+	MaxScale                string     `xml:"maxScale,attr"`
+	MinimumScale            string     `xml:"minimumScale,attr"`
+	MaximumScale            string     `xml:"maximumScale,attr"`
+	LabelsEnabled           string     `xml:"labelsEnabled,attr"`
+	LayerName               string     `xml:"layername"`
+	Datasource              string     `xml:"datasource"`
+	Renderer                *Renderer  `xml:"renderer-v2"`
+	Labeling                *Labeling  `xml:"labeling"`
+	CustomProperties        []Property `xml:"customproperties>property"`
+}
+
+//Renderer draws the features with symbols
+//Type = singleSymbol, categorizedSymbol, graduatedSymbol, RuleRenderer or a renderer with an embedded renderer (e.g. pointDisplacement)
+//Attr = the field or expression of categorized and graduated renderers
+type Renderer struct {
+	Type          string         `xml:"type,attr"`
+	Attr          string         `xml:"attr,attr"`
+	EnableOrderBy string         `xml:"enableorderby,attr"`
+	Rules         []RendererRule `xml:"rules>rule"`
+	Categories    []Category     `xml:"categories>category"`
+	Ranges        []Range        `xml:"ranges>range"`
+	Symbols       []Symbol       `xml:"symbols>symbol"`
+	OrderBy       []string       `xml:"orderby>orderByClause"`
+	Embedded      *Renderer      `xml:"renderer-v2"`
+}
+
+//RendererRule is a rule of a rule-based renderer, nested rules are only used if their parent matches
+//Filter = QGIS expression or "ELSE", which matches all features not matched by the sibling rules
+//Active = "0" if the rule is disabled
+type RendererRule struct {
+	Label         string         `xml:"label,attr"`
+	Filter        string         `xml:"filter,attr"`
+	Symbol        string         `xml:"symbol,attr"`
+	Active        string         `xml:"active,attr"`
+	ScaleMinDenom string         `xml:"scalemindenom,attr"`
+	ScaleMaxDenom string         `xml:"scalemaxdenom,attr"`
+	Rules         []RendererRule `xml:"rule"`
+}
+
+//Category of a categorized renderer, an empty value matches all other values
+//Values = the values of a category with multiple values
+type Category struct {
+	Label  string          `xml:"label,attr"`
+	Value  string          `xml:"value,attr"`
+	Symbol string          `xml:"symbol,attr"`
+	Render string          `xml:"render,attr"`
+	Values []CategoryValue `xml:"val"`
+}
+
+//CategoryValue is one of the values of a category
+type CategoryValue struct {
+	Value string `xml:"value,attr"`
+}
+
+//Range of a graduated renderer
+type Range struct {
+	Label  string `xml:"label,attr"`
+	Lower  string `xml:"lower,attr"`
+	Upper  string `xml:"upper,attr"`
+	Symbol string `xml:"symbol,attr"`
+	Render string `xml:"render,attr"`
+}
+
+//Symbol is a named marker, line or fill symbol
+type Symbol struct {
+	Name     string    `xml:"name,attr"`
+	Type     string    `xml:"type,attr"`
+	Elements []element `xml:",any"`
+}
+
+//Labeling contains the label settings of a simple or rule-based labeling
+type Labeling struct {
+	Type     string         `xml:"type,attr"`
+	Settings *LabelSettings `xml:"settings"`
+	Rules    []LabelRule    `xml:"rules>rule"`
+}
+
+//LabelRule is a rule of a rule-based labeling, nested rules are only used if their parent matches
+type LabelRule struct {
+	Description   string         `xml:"description,attr"`
+	Filter        string         `xml:"filter,attr"`
+	Active        string         `xml:"active,attr"`
+	ScaleMinDenom string         `xml:"scalemindenom,attr"`
+	ScaleMaxDenom string         `xml:"scalemaxdenom,attr"`
+	Settings      *LabelSettings `xml:"settings"`
+	Rules         []LabelRule    `xml:"rule"`
+}
+
+//LabelSettings describe the text and the visibility of a label
+type LabelSettings struct {
+	TextStyle   TextStyle `xml:"text-style"`
+	Rendering   Rendering `xml:"rendering"`
+	DataDefined []element `xml:"dd_properties"`
+}
+
+//TextStyle references the field or expression of the label text
+type TextStyle struct {
+	FieldName    string `xml:"fieldName,attr"`
+	IsExpression string `xml:"isExpression,attr"`
+}
+
+//Rendering contains the scale visibility of a label
+//ScaleMin/ScaleMax = minimum and maximum scale denominator, used if ScaleVisibility is "1"
+type Rendering struct {
+	DrawLabels      string `xml:"drawLabels,attr"`
+	ScaleVisibility string `xml:"scaleVisibility,attr"`
+	ScaleMin        string `xml:"scaleMin,attr"`
+	ScaleMax        string `xml:"scaleMax,attr"`
+}
+
+//Property is a custom layer property, QGIS 2 stores the label settings as "labeling/..." properties
+type Property struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+//element contains an arbitrary XML element, used for the symbol layers and the data defined properties
+type element struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []element  `xml:",any"`
+}
+
+//attribute returns the value of an attribute of the element
+func (e element) attribute(name string) string {
+	for _, attr := range e.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+
+	return ""
+}
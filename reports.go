@@ -0,0 +1,394 @@
+package main
+
+import (
+	"Imposm_Optimizer/mapping"
+	"Imposm_Optimizer/sld"
+	functions "Imposm_Optimizer/std_functions"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//printAnalysis prints the combined requirements of the style files of every table
+func printAnalysis(comparedTables map[string][]sld.ParsedSLD) {
+
+	fmt.Println("***************** Analysing tables *****************")
+
+	for _, tableName := range sortedTableNames(comparedTables) {
+		parsedSLDs := comparedTables[tableName]
+
+		if len(parsedSLDs) == 0 {
+			continue
+		}
+
+		fmt.Println(`-------- Table "` + tableName + `" --------`)
+
+		styleFiles := make([]string, 0)
+		columnUsages := make(map[string][]string)
+		columnNames := make([]string, 0)
+		mappingValues := make([]string, 0)
+		mappingPatterns := make([]string, 0)
+		symbolizerKinds := make([]string, 0)
+		scale := parsedSLDs[0].Scale
+		useAllMappingTypes := false
+
+		for _, parsedSLD := range parsedSLDs {
+			styleFiles = append(styleFiles, parsedSLD.FileName)
+			scale = combinedScale(scale, parsedSLD.Scale)
+			useAllMappingTypes = useAllMappingTypes || parsedSLD.UseAllMappingTypes
+
+			for _, column := range parsedSLD.Requirements.RequiredColumnList {
+				if _, known := columnUsages[column.PropertyName]; !known {
+					columnNames = append(columnNames, column.PropertyName)
+					columnUsages[column.PropertyName] = make([]string, 0)
+				}
+
+				columnUsages[column.PropertyName] = appendMissing(columnUsages[column.PropertyName], column.Usages...)
+			}
+
+			mappingValues = appendMissing(mappingValues, parsedSLD.Requirements.RequiredMappingValues...)
+			mappingPatterns = appendMissing(mappingPatterns, parsedSLD.Requirements.RequiredMappingPatterns...)
+			symbolizerKinds = appendMissing(symbolizerKinds, parsedSLD.Requirements.SymbolizerKinds...)
+		}
+
+		sort.Strings(columnNames)
+		sort.Strings(mappingValues)
+
+		fmt.Println("- style files:", styleFiles)
+		fmt.Println("- minimum/maximum scaling: " + scaleRangeText(scale))
+
+		for _, columnName := range columnNames {
+			fmt.Println(`- column "`+columnName+`" used for:`, strings.Join(columnUsages[columnName], ", "))
+		}
+
+		if useAllMappingTypes {
+			fmt.Println("- all mapping values are used, not all rules filter the mapping values")
+		} else {
+			fmt.Println("- required mapping values:", mappingValues)
+
+			if len(mappingPatterns) > 0 {
+				fmt.Println("- required mapping value patterns:", mappingPatterns)
+			}
+		}
+
+		fmt.Println("- symbolizer kinds:", symbolizerKinds)
+		fmt.Println("")
+	}
+}
+
+//explainTables explains for every table why its columns and mapping values are kept or removed by the optimization.
+//The tables of the generalized tables are listed in sourceTables like the result of GetSourceTableNames
+func explainTables(tableNames []string, originalMapping mapping.Mapping, optimizedMapping mapping.Mapping, comparedTables map[string][]sld.ParsedSLD, sourceTables map[string][]string, keepColumns []string) error {
+
+	if len(tableNames) == 0 {
+		for tableName := range originalMapping.Tables {
+			tableNames = append(tableNames, tableName)
+		}
+
+		for genTableName := range originalMapping.GeneralizedTables {
+			tableNames = append(tableNames, genTableName)
+		}
+
+		sort.Strings(tableNames)
+	}
+
+	for _, tableName := range tableNames {
+		_, isTable := originalMapping.Tables[tableName]
+		_, isGenTable := originalMapping.GeneralizedTables[tableName]
+
+		if !isTable && !isGenTable {
+			return errors.New(`the table "` + tableName + `" is not defined in the mapping file`)
+		}
+	}
+
+	fmt.Println("")
+	fmt.Println("***************** Explaining tables ****************")
+
+	for _, tableName := range tableNames {
+		if _, isTable := originalMapping.Tables[tableName]; isTable {
+			explainTable(tableName, originalMapping, optimizedMapping, comparedTables, sourceTables, keepColumns)
+		} else {
+			explainGeneralizedTable(tableName, originalMapping, optimizedMapping, comparedTables, sourceTables)
+		}
+
+		fmt.Println("")
+	}
+
+	return nil
+}
+
+//explainTable explains the changes of the columns, mapping values and geometry type of a table
+func explainTable(tableName string, originalMapping mapping.Mapping, optimizedMapping mapping.Mapping, comparedTables map[string][]sld.ParsedSLD, sourceTables map[string][]string, keepColumns []string) {
+
+	fmt.Println(`-------- Table "` + tableName + `" --------`)
+
+	table := originalMapping.Tables[tableName]
+	optimizedTable, isOptimized := optimizedMapping.Tables[tableName]
+
+	if !isOptimized {
+		fmt.Println("- the table is ignored by the configuration and removed from the mapping file")
+		return
+	}
+
+	//the generalized tables of a table need its columns as well
+	parsedSLDs := comparedTables[tableName]
+	genTableNames := make([]string, 0)
+
+	for _, genTableName := range sortedTableNames(sourceTables) {
+		if len(comparedTables[genTableName]) > 0 && functions.StringInSlice(tableName, sourceTables[genTableName][1:]) {
+			genTableNames = append(genTableNames, genTableName)
+			parsedSLDs = append(parsedSLDs, comparedTables[genTableName]...)
+		}
+	}
+
+	if len(parsedSLDs) == 0 {
+		fmt.Println("- no style files, the table is not changed")
+		return
+	}
+
+	fmt.Println("- style files:", styleFileNames(comparedTables[tableName]))
+
+	if len(genTableNames) > 0 {
+		fmt.Println("- generalized tables, whose styles use the columns as well:", genTableNames)
+	}
+
+	requiresColumns := false
+
+	for _, parsedSLD := range parsedSLDs {
+		requiresColumns = requiresColumns || len(parsedSLD.Requirements.RequiredColumnList) > 0
+	}
+
+	optimizedColumns := make([]string, 0)
+
+	for _, column := range optimizedTable.Columns {
+		optimizedColumns = append(optimizedColumns, column.Name)
+	}
+
+	originalColumns := make([]string, 0)
+
+	for _, column := range table.Columns {
+		originalColumns = append(originalColumns, column.Name)
+		usages := columnUsages(column.Name, parsedSLDs)
+
+		switch {
+		case !functions.StringInSlice(column.Name, optimizedColumns):
+			fmt.Println(`- column "` + column.Name + `" is removed, no style uses it`)
+		case len(usages) > 0:
+			fmt.Println(`- column "` + column.Name + `" is kept, it is used for ` + strings.Join(usages, ", "))
+		case functions.StringInSlice(column.Type, keepColumns):
+			fmt.Println(`- column "` + column.Name + `" is kept, its type "` + column.Type + `" is listed in keep_columns`)
+		case !requiresColumns:
+			fmt.Println(`- column "` + column.Name + `" is kept, the styles use no column, so all columns are kept`)
+		default:
+			fmt.Println(`- column "` + column.Name + `" is kept, it describes the members of the relation member table`)
+		}
+	}
+
+	optimizedColumns = optimizedColumns[:0]
+
+	for _, column := range optimizedTable.Columns {
+		optimizedColumns = append(optimizedColumns, column.Name)
+
+		if !functions.StringInSlice(column.Name, originalColumns) {
+			fmt.Println(`- column "` + column.Name + `" is added, it is used by the styles and was found by the tag research`)
+		}
+	}
+
+	for _, parsedSLD := range parsedSLDs {
+		for _, column := range parsedSLD.Requirements.RequiredColumnList {
+			if !functions.StringInSlice(column.PropertyName, optimizedColumns) {
+				optimizedColumns = append(optimizedColumns, column.PropertyName)
+				fmt.Println(`- column "` + column.PropertyName + `" is used by "` + parsedSLD.FileName + `", but is not defined in the mapping`)
+			}
+		}
+	}
+
+	useAllMappingTypes := false
+
+	for _, parsedSLD := range parsedSLDs {
+		useAllMappingTypes = useAllMappingTypes || parsedSLD.UseAllMappingTypes
+	}
+
+	explainMappingValues("mapping", table.Mapping, optimizedTable.Mapping)
+
+	for _, mappingName := range sortedMappingNames(table.Mappings) {
+		explainMappingValues(`mappings "`+mappingName+`"`, table.Mappings[mappingName].Mapping, optimizedTable.Mappings[mappingName].Mapping)
+	}
+
+	if useAllMappingTypes {
+		fmt.Println("- all mapping values are kept, not all rules filter the mapping values")
+	}
+
+	if table.Type != optimizedTable.Type {
+		fmt.Println(`- the geometry type "` + table.Type + `" is narrowed to "` + optimizedTable.Type + `", the styles only draw these geometries`)
+	}
+
+	if optimizedTable.Filter != nil && table.Filter == nil {
+		fmt.Println("- a filter is added, which rejects the rows not drawn by the styles")
+	}
+}
+
+//explainGeneralizedTable explains the changes of the tolerance and the filter of a generalized table
+func explainGeneralizedTable(genTableName string, originalMapping mapping.Mapping, optimizedMapping mapping.Mapping, comparedTables map[string][]sld.ParsedSLD, sourceTables map[string][]string) {
+
+	fmt.Println(`-------- Generalized table "` + genTableName + `" --------`)
+
+	genTable := originalMapping.GeneralizedTables[genTableName]
+	optimizedGenTable, isOptimized := optimizedMapping.GeneralizedTables[genTableName]
+
+	if !isOptimized {
+		fmt.Println("- the generalized table is ignored by the configuration and removed from the mapping file")
+		return
+	}
+
+	if tables := sourceTables[genTableName]; len(tables) > 0 {
+		fmt.Println(`- the columns and mapping values are defined by the table "` + tables[len(tables)-1] + `"`)
+	}
+
+	if len(comparedTables[genTableName]) == 0 {
+		fmt.Println("- no style files, the generalized table is not changed")
+		return
+	}
+
+	fmt.Println("- style files:", styleFileNames(comparedTables[genTableName]))
+
+	if genTable.Tolerance != optimizedGenTable.Tolerance {
+		fmt.Println("- the tolerance is changed from", genTable.Tolerance, "to", optimizedGenTable.Tolerance, "to fit the scales of the styles")
+	}
+
+	if genTable.SQLFilter != optimizedGenTable.SQLFilter {
+		fmt.Println(`- the SQL filter "` + optimizedGenTable.SQLFilter + `" rejects the rows not drawn by the styles`)
+	}
+}
+
+//explainMappingValues prints the kept and removed values of every key of a mapping
+func explainMappingValues(mappingName string, originalValues map[string][]string, optimizedValues map[string][]string) {
+
+	keys := make([]string, 0, len(originalValues))
+
+	for key := range originalValues {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		removedValues := make([]string, 0)
+
+		for _, value := range originalValues[key] {
+			if !functions.StringInSlice(value, optimizedValues[key]) {
+				removedValues = append(removedValues, value)
+			}
+		}
+
+		switch {
+		case len(removedValues) == 0:
+			fmt.Println(`- all values of the key "`+key+`" in the `+mappingName+` are kept:`, optimizedValues[key])
+		case len(optimizedValues[key]) == 0:
+			fmt.Println(`- the key "` + key + `" is removed from the ` + mappingName + `, no style uses its values`)
+		default:
+			fmt.Println(`- values of the key "`+key+`" in the `+mappingName+` kept:`, optimizedValues[key], "removed:", removedValues)
+		}
+	}
+}
+
+//columnUsages returns the usages of a column like "label in roads.sld"
+func columnUsages(columnName string, parsedSLDs []sld.ParsedSLD) []string {
+
+	usages := make([]string, 0)
+
+	for _, parsedSLD := range parsedSLDs {
+		if found, index := sld.ColumnInColumnlist(columnName, parsedSLD.Requirements.RequiredColumnList); found {
+			column := parsedSLD.Requirements.RequiredColumnList[index]
+			usages = appendMissing(usages, strings.Join(column.Usages, "/")+` in "`+parsedSLD.FileName+`"`)
+		}
+	}
+
+	return usages
+}
+
+func styleFileNames(parsedSLDs []sld.ParsedSLD) []string {
+
+	fileNames := make([]string, 0)
+
+	for _, parsedSLD := range parsedSLDs {
+		fileNames = append(fileNames, parsedSLD.FileName)
+	}
+
+	return fileNames
+}
+
+//combinedScale returns the scale range containing both scale ranges, -2 is an infinite maximum
+func combinedScale(first sld.ScaleDenominator, second sld.ScaleDenominator) sld.ScaleDenominator {
+
+	combined := first
+
+	if second.MinScaleDenominator < combined.MinScaleDenominator {
+		combined.MinScaleDenominator = second.MinScaleDenominator
+	}
+
+	if combined.MaxScaleDenominator != -2 && (second.MaxScaleDenominator == -2 || second.MaxScaleDenominator > combined.MaxScaleDenominator) {
+		combined.MaxScaleDenominator = second.MaxScaleDenominator
+	}
+
+	return combined
+}
+
+//scaleRangeText returns a scale range like "0/∞"
+func scaleRangeText(scale sld.ScaleDenominator) string {
+
+	maxScale := "∞"
+
+	if scale.MaxScaleDenominator != -2 {
+		maxScale = strconv.Itoa(scale.MaxScaleDenominator)
+	}
+
+	return strconv.Itoa(scale.MinScaleDenominator) + "/" + maxScale
+}
+
+func sortedTableNames(tables interface{}) []string {
+
+	tableNames := make([]string, 0)
+
+	switch tableMap := tables.(type) {
+	case map[string][]sld.ParsedSLD:
+		for tableName := range tableMap {
+			tableNames = append(tableNames, tableName)
+		}
+	case map[string][]string:
+		for tableName := range tableMap {
+			tableNames = append(tableNames, tableName)
+		}
+	}
+
+	sort.Strings(tableNames)
+
+	return tableNames
+}
+
+func sortedMappingNames(mappings map[string]mapping.TableMapping) []string {
+
+	names := make([]string, 0, len(mappings))
+
+	for name := range mappings {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+//appendMissing appends the values, which are not already in the list
+func appendMissing(list []string, values ...string) []string {
+
+	for _, value := range values {
+		if !functions.StringInSlice(value, list) {
+			list = append(list, value)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,183 @@
+package sld
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"unicode"
+)
+
+//usages of a required column
+const (
+	FilterUsage = "filter"
+	LabelUsage  = "label"
+	StyleUsage  = "style"
+	SortUsage   = "sort"
+)
+
+//cqlKeywords are the words of an embedded CQL expression, which are no property names
+var cqlKeywords = []string{"and", "or", "not", "like", "ilike", "is", "null", "true", "false", "in", "between", "include", "exclude", "exists"}
+
+//columnUsage returns the usage of a column, which is referenced by a node. The usage depends on the first
+//Filter or Label element found in the node and its ancestors, every other expression is used for styling
+func columnUsage(node *recursiveNode) string {
+
+	for parent := node; parent != nil; parent = parent.ParentNode {
+		switch {
+		case isFilterElement(parent, "Filter"):
+			return FilterUsage
+		case isStyleElement(parent, "Label"):
+			return LabelUsage
+		}
+	}
+
+	return StyleUsage
+}
+
+//SortByColumns returns the columns of a sortBy VendorOption, e.g. "z_order D, name A"
+func SortByColumns(option string) []string {
+
+	columns := make([]string, 0)
+
+	for _, entry := range strings.Split(option, ",") {
+		fields := strings.Fields(entry)
+
+		if len(fields) > 0 {
+			columns = appendUnique(columns, fields[0])
+		}
+	}
+
+	return columns
+}
+
+//ownText returns the character data of a node without the text of its child nodes
+func ownText(node *recursiveNode) string {
+
+	decoder := xml.NewDecoder(bytes.NewBuffer(node.Content))
+	text := ""
+	depth := 0
+
+	for {
+		token, err := decoder.Token()
+
+		if err != nil {
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 {
+				text += string(t)
+			}
+		}
+	}
+
+	return text
+}
+
+//EmbeddedExpressionColumns returns the property names of all embedded CQL expressions "${...}" of a text
+func EmbeddedExpressionColumns(text string) []string {
+
+	columns := make([]string, 0)
+
+	for {
+		start := strings.Index(text, "${")
+
+		if start == -1 {
+			break
+		}
+
+		end := strings.Index(text[start:], "}")
+
+		if end == -1 {
+			break
+		}
+
+		columns = appendUnique(columns, CQLPropertyNames(text[start+2:start+end])...)
+		text = text[start+end+1:]
+	}
+
+	return columns
+}
+
+//CQLPropertyNames returns the property names of a CQL expression. Function names, keywords,
+//numbers and string literals are skipped, quoted identifiers are property names
+func CQLPropertyNames(expression string) []string {
+
+	names := make([]string, 0)
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case r == '\'':
+			//string literal, a doubled quote is an escaped quote
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			i++
+
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+
+			names = appendUnique(names, string(runes[i+1:end]))
+			i = end + 1
+
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || strings.ContainsRune("_:", runes[end])) {
+				end++
+			}
+
+			word := string(runes[i:end])
+
+			next := end
+			for next < len(runes) && unicode.IsSpace(runes[next]) {
+				next++
+			}
+
+			isFunction := next < len(runes) && runes[next] == '('
+
+			if !isFunction && !containsFold(cqlKeywords, word) {
+				names = appendUnique(names, word)
+			}
+
+			i = end
+
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E') {
+				i++
+			}
+
+		default:
+			i++
+		}
+	}
+
+	return names
+}
+
+func containsFold(list []string, value string) bool {
+
+	for _, entry := range list {
+		if strings.EqualFold(entry, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,619 @@
+package sld
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+//cqlComparisonOperators maps the comparison operators of (E)CQL to the OGC comparison operators
+var cqlComparisonOperators = map[string]string{
+	"=":  "PropertyIsEqualTo",
+	"<>": "PropertyIsNotEqualTo",
+	"!=": "PropertyIsNotEqualTo",
+	"<":  "PropertyIsLessThan",
+	"<=": "PropertyIsLessThanOrEqualTo",
+	">":  "PropertyIsGreaterThan",
+	">=": "PropertyIsGreaterThanOrEqualTo"}
+
+//cqlArithmeticOperators maps the arithmetic operators of (E)CQL to the OGC arithmetic operators
+var cqlArithmeticOperators = map[string]string{
+	"+": "Add",
+	"-": "Sub",
+	"*": "Mul",
+	"/": "Div"}
+
+//cqlToken is a word, a quoted property name, a string, a number or an operator of a CQL text
+type cqlToken struct {
+	kind  rune
+	value string
+}
+
+//kinds of CQL tokens
+const (
+	cqlWord     = 'w'
+	cqlProperty = 'p'
+	cqlString   = 's'
+	cqlNumber   = 'n'
+	cqlOperator = 'o'
+)
+
+//cqlParser is a recursive descent parser for the tokens of a CQL text
+type cqlParser struct {
+	tokens   []cqlToken
+	position int
+}
+
+//ParseCQLFilter builds the filter tree of an (E)CQL filter like "type IN ('primary', 'secondary') AND name IS NOT NULL",
+//which is used by GeoServer CSS and YSLD styles. Comparisons of known functions are replaced by equivalent filters
+func ParseCQLFilter(text string) (FilterNode, error) {
+
+	tokens, err := tokenizeCQL(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parser := cqlParser{tokens, 0}
+	filter, err := parser.parseOr()
+
+	if err != nil {
+		return nil, errors.New(`invalid CQL filter "` + text + `": ` + err.Error())
+	}
+
+	if parser.position < len(tokens) {
+		return nil, errors.New(`invalid CQL filter "` + text + `": unexpected "` + tokens[parser.position].value + `"`)
+	}
+
+	return filter, nil
+}
+
+//ParseCQLExpression builds the expression of a CQL text like "lanes * 2" or "strToUpperCase(name)"
+func ParseCQLExpression(text string) (Expression, error) {
+
+	tokens, err := tokenizeCQL(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parser := cqlParser{tokens, 0}
+	expression, err := parser.parseAdditive()
+
+	if err == nil && parser.position < len(tokens) {
+		err = errors.New(`unexpected "` + tokens[parser.position].value + `"`)
+	}
+
+	if err != nil {
+		return nil, errors.New(`invalid CQL expression "` + text + `": ` + err.Error())
+	}
+
+	return expression, nil
+}
+
+func tokenizeCQL(text string) ([]cqlToken, error) {
+
+	tokens := make([]cqlToken, 0)
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '\'' || r == '"':
+			//a doubled quote is an escaped quote
+			value := make([]rune, 0)
+			end := i + 1
+
+			for ; end < len(runes); end++ {
+				if runes[end] == r {
+					if end+1 < len(runes) && runes[end+1] == r {
+						end++
+					} else {
+						break
+					}
+				}
+				value = append(value, runes[end])
+			}
+
+			if end == len(runes) {
+				return nil, errors.New(`unclosed quote in CQL text "` + text + `"`)
+			}
+
+			kind := cqlString
+
+			if r == '"' {
+				kind = cqlProperty
+			}
+
+			tokens = append(tokens, cqlToken{kind, string(value)})
+			i = end + 1
+
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.' || runes[end] == 'e' || runes[end] == 'E' ||
+				((runes[end] == '-' || runes[end] == '+') && (runes[end-1] == 'e' || runes[end-1] == 'E'))) {
+				end++
+			}
+
+			tokens = append(tokens, cqlToken{cqlNumber, string(runes[i:end])})
+			i = end
+
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || strings.ContainsRune("_:.", runes[end])) {
+				end++
+			}
+
+			tokens = append(tokens, cqlToken{cqlWord, string(runes[i:end])})
+			i = end
+
+		default:
+			operator := string(r)
+
+			if i+1 < len(runes) && (string(runes[i:i+2]) == "<>" || string(runes[i:i+2]) == "<=" || string(runes[i:i+2]) == ">=" || string(runes[i:i+2]) == "!=") {
+				operator = string(runes[i : i+2])
+			} else if !strings.ContainsRune("=<>+-*/(),", r) {
+				return nil, errors.New(`unknown character "` + operator + `" in CQL text "` + text + `"`)
+			}
+
+			tokens = append(tokens, cqlToken{cqlOperator, operator})
+			i += len([]rune(operator))
+		}
+	}
+
+	return tokens, nil
+}
+
+//isWord checks if the token at the offset of the current position is the keyword
+func (p *cqlParser) isWord(offset int, word string) bool {
+	position := p.position + offset
+	return position < len(p.tokens) && p.tokens[position].kind == cqlWord && strings.EqualFold(p.tokens[position].value, word)
+}
+
+//isOperator checks if the current token is the operator
+func (p *cqlParser) isOperator(operator string) bool {
+	return p.position < len(p.tokens) && p.tokens[p.position].kind == cqlOperator && p.tokens[p.position].value == operator
+}
+
+func (p *cqlParser) expectOperator(operator string) error {
+
+	if !p.isOperator(operator) {
+		return errors.New(`"` + operator + `" expected`)
+	}
+
+	p.position++
+	return nil
+}
+
+func (p *cqlParser) parseOr() (FilterNode, error) {
+	return p.parseLogical("OR", p.parseAnd)
+}
+
+func (p *cqlParser) parseAnd() (FilterNode, error) {
+	return p.parseLogical("AND", p.parseNot)
+}
+
+//parseLogical parses a list of operands, which are combined by the keyword
+func (p *cqlParser) parseLogical(keyword string, parseOperand func() (FilterNode, error)) (FilterNode, error) {
+
+	first, err := parseOperand()
+
+	if err != nil {
+		return nil, err
+	}
+
+	children := []FilterNode{first}
+
+	for p.isWord(0, keyword) {
+		p.position++
+
+		child, err := parseOperand()
+
+		if err != nil {
+			return nil, err
+		}
+
+		children = append(children, child)
+	}
+
+	if len(children) == 1 {
+		return first, nil
+	}
+
+	name := "And"
+
+	if keyword == "OR" {
+		name = "Or"
+	}
+
+	return LogicalFilter{name, children}, nil
+}
+
+func (p *cqlParser) parseNot() (FilterNode, error) {
+
+	if p.isWord(0, "NOT") {
+		p.position++
+
+		child, err := p.parseNot()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NotFilter{child}, nil
+	}
+
+	return p.parsePredicate()
+}
+
+//parsePredicate parses a filter in brackets, INCLUDE, EXCLUDE, a spatial operator or a predicate of an expression
+func (p *cqlParser) parsePredicate() (FilterNode, error) {
+
+	switch {
+	case p.isWord(0, "INCLUDE"):
+		p.position++
+		return UnknownFilter{"INCLUDE"}, nil
+
+	case p.isWord(0, "EXCLUDE"):
+		p.position++
+		return NotFilter{UnknownFilter{"INCLUDE"}}, nil
+
+	case p.isOperator("("):
+		//brackets can contain a filter or an expression like "(lanes + 1) > 2"
+		start := p.position
+		p.position++
+
+		if filter, err := p.parseOr(); err == nil && p.isOperator(")") {
+			p.position++
+
+			if !p.continuesExpression() {
+				return filter, nil
+			}
+		}
+
+		p.position = start
+	}
+
+	for _, operator := range spatialOperators {
+		if p.isWord(0, operator) && p.position+1 < len(p.tokens) && p.tokens[p.position+1].value == "(" {
+			return p.parseSpatial(operator)
+		}
+	}
+
+	//feature id filters like "IN ('roads.1', 'roads.2')", the function in(type, 'a', 'b') is compared with a value
+	if p.isWord(0, "IN") {
+		start := p.position
+		p.position++
+
+		if values, err := p.parseExpressionList(); err == nil && !p.continuesExpression() {
+			ids := make([]string, 0, len(values))
+
+			for _, value := range values {
+				if literal, ok := value.(LiteralExpression); ok {
+					ids = append(ids, literal.Value)
+				}
+			}
+
+			return FeatureIDFilter{ids}, nil
+		}
+
+		p.position = start
+	}
+
+	left, err := p.parseAdditive()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseCondition(left)
+}
+
+//continuesExpression checks if the current token continues an arithmetic expression or a comparison
+func (p *cqlParser) continuesExpression() bool {
+
+	if p.position >= len(p.tokens) {
+		return false
+	}
+
+	token := p.tokens[p.position]
+
+	if token.kind == cqlOperator {
+		_, isComparison := cqlComparisonOperators[token.value]
+		_, isArithmetic := cqlArithmeticOperators[token.value]
+		return isComparison || isArithmetic
+	}
+
+	offset := 0
+
+	//negated predicates like "NOT LIKE"
+	if p.isWord(0, "NOT") {
+		offset = 1
+	}
+
+	for _, keyword := range []string{"LIKE", "ILIKE", "IN", "BETWEEN", "IS", "EXISTS"} {
+		if p.isWord(offset, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//parseCondition parses the comparison, LIKE, IS NULL, IN or BETWEEN predicate of an expression
+func (p *cqlParser) parseCondition(left Expression) (FilterNode, error) {
+
+	if p.position < len(p.tokens) && p.tokens[p.position].kind == cqlOperator {
+		if name, isComparison := cqlComparisonOperators[p.tokens[p.position].value]; isComparison {
+			p.position++
+
+			right, err := p.parseAdditive()
+
+			if err != nil {
+				return nil, err
+			}
+
+			//comparisons of known functions like in(type, 'a', 'b') = true are replaced by an equivalent filter
+			return functionComparisonFilter(ComparisonFilter{name, left, right, true}), nil
+		}
+	}
+
+	negated := p.isWord(0, "NOT")
+
+	if negated {
+		p.position++
+	}
+
+	var filter FilterNode
+
+	switch {
+	case p.isWord(0, "LIKE") || p.isWord(0, "ILIKE"):
+		matchCase := p.isWord(0, "LIKE")
+		p.position++
+
+		if p.position >= len(p.tokens) || p.tokens[p.position].kind != cqlString {
+			return nil, errors.New("LIKE requires a pattern")
+		}
+
+		filter = LikeFilter{left, p.tokens[p.position].value, "%", "_", "\\", matchCase}
+		p.position++
+
+	case p.isWord(0, "IN"):
+		p.position++
+
+		values, err := p.parseExpressionList()
+
+		if err != nil {
+			return nil, err
+		}
+
+		children := make([]FilterNode, 0, len(values))
+
+		for _, value := range values {
+			children = append(children, ComparisonFilter{"PropertyIsEqualTo", left, value, true})
+		}
+
+		filter = LogicalFilter{"Or", children}
+
+	case p.isWord(0, "BETWEEN"):
+		p.position++
+
+		lower, err := p.parseAdditive()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.isWord(0, "AND") {
+			return nil, errors.New(`"AND" expected after the lower boundary of BETWEEN`)
+		}
+
+		p.position++
+
+		upper, err := p.parseAdditive()
+
+		if err != nil {
+			return nil, err
+		}
+
+		filter = BetweenFilter{left, lower, upper}
+
+	case p.isWord(0, "IS") && !negated:
+		p.position++
+
+		isNull := NullFilter{"PropertyIsNull", left}
+
+		if p.isWord(0, "NOT") && p.isWord(1, "NULL") {
+			p.position += 2
+			return NotFilter{isNull}, nil
+		}
+
+		if !p.isWord(0, "NULL") {
+			return nil, errors.New(`"NULL" expected after "IS"`)
+		}
+
+		p.position++
+		return isNull, nil
+
+	case p.isWord(0, "EXISTS") && !negated:
+		p.position++
+		return UnknownFilter{"EXISTS"}, nil
+
+	default:
+		return nil, errors.New("comparison expected")
+	}
+
+	if negated {
+		return NotFilter{filter}, nil
+	}
+
+	return filter, nil
+}
+
+//parseSpatial parses a spatial operator like "DWITHIN(the_geom, POINT(1 2), 10, meters)", only the property name is kept
+func (p *cqlParser) parseSpatial(operator string) (FilterNode, error) {
+
+	p.position += 2
+	filter := SpatialFilter{Name: operator}
+
+	if p.position < len(p.tokens) && (p.tokens[p.position].kind == cqlWord || p.tokens[p.position].kind == cqlProperty) {
+		filter.PropertyName = p.tokens[p.position].value
+	}
+
+	//the geometry literals are skipped
+	for depth := 1; depth > 0; p.position++ {
+		if p.position >= len(p.tokens) {
+			return nil, errors.New(`missing ")" after ` + operator)
+		}
+
+		switch {
+		case p.isOperator("("):
+			depth++
+		case p.isOperator(")"):
+			depth--
+		}
+	}
+
+	return filter, nil
+}
+
+func (p *cqlParser) parseAdditive() (Expression, error) {
+	return p.parseArithmetic([]string{"+", "-"}, p.parseMultiplicative)
+}
+
+func (p *cqlParser) parseMultiplicative() (Expression, error) {
+	return p.parseArithmetic([]string{"*", "/"}, p.parseUnary)
+}
+
+//parseArithmetic parses a left associative list of operands, which are combined by the operators
+func (p *cqlParser) parseArithmetic(operators []string, parseOperand func() (Expression, error)) (Expression, error) {
+
+	left, err := parseOperand()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator := ""
+
+		for _, candidate := range operators {
+			if p.isOperator(candidate) {
+				operator = candidate
+			}
+		}
+
+		if operator == "" {
+			return left, nil
+		}
+
+		p.position++
+
+		right, err := parseOperand()
+
+		if err != nil {
+			return nil, err
+		}
+
+		left = ArithmeticExpression{cqlArithmeticOperators[operator], left, right}
+	}
+}
+
+func (p *cqlParser) parseUnary() (Expression, error) {
+
+	if p.isOperator("-") && p.position+1 < len(p.tokens) && p.tokens[p.position+1].kind == cqlNumber {
+		p.position += 2
+		return LiteralExpression{"-" + p.tokens[p.position-1].value}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+//parsePrimary parses a literal, a property name, a function or an expression in brackets
+func (p *cqlParser) parsePrimary() (Expression, error) {
+
+	if p.position >= len(p.tokens) {
+		return nil, errors.New("unexpected end of text")
+	}
+
+	token := p.tokens[p.position]
+	p.position++
+
+	switch token.kind {
+	case cqlString, cqlNumber:
+		return LiteralExpression{token.value}, nil
+
+	case cqlProperty:
+		return PropertyNameExpression{token.value}, nil
+
+	case cqlWord:
+		if p.isOperator("(") {
+			arguments, err := p.parseExpressionList()
+
+			if err != nil {
+				return nil, err
+			}
+
+			return FunctionExpression{token.value, arguments}, nil
+		}
+
+		if strings.EqualFold(token.value, "true") || strings.EqualFold(token.value, "false") {
+			return LiteralExpression{strings.ToLower(token.value)}, nil
+		}
+
+		if containsFold(cqlKeywords, token.value) {
+			return nil, errors.New(`unexpected "` + token.value + `"`)
+		}
+
+		return PropertyNameExpression{token.value}, nil
+
+	case cqlOperator:
+		if token.value == "(" {
+			expression, err := p.parseAdditive()
+
+			if err != nil {
+				return nil, err
+			}
+
+			return expression, p.expectOperator(")")
+		}
+	}
+
+	return nil, errors.New(`unexpected "` + token.value + `"`)
+}
+
+//parseExpressionList parses a comma separated list of expressions in brackets
+func (p *cqlParser) parseExpressionList() ([]Expression, error) {
+
+	if err := p.expectOperator("("); err != nil {
+		return nil, err
+	}
+
+	expressions := make([]Expression, 0)
+
+	if p.isOperator(")") {
+		p.position++
+		return expressions, nil
+	}
+
+	for {
+		expression, err := p.parseAdditive()
+
+		if err != nil {
+			return nil, err
+		}
+
+		expressions = append(expressions, expression)
+
+		if p.isOperator(",") {
+			p.position++
+			continue
+		}
+
+		return expressions, p.expectOperator(")")
+	}
+}
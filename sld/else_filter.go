@@ -0,0 +1,48 @@
+package sld
+
+import "fmt"
+
+//resolveElseFilters replaces the ElseFilter of a rule by the negation of the filters of all other rules of the same FeatureTypeStyle,
+//which are visible in the whole scale range of the rule. Rules, which are partially visible, do not restrict the ElseFilter.
+//An ElseFilter rule is removed, if another rule without filter always matches its features
+func resolveElseFilters(ruleList []Rule) []Rule {
+
+	resolved := make([]Rule, 0, len(ruleList))
+
+	for _, rule := range ruleList {
+
+		if rule.ElseFilter == nil {
+			resolved = append(resolved, rule)
+			continue
+		}
+
+		siblingFilters := make([]FilterNode, 0)
+		neverMatches := false
+
+		for _, sibling := range ruleList {
+			if sibling.ElseFilter != nil || sibling.FeatureTypeStyle != rule.FeatureTypeStyle || !ruleScale(&sibling).Covers(ruleScale(&rule)) {
+				continue
+			}
+
+			if sibling.FilterTree == nil {
+				neverMatches = true
+				break
+			}
+
+			siblingFilters = append(siblingFilters, sibling.FilterTree)
+		}
+
+		if neverMatches {
+			fmt.Println(`- ElseFilter rule "` + rule.Name + `" ignored, another rule without filter matches all features`)
+			continue
+		}
+
+		if len(siblingFilters) > 0 {
+			rule.FilterTree = NotFilter{LogicalFilter{"Or", siblingFilters}}
+		}
+
+		resolved = append(resolved, rule)
+	}
+
+	return resolved
+}
@@ -0,0 +1,418 @@
+package sld
+
+import (
+	functions "Imposm_Optimizer/std_functions"
+	"regexp"
+)
+
+//NullValue represents a missing value (NULL) of a column in a ValueSet
+const NullValue = "__nil__"
+
+//ValueSet describes the values of a single column for which a filter can be true.
+//Patterns are anchored regular expressions (Go and POSIX compatible), a value is an element if it matches one of them
+//Negated = false: the set contains only the listed Values and the values matching the Patterns
+//Negated = true: the set contains all values except the listed Values and the values matching the Patterns
+//Exact = the filter is true if and only if the column value is in the set, otherwise the set is only an upper bound
+type ValueSet struct {
+	Values   []string
+	Patterns []string
+	Negated  bool
+	Exact    bool
+}
+
+//UnconstrainedValueSet returns a set which contains every value of a column
+func UnconstrainedValueSet() ValueSet {
+	return ValueSet{nil, nil, true, false}
+}
+
+//IsUnconstrained checks if the set contains every value of a column
+func (v ValueSet) IsUnconstrained() bool {
+	return v.Negated && len(v.Values) == 0 && len(v.Patterns) == 0
+}
+
+//Contains checks if a value is an element of the set
+func (v ValueSet) Contains(value string) bool {
+	return valueInList(value, v.Values, v.Patterns) != v.Negated
+}
+
+//Union of two value sets (logical or)
+func (v ValueSet) Union(other ValueSet) ValueSet {
+
+	exact := v.Exact && other.Exact
+
+	switch {
+	case !v.Negated && !other.Negated:
+		return ValueSet{appendUnique(v.Values, other.Values...), appendUnique(v.Patterns, other.Patterns...), false, exact}
+
+	case v.Negated && other.Negated:
+		//a value stays excluded, if it is excluded by both sets
+		values := make([]string, 0)
+
+		for _, value := range v.Values {
+			if valueInList(value, other.Values, other.Patterns) {
+				values = appendUnique(values, value)
+			}
+		}
+
+		for _, value := range other.Values {
+			if valueInList(value, v.Values, v.Patterns) {
+				values = appendUnique(values, value)
+			}
+		}
+
+		//patterns can only be compared textually, dropping a pattern enlarges the set
+		patterns := intersectValues(v.Patterns, other.Patterns)
+
+		if len(patterns) != len(v.Patterns) || len(patterns) != len(other.Patterns) {
+			exact = false
+		}
+
+		return ValueSet{values, patterns, true, exact}
+
+	case v.Negated:
+		return unionNegatedPositive(v, other, exact)
+	default:
+		return unionNegatedPositive(other, v, exact)
+	}
+}
+
+//Intersect two value sets (logical and)
+func (v ValueSet) Intersect(other ValueSet) ValueSet {
+
+	exact := v.Exact && other.Exact
+
+	switch {
+	case !v.Negated && !other.Negated:
+		values := make([]string, 0)
+
+		for _, value := range v.Values {
+			if valueInList(value, other.Values, other.Patterns) {
+				values = appendUnique(values, value)
+			}
+		}
+
+		for _, value := range other.Values {
+			if valueInList(value, v.Values, v.Patterns) {
+				values = appendUnique(values, value)
+			}
+		}
+
+		//the intersection of two patterns cannot be calculated, the first pattern is used as upper bound
+		var patterns []string
+
+		if len(v.Patterns) > 0 && len(other.Patterns) > 0 {
+			patterns = v.Patterns
+			exact = false
+		}
+
+		return ValueSet{values, patterns, false, exact}
+
+	case v.Negated && other.Negated:
+		return ValueSet{appendUnique(v.Values, other.Values...), appendUnique(v.Patterns, other.Patterns...), true, exact}
+	case v.Negated:
+		return intersectNegatedPositive(v, other, exact)
+	default:
+		return intersectNegatedPositive(other, v, exact)
+	}
+}
+
+//Complement of a value set (logical not). Only an exact set can be complemented,
+//otherwise the result is unconstrained
+func (v ValueSet) Complement() ValueSet {
+	if !v.Exact {
+		return UnconstrainedValueSet()
+	}
+
+	return ValueSet{v.Values, v.Patterns, !v.Negated, true}
+}
+
+func unionNegatedPositive(negated ValueSet, positive ValueSet, exact bool) ValueSet {
+
+	values := make([]string, 0)
+
+	for _, value := range negated.Values {
+		if !valueInList(value, positive.Values, positive.Patterns) {
+			values = append(values, value)
+		}
+	}
+
+	//an excluded pattern is only kept, if it does not overlap with the positive set
+	patterns := make([]string, 0)
+
+	if len(positive.Patterns) == 0 {
+		for _, pattern := range negated.Patterns {
+			if !patternMatchesAny(pattern, positive.Values) {
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+
+	if len(patterns) != len(negated.Patterns) {
+		exact = false
+	}
+
+	return ValueSet{values, patterns, true, exact}
+}
+
+func intersectNegatedPositive(negated ValueSet, positive ValueSet, exact bool) ValueSet {
+
+	values := make([]string, 0)
+
+	for _, value := range positive.Values {
+		if !valueInList(value, negated.Values, negated.Patterns) {
+			values = append(values, value)
+		}
+	}
+
+	//the excluded values cannot be removed from a pattern
+	if len(positive.Patterns) > 0 && !negated.IsUnconstrained() {
+		exact = false
+	}
+
+	return ValueSet{values, positive.Patterns, false, exact}
+}
+
+//ColumnValueSet calculates the set of values of a column for which the filter can be true.
+//A nil filter matches every value
+func ColumnValueSet(filter FilterNode, column string) ValueSet {
+
+	switch f := filter.(type) {
+	case ComparisonFilter:
+		literal, ok := comparedLiteral(f.Left, f.Right, column)
+
+		if !ok {
+			return UnconstrainedValueSet()
+		}
+
+		//a case insensitive comparison matches all spellings of the literal, like a case insensitive PropertyIsLike
+		valueSet := ValueSet{[]string{literal}, nil, false, true}
+
+		if !f.MatchCase {
+			valueSet = ValueSet{nil, []string{"(?i)^" + regexp.QuoteMeta(literal) + "$"}, false, true}
+		}
+
+		switch f.Name {
+		case "PropertyIsEqualTo":
+			return valueSet
+		case "PropertyIsNotEqualTo":
+			return valueSet.Complement()
+		}
+
+	case NullFilter:
+		if isProperty(f.Expression, column) {
+			return ValueSet{[]string{NullValue}, nil, false, true}
+		}
+
+	case LogicalFilter:
+		if len(f.Children) == 0 {
+			break
+		}
+
+		result := ColumnValueSet(f.Children[0], column)
+
+		for _, child := range f.Children[1:] {
+			if f.Name == "And" {
+				result = result.Intersect(ColumnValueSet(child, column))
+			} else {
+				result = result.Union(ColumnValueSet(child, column))
+			}
+		}
+
+		return result
+
+	case LikeFilter:
+		if !isProperty(f.Expression, column) {
+			break
+		}
+
+		//a pattern without wildcards is an equality comparison
+		if value, ok := f.literalPattern(); ok {
+			return ValueSet{[]string{value}, nil, false, true}
+		}
+
+		if _, err := regexp.Compile(f.Regexp()); err != nil {
+			break
+		}
+
+		return ValueSet{nil, []string{f.Regexp()}, false, true}
+
+	case RegexpFilter:
+		//the values of a pattern, which cannot be compiled, are unknown
+		if _, err := regexp.Compile(f.Pattern); err == nil && isProperty(f.Expression, column) {
+			return ValueSet{nil, []string{f.Pattern}, false, true}
+		}
+
+	case NotFilter:
+		return ColumnValueSet(f.Child, column).Complement()
+	}
+
+	return UnconstrainedValueSet()
+}
+
+//MergeColumnValueSets combines the column value sets of two styles (logical or).
+//A column which is missing in one of the maps is unconstrained and will therefore be dropped
+func MergeColumnValueSets(a map[string]ValueSet, b map[string]ValueSet) map[string]ValueSet {
+
+	merged := make(map[string]ValueSet)
+
+	for column, valueSet := range a {
+		if otherValueSet, found := b[column]; found {
+			union := valueSet.Union(otherValueSet)
+
+			if !union.IsUnconstrained() {
+				merged[column] = union
+			}
+		}
+	}
+
+	return merged
+}
+
+//FilterPropertyNames returns all property names used inside of a filter
+func FilterPropertyNames(filter FilterNode) []string {
+
+	names := make([]string, 0)
+
+	switch f := filter.(type) {
+	case ComparisonFilter:
+		names = appendUnique(names, f.Left.PropertyNames()...)
+		names = appendUnique(names, f.Right.PropertyNames()...)
+	case LikeFilter:
+		names = appendUnique(names, f.Expression.PropertyNames()...)
+	case RegexpFilter:
+		names = appendUnique(names, f.Expression.PropertyNames()...)
+	case BetweenFilter:
+		names = appendUnique(names, f.Expression.PropertyNames()...)
+	case NullFilter:
+		names = appendUnique(names, f.Expression.PropertyNames()...)
+	case SpatialFilter:
+		names = appendUnique(names, f.PropertyName)
+	case LogicalFilter:
+		for _, child := range f.Children {
+			names = appendUnique(names, FilterPropertyNames(child)...)
+		}
+	case NotFilter:
+		names = appendUnique(names, FilterPropertyNames(f.Child)...)
+	}
+
+	return names
+}
+
+//CollectComparedLiterals calls add for every literal that is compared with a property inside of the filter
+func CollectComparedLiterals(filter FilterNode, add func(propertyName string, literal string)) {
+
+	switch f := filter.(type) {
+	case ComparisonFilter:
+		if property, ok := f.Left.(PropertyNameExpression); ok {
+			if literal, ok := f.Right.(LiteralExpression); ok {
+				add(property.Name, literal.Value)
+			}
+		} else if property, ok := f.Right.(PropertyNameExpression); ok {
+			if literal, ok := f.Left.(LiteralExpression); ok {
+				add(property.Name, literal.Value)
+			}
+		}
+
+	case BetweenFilter:
+		if property, ok := f.Expression.(PropertyNameExpression); ok {
+			for _, boundary := range []Expression{f.LowerBoundary, f.UpperBoundary} {
+				if literal, ok := boundary.(LiteralExpression); ok {
+					add(property.Name, literal.Value)
+				}
+			}
+		}
+
+	case LogicalFilter:
+		for _, child := range f.Children {
+			CollectComparedLiterals(child, add)
+		}
+
+	case NotFilter:
+		CollectComparedLiterals(f.Child, add)
+	}
+}
+
+//comparedLiteral returns the literal of a comparison between the given column and a literal
+func comparedLiteral(left Expression, right Expression, column string) (string, bool) {
+
+	if isProperty(left, column) {
+		if literal, ok := right.(LiteralExpression); ok {
+			return literal.Value, true
+		}
+	} else if isProperty(right, column) {
+		if literal, ok := left.(LiteralExpression); ok {
+			return literal.Value, true
+		}
+	}
+
+	return "", false
+}
+
+func isProperty(expression Expression, column string) bool {
+	property, ok := expression.(PropertyNameExpression)
+	return ok && column != "" && property.Name == column
+}
+
+//valueInList checks if a value is listed or matches one of the patterns.
+//A pattern, which cannot be compiled, matches every value, because the values it excludes are unknown
+func valueInList(value string, values []string, patterns []string) bool {
+
+	if functions.StringInSlice(value, values) {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if matched, err := regexp.MatchString(pattern, value); err != nil || matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+//patternMatchesAny checks if at least one of the values matches the pattern
+func patternMatchesAny(pattern string, values []string) bool {
+	for _, value := range values {
+		if valueInList(value, nil, []string{pattern}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func appendUnique(list []string, values ...string) []string {
+	result := append(make([]string, 0, len(list)+len(values)), list...)
+
+	for _, value := range values {
+		if !functions.StringInSlice(value, result) {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
+
+func intersectValues(a []string, b []string) []string {
+	result := make([]string, 0)
+
+	for _, value := range a {
+		if functions.StringInSlice(value, b) {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
+
+func subtractValues(a []string, b []string) []string {
+	result := make([]string, 0)
+
+	for _, value := range a {
+		if !functions.StringInSlice(value, b) {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
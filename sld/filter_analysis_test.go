@@ -0,0 +1,119 @@
+package sld
+
+import (
+	"reflect"
+	"testing"
+)
+
+//sameValueSet compares two value sets, a nil list equals an empty list
+func sameValueSet(a ValueSet, b ValueSet) bool {
+
+	normalize := func(list []string) []string {
+		if len(list) == 0 {
+			return nil
+		}
+
+		return list
+	}
+
+	return reflect.DeepEqual(normalize(a.Values), normalize(b.Values)) && reflect.DeepEqual(normalize(a.Patterns), normalize(b.Patterns)) &&
+		a.Negated == b.Negated && a.Exact == b.Exact
+}
+
+func TestValueSetUnion(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		a        ValueSet
+		b        ValueSet
+		expected ValueSet
+	}{
+		{"values", ValueSet{[]string{"a", "b"}, nil, false, true}, ValueSet{[]string{"b", "c"}, nil, false, true}, ValueSet{[]string{"a", "b", "c"}, nil, false, true}},
+		{"values and patterns", ValueSet{[]string{"a"}, nil, false, true}, ValueSet{nil, []string{"^x.*$"}, false, true}, ValueSet{[]string{"a"}, []string{"^x.*$"}, false, true}},
+		{"inexact operand", ValueSet{[]string{"a"}, nil, false, true}, ValueSet{[]string{"b"}, nil, false, false}, ValueSet{[]string{"a", "b"}, nil, false, false}},
+		{"negated sets", ValueSet{[]string{"a", "b"}, nil, true, true}, ValueSet{[]string{"b", "c"}, nil, true, true}, ValueSet{[]string{"b"}, nil, true, true}},
+		{"negated and positive set", ValueSet{[]string{"a", "b"}, nil, true, true}, ValueSet{[]string{"a"}, nil, false, true}, ValueSet{[]string{"b"}, nil, true, true}},
+		{"positive and negated set", ValueSet{[]string{"a"}, nil, false, true}, ValueSet{[]string{"a", "b"}, nil, true, true}, ValueSet{[]string{"b"}, nil, true, true}},
+		{"overlapping excluded pattern", ValueSet{nil, []string{"^x.*$"}, true, true}, ValueSet{[]string{"xy"}, nil, false, true}, UnconstrainedValueSet()},
+		{"disjoint excluded pattern", ValueSet{nil, []string{"^x.*$"}, true, true}, ValueSet{[]string{"yz"}, nil, false, true}, ValueSet{nil, []string{"^x.*$"}, true, true}},
+		{"unconstrained set", UnconstrainedValueSet(), ValueSet{[]string{"a"}, nil, false, true}, UnconstrainedValueSet()}}
+
+	for _, test := range tests {
+		if result := test.a.Union(test.b); !sameValueSet(result, test.expected) {
+			t.Errorf("%s: %+v, expected %+v", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestValueSetIntersect(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		a        ValueSet
+		b        ValueSet
+		expected ValueSet
+	}{
+		{"values", ValueSet{[]string{"a", "b"}, nil, false, true}, ValueSet{[]string{"b", "c"}, nil, false, true}, ValueSet{[]string{"b"}, nil, false, true}},
+		{"values and pattern", ValueSet{[]string{"a", "b"}, nil, false, true}, ValueSet{nil, []string{"^a.*$"}, false, true}, ValueSet{[]string{"a"}, nil, false, true}},
+		{"patterns", ValueSet{nil, []string{"^a.*$"}, false, true}, ValueSet{nil, []string{"^b.*$"}, false, true}, ValueSet{nil, []string{"^a.*$"}, false, false}},
+		{"negated sets", ValueSet{[]string{"a"}, nil, true, true}, ValueSet{[]string{"b"}, nil, true, true}, ValueSet{[]string{"a", "b"}, nil, true, true}},
+		{"negated and positive set", ValueSet{[]string{"a"}, nil, true, true}, ValueSet{[]string{"a", "b"}, nil, false, true}, ValueSet{[]string{"b"}, nil, false, true}},
+		{"positive and negated set", ValueSet{[]string{"a", "b"}, nil, false, true}, ValueSet{[]string{"a"}, nil, true, true}, ValueSet{[]string{"b"}, nil, false, true}},
+		{"negated set and pattern", ValueSet{[]string{"a"}, nil, true, true}, ValueSet{nil, []string{"^x.*$"}, false, true}, ValueSet{nil, []string{"^x.*$"}, false, false}},
+		{"unconstrained set", UnconstrainedValueSet(), ValueSet{[]string{"a"}, nil, false, true}, ValueSet{[]string{"a"}, nil, false, false}}}
+
+	for _, test := range tests {
+		if result := test.a.Intersect(test.b); !sameValueSet(result, test.expected) {
+			t.Errorf("%s: %+v, expected %+v", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestValueSetComplement(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		set      ValueSet
+		expected ValueSet
+	}{
+		{"values", ValueSet{[]string{"a"}, nil, false, true}, ValueSet{[]string{"a"}, nil, true, true}},
+		{"negated values", ValueSet{[]string{"a"}, []string{"^x.*$"}, true, true}, ValueSet{[]string{"a"}, []string{"^x.*$"}, false, true}},
+		{"inexact set", ValueSet{[]string{"a"}, nil, false, false}, UnconstrainedValueSet()},
+		{"unconstrained set", UnconstrainedValueSet(), UnconstrainedValueSet()}}
+
+	for _, test := range tests {
+		if result := test.set.Complement(); !sameValueSet(result, test.expected) {
+			t.Errorf("%s: %+v, expected %+v", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestColumnValueSet(t *testing.T) {
+
+	property := PropertyNameExpression{"type"}
+	equalTo := func(value string) FilterNode {
+		return ComparisonFilter{"PropertyIsEqualTo", property, LiteralExpression{value}, true}
+	}
+
+	tests := []struct {
+		name     string
+		filter   FilterNode
+		expected ValueSet
+	}{
+		{"equal to", equalTo("a"), ValueSet{[]string{"a"}, nil, false, true}},
+		{"case insensitive", ComparisonFilter{"PropertyIsEqualTo", property, LiteralExpression{"a.b"}, false}, ValueSet{nil, []string{`(?i)^a\.b$`}, false, true}},
+		{"not equal to", ComparisonFilter{"PropertyIsNotEqualTo", property, LiteralExpression{"a"}, true}, ValueSet{[]string{"a"}, nil, true, true}},
+		{"or", LogicalFilter{"Or", []FilterNode{equalTo("a"), equalTo("b")}}, ValueSet{[]string{"a", "b"}, nil, false, true}},
+		{"not or", NotFilter{LogicalFilter{"Or", []FilterNode{equalTo("a"), equalTo("b")}}}, ValueSet{[]string{"a", "b"}, nil, true, true}},
+		{"other column", ComparisonFilter{"PropertyIsEqualTo", PropertyNameExpression{"name"}, LiteralExpression{"a"}, true}, UnconstrainedValueSet()},
+		{"like", LikeFilter{property, "res%", "%", "_", "\\", true}, ValueSet{nil, []string{"^res.*$"}, false, true}},
+		{"like without wildcard", LikeFilter{property, `100\%`, "%", "_", "\\", true}, ValueSet{[]string{"100%"}, nil, false, true}},
+		{"null", NullFilter{"PropertyIsNull", property}, ValueSet{[]string{NullValue}, nil, false, true}},
+		{"invalid regexp", RegexpFilter{property, "^(a$"}, UnconstrainedValueSet()}}
+
+	for _, test := range tests {
+		if result := ColumnValueSet(test.filter, "type"); !sameValueSet(result, test.expected) {
+			t.Errorf("%s: %+v, expected %+v", test.name, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package sld
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//filterFunction describes the filtering semantics of a GeoServer/GeoTools function
+//predicate = builds an equivalent filter for "function = result", false if the result cannot be expressed as filter
+//literals = calls add for every literal, which is compared with a property by the function
+type filterFunction struct {
+	predicate func(arguments []Expression, result string) (FilterNode, bool)
+	literals  func(arguments []Expression, add func(propertyName string, literal string))
+}
+
+//filterFunctions is the registry of all known functions, the function names are stored in lower case
+var filterFunctions map[string]filterFunction
+
+//the registry is built on initialization, because some functions evaluate nested functions
+func init() {
+	filterFunctions = map[string]filterFunction{
+		"in":                  {booleanPredicate(inPredicate), listLiterals},
+		"equalto":             {booleanPredicate(comparisonPredicate("PropertyIsEqualTo")), listLiterals},
+		"notequalto":          {booleanPredicate(comparisonPredicate("PropertyIsNotEqualTo")), listLiterals},
+		"lessthan":            {booleanPredicate(comparisonPredicate("PropertyIsLessThan")), listLiterals},
+		"lessequalthan":       {booleanPredicate(comparisonPredicate("PropertyIsLessThanOrEqualTo")), listLiterals},
+		"greaterthan":         {booleanPredicate(comparisonPredicate("PropertyIsGreaterThan")), listLiterals},
+		"greaterequalthan":    {booleanPredicate(comparisonPredicate("PropertyIsGreaterThanOrEqualTo")), listLiterals},
+		"between":             {booleanPredicate(betweenPredicate), listLiterals},
+		"isnull":              {booleanPredicate(nullPredicate), nil},
+		"not":                 {booleanPredicate(notPredicate), nil},
+		"and":                 {booleanPredicate(logicalPredicate("And")), nil},
+		"or":                  {booleanPredicate(logicalPredicate("Or")), nil},
+		"strmatches":          {booleanPredicate(regexpPredicate(func(pattern string) string { return pattern })), nil},
+		"islike":              {booleanPredicate(regexpPredicate(func(pattern string) string { return pattern })), nil},
+		"strstartswith":       {booleanPredicate(regexpPredicate(func(prefix string) string { return regexp.QuoteMeta(prefix) + ".*" })), nil},
+		"strendswith":         {booleanPredicate(regexpPredicate(func(suffix string) string { return ".*" + regexp.QuoteMeta(suffix) })), nil},
+		"strequalsignorecase": {booleanPredicate(regexpPredicate(func(value string) string { return "(?i)" + regexp.QuoteMeta(value) })), nil},
+		"recode":              {recodePredicate, recodeLiterals},
+		"categorize":          {categorizePredicate, categorizeLiterals},
+		"if_then_else":        {ifThenElsePredicate, nil}}
+
+	//GeoTools provides the in function with a fixed number of arguments as well (in2 - in10)
+	for i := 2; i <= 10; i++ {
+		filterFunctions["in"+strconv.Itoa(i)] = filterFunctions["in"]
+	}
+}
+
+//functionComparisonFilter replaces the comparison of a function with a literal by an equivalent filter,
+//e.g. in(type, 'a', 'b') = true becomes type = 'a' OR type = 'b'. The comparison is kept, if the function is unknown
+func functionComparisonFilter(comparison ComparisonFilter) FilterNode {
+
+	if comparison.Name != "PropertyIsEqualTo" && comparison.Name != "PropertyIsNotEqualTo" {
+		return comparison
+	}
+
+	function, ok := comparison.Left.(FunctionExpression)
+	literal, literalOk := comparison.Right.(LiteralExpression)
+
+	if !ok {
+		function, ok = comparison.Right.(FunctionExpression)
+		literal, literalOk = comparison.Left.(LiteralExpression)
+	}
+
+	if !ok || !literalOk {
+		return comparison
+	}
+
+	filter, ok := functionPredicate(function, literal.Value)
+
+	if !ok {
+		return comparison
+	}
+
+	if comparison.Name == "PropertyIsNotEqualTo" {
+		return NotFilter{filter}
+	}
+
+	return filter
+}
+
+//functionPredicate builds the filter for "function = result"
+func functionPredicate(function FunctionExpression, result string) (FilterNode, bool) {
+
+	registered, found := filterFunctions[strings.ToLower(function.Name)]
+
+	if !found || registered.predicate == nil {
+		return nil, false
+	}
+
+	return registered.predicate(function.Arguments, result)
+}
+
+//CollectFunctionLiterals calls add for every literal that is compared with a property by a known function,
+//e.g. the keys of a recode function. Nested functions are searched as well
+func CollectFunctionLiterals(expression Expression, add func(propertyName string, literal string)) {
+
+	switch e := expression.(type) {
+	case FunctionExpression:
+		if registered, found := filterFunctions[strings.ToLower(e.Name)]; found && registered.literals != nil {
+			registered.literals(e.Arguments, add)
+		}
+
+		for _, argument := range e.Arguments {
+			CollectFunctionLiterals(argument, add)
+		}
+
+	case ArithmeticExpression:
+		CollectFunctionLiterals(e.Left, add)
+		CollectFunctionLiterals(e.Right, add)
+	}
+}
+
+//booleanPredicate wraps the filter of a boolean function, the result "false" negates the filter
+func booleanPredicate(build func(arguments []Expression) (FilterNode, bool)) func([]Expression, string) (FilterNode, bool) {
+	return func(arguments []Expression, result string) (FilterNode, bool) {
+
+		filter, ok := build(arguments)
+
+		if !ok {
+			return nil, false
+		}
+
+		switch strings.ToLower(result) {
+		case "true":
+			return filter, true
+		case "false":
+			return NotFilter{filter}, true
+		}
+
+		return nil, false
+	}
+}
+
+//booleanExpressionFilter converts an argument of a boolean function into a filter
+func booleanExpressionFilter(expression Expression) (FilterNode, bool) {
+
+	function, ok := expression.(FunctionExpression)
+
+	if !ok {
+		return nil, false
+	}
+
+	return functionPredicate(function, "true")
+}
+
+//in(property, value1, value2, ...)
+func inPredicate(arguments []Expression) (FilterNode, bool) {
+
+	if len(arguments) < 2 {
+		return nil, false
+	}
+
+	filter := LogicalFilter{"Or", make([]FilterNode, 0)}
+
+	for _, value := range arguments[1:] {
+		filter.Children = append(filter.Children, ComparisonFilter{"PropertyIsEqualTo", arguments[0], value, true})
+	}
+
+	return filter, true
+}
+
+//equalTo(a, b), lessThan(a, b), ...
+func comparisonPredicate(operator string) func([]Expression) (FilterNode, bool) {
+	return func(arguments []Expression) (FilterNode, bool) {
+
+		if len(arguments) != 2 {
+			return nil, false
+		}
+
+		return ComparisonFilter{operator, arguments[0], arguments[1], true}, true
+	}
+}
+
+//between(value, lower, upper)
+func betweenPredicate(arguments []Expression) (FilterNode, bool) {
+
+	if len(arguments) != 3 {
+		return nil, false
+	}
+
+	return BetweenFilter{arguments[0], arguments[1], arguments[2]}, true
+}
+
+//isNull(value)
+func nullPredicate(arguments []Expression) (FilterNode, bool) {
+
+	if len(arguments) != 1 {
+		return nil, false
+	}
+
+	return NullFilter{"PropertyIsNull", arguments[0]}, true
+}
+
+//not(condition)
+func notPredicate(arguments []Expression) (FilterNode, bool) {
+
+	if len(arguments) != 1 {
+		return nil, false
+	}
+
+	filter, ok := booleanExpressionFilter(arguments[0])
+
+	if !ok {
+		return nil, false
+	}
+
+	return NotFilter{filter}, true
+}
+
+//and(condition, condition), or(condition, condition)
+func logicalPredicate(operator string) func([]Expression) (FilterNode, bool) {
+	return func(arguments []Expression) (FilterNode, bool) {
+
+		filter := LogicalFilter{operator, make([]FilterNode, 0)}
+
+		for _, argument := range arguments {
+			child, ok := booleanExpressionFilter(argument)
+
+			if !ok {
+				return nil, false
+			}
+
+			filter.Children = append(filter.Children, child)
+		}
+
+		return filter, len(filter.Children) > 0
+	}
+}
+
+//strMatches(value, regex) and similar string functions, which match the whole value
+func regexpPredicate(pattern func(string) string) func([]Expression) (FilterNode, bool) {
+	return func(arguments []Expression) (FilterNode, bool) {
+
+		if len(arguments) != 2 {
+			return nil, false
+		}
+
+		literal, ok := arguments[1].(LiteralExpression)
+
+		if !ok {
+			return nil, false
+		}
+
+		expression := pattern(literal.Value)
+		flags := ""
+
+		if strings.HasPrefix(expression, "(?i)") {
+			flags, expression = "(?i)", strings.TrimPrefix(expression, "(?i)")
+		}
+
+		expression = flags + "^(?:" + expression + ")$"
+
+		//patterns, which are not supported by Go, can not be evaluated
+		if _, err := regexp.Compile(expression); err != nil {
+			return nil, false
+		}
+
+		return RegexpFilter{arguments[0], expression}, true
+	}
+}
+
+//recode(value, key1, result1, key2, result2, ...), unknown keys return NULL
+func recodePredicate(arguments []Expression, result string) (FilterNode, bool) {
+
+	if len(arguments) < 3 {
+		return nil, false
+	}
+
+	filter := LogicalFilter{"Or", make([]FilterNode, 0)}
+
+	for i := 1; i+1 < len(arguments); i += 2 {
+		recoded, ok := arguments[i+1].(LiteralExpression)
+
+		if !ok {
+			return nil, false
+		}
+
+		if recoded.Value == result {
+			filter.Children = append(filter.Children, ComparisonFilter{"PropertyIsEqualTo", arguments[0], arguments[i], true})
+		}
+	}
+
+	//no key is recoded into the result, the function is only true for unknown keys
+	if len(filter.Children) == 0 {
+		return nil, false
+	}
+
+	return filter, true
+}
+
+func recodeLiterals(arguments []Expression, add func(propertyName string, literal string)) {
+
+	if len(arguments) < 3 {
+		return
+	}
+
+	property, ok := arguments[0].(PropertyNameExpression)
+
+	if !ok {
+		return
+	}
+
+	for i := 1; i+1 < len(arguments); i += 2 {
+		if key, ok := arguments[i].(LiteralExpression); ok {
+			add(property.Name, key.Value)
+		}
+	}
+}
+
+//categorize(value, result0, threshold1, result1, ..., thresholdN, resultN, ["succeeding"|"preceding"])
+func categorizePredicate(arguments []Expression, result string) (FilterNode, bool) {
+
+	arguments, thresholdOperators := categorizeArguments(arguments)
+
+	if len(arguments) < 4 {
+		return nil, false
+	}
+
+	filter := LogicalFilter{"Or", make([]FilterNode, 0)}
+
+	for i := 1; i < len(arguments); i += 2 {
+		categorized, ok := arguments[i].(LiteralExpression)
+
+		if !ok {
+			return nil, false
+		}
+
+		if categorized.Value != result {
+			continue
+		}
+
+		bounds := LogicalFilter{"And", make([]FilterNode, 0)}
+
+		//the category is between the preceding and the following threshold
+		if i > 1 {
+			bounds.Children = append(bounds.Children, ComparisonFilter{thresholdOperators[0], arguments[0], arguments[i-1], true})
+		}
+
+		if i+1 < len(arguments) {
+			bounds.Children = append(bounds.Children, ComparisonFilter{thresholdOperators[1], arguments[0], arguments[i+1], true})
+		}
+
+		filter.Children = append(filter.Children, bounds)
+	}
+
+	if len(filter.Children) == 0 {
+		return nil, false
+	}
+
+	return filter, true
+}
+
+func categorizeLiterals(arguments []Expression, add func(propertyName string, literal string)) {
+
+	arguments, _ = categorizeArguments(arguments)
+
+	property, ok := arguments[0].(PropertyNameExpression)
+
+	if !ok {
+		return
+	}
+
+	for i := 2; i < len(arguments); i += 2 {
+		if threshold, ok := arguments[i].(LiteralExpression); ok {
+			add(property.Name, threshold.Value)
+		}
+	}
+}
+
+//categorizeArguments removes the optional threshold belongs to argument and returns the operators
+//for the lower and the upper threshold of a category. By default a threshold belongs to the succeeding category
+func categorizeArguments(arguments []Expression) ([]Expression, [2]string) {
+
+	operators := [2]string{"PropertyIsGreaterThanOrEqualTo", "PropertyIsLessThan"}
+
+	if len(arguments) > 0 && len(arguments)%2 == 1 {
+		if option, ok := arguments[len(arguments)-1].(LiteralExpression); ok {
+			if strings.ToLower(option.Value) == "preceding" {
+				operators = [2]string{"PropertyIsGreaterThan", "PropertyIsLessThanOrEqualTo"}
+			}
+
+			arguments = arguments[:len(arguments)-1]
+		}
+	}
+
+	return arguments, operators
+}
+
+//if_then_else(condition, then, else)
+func ifThenElsePredicate(arguments []Expression, result string) (FilterNode, bool) {
+
+	if len(arguments) != 3 {
+		return nil, false
+	}
+
+	condition, ok := booleanExpressionFilter(arguments[0])
+
+	if !ok {
+		return nil, false
+	}
+
+	filter := LogicalFilter{"Or", make([]FilterNode, 0)}
+
+	for i, branchCondition := range []FilterNode{condition, NotFilter{condition}} {
+		branch := arguments[i+1]
+
+		if literal, ok := branch.(LiteralExpression); ok {
+			//a constant branch is either always or never equal to the result
+			if literal.Value == result {
+				filter.Children = append(filter.Children, branchCondition)
+			}
+			continue
+		}
+
+		branchFilter := ComparisonFilter{"PropertyIsEqualTo", branch, LiteralExpression{result}, true}
+		filter.Children = append(filter.Children, LogicalFilter{"And", []FilterNode{branchCondition, functionComparisonFilter(branchFilter)}})
+	}
+
+	if len(filter.Children) == 0 {
+		return nil, false
+	}
+
+	return filter, true
+}
+
+//listLiterals adds the literals of a function, which compares its first argument with the other arguments
+func listLiterals(arguments []Expression, add func(propertyName string, literal string)) {
+
+	if len(arguments) < 2 {
+		return
+	}
+
+	property, ok := arguments[0].(PropertyNameExpression)
+
+	if !ok {
+		return
+	}
+
+	for _, argument := range arguments[1:] {
+		if literal, ok := argument.(LiteralExpression); ok {
+			add(property.Name, literal.Value)
+		}
+	}
+}
+
+//collectSEFunctionLiterals calls add for the keys of a Symbology Encoding 1.1 Recode element,
+//the thresholds of a Categorize element and the data points of an Interpolate element
+func collectSEFunctionLiterals(node *recursiveNode, add func(propertyName string, literal string)) {
+
+	propertyName := ""
+
+	for i := range node.Nodes {
+		if isStyleElement(&node.Nodes[i], "LookupValue") {
+			for j := range node.Nodes[i].Nodes {
+				if isPropertyElement(&node.Nodes[i].Nodes[j]) {
+					propertyName = nodeText(&node.Nodes[i].Nodes[j])
+				}
+			}
+		}
+	}
+
+	if propertyName == "" {
+		return
+	}
+
+	for i := range node.Nodes {
+		child := &node.Nodes[i]
+
+		switch {
+		case isStyleElement(child, "Threshold"):
+			add(propertyName, nodeText(child))
+		case isStyleElement(child, "MapItem", "InterpolationPoint"):
+			for j := range child.Nodes {
+				if isStyleElement(&child.Nodes[j], "Data") {
+					add(propertyName, nodeText(&child.Nodes[j]))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package sld
+
+import (
+	functions "Imposm_Optimizer/std_functions"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+var comparisonOperators = []string{
+	"PropertyIsEqualTo",
+	"PropertyIsNotEqualTo",
+	"PropertyIsLessThan",
+	"PropertyIsLessThanOrEqualTo",
+	"PropertyIsGreaterThan",
+	"PropertyIsGreaterThanOrEqualTo"}
+
+var spatialOperators = []string{
+	"BBOX",
+	"Equals",
+	"Disjoint",
+	"Touches",
+	"Within",
+	"Overlaps",
+	"Crosses",
+	"Intersects",
+	"Contains",
+	"DWithin",
+	"Beyond"}
+
+var arithmeticOperators = []string{
+	"Add",
+	"Sub",
+	"Mul",
+	"Div"}
+
+//parseFilter builds the filter expression tree from a Filter element.
+//filterNode has to be the Filter element itself, not its content
+func parseFilter(filterNode *recursiveNode) (FilterNode, error) {
+
+	if len(filterNode.Nodes) == 0 {
+		return nil, errors.New("empty filter")
+	}
+
+	//multiple id elements are allowed directly inside of a filter
+	if isIDElement(filterNode.Nodes[0].XMLName.Local) {
+		return parseFeatureIDs(filterNode.Nodes), nil
+	}
+
+	return parseFilterNode(&filterNode.Nodes[0])
+}
+
+func parseFilterNode(node *recursiveNode) (FilterNode, error) {
+
+	name := node.XMLName.Local
+
+	//elements of other namespaces are no filter operators
+	if !inNamespace(node.XMLName, filterNamespaces) {
+		return UnknownFilter{name}, nil
+	}
+
+	switch {
+	case functions.StringInSlice(name, comparisonOperators):
+		expressions, err := parseExpressionList(node.Nodes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(expressions) != 2 {
+			return nil, errors.New(name + " requires exactly two expressions")
+		}
+
+		//comparisons of known functions like in(type, 'a', 'b') = true are replaced by an equivalent filter
+		return functionComparisonFilter(ComparisonFilter{name, expressions[0], expressions[1], matchCase(node)}), nil
+
+	case name == "PropertyIsLike":
+		likeFilter := LikeFilter{WildCard: "*", SingleChar: ".", EscapeChar: "!", MatchCase: matchCase(node)}
+
+		for _, attr := range node.Attrs {
+			switch attr.Name.Local {
+			case "wildCard":
+				likeFilter.WildCard = attr.Value
+			case "singleChar":
+				likeFilter.SingleChar = attr.Value
+			case "escape", "escapeChar":
+				likeFilter.EscapeChar = attr.Value
+			}
+		}
+
+		for i := range node.Nodes {
+			if isFilterElement(&node.Nodes[i], "Literal") && likeFilter.Expression != nil {
+				likeFilter.Pattern = nodeText(&node.Nodes[i])
+				continue
+			}
+
+			expression, err := parseExpression(&node.Nodes[i])
+
+			if err != nil {
+				return nil, err
+			}
+
+			likeFilter.Expression = expression
+		}
+
+		if likeFilter.Expression == nil {
+			return nil, errors.New("PropertyIsLike requires an expression")
+		}
+
+		return likeFilter, nil
+
+	case name == "PropertyIsBetween":
+		betweenFilter := BetweenFilter{}
+
+		for i := range node.Nodes {
+			child := &node.Nodes[i]
+
+			switch child.XMLName.Local {
+			case "LowerBoundary", "UpperBoundary":
+				if len(child.Nodes) != 1 {
+					return nil, errors.New(child.XMLName.Local + " requires exactly one expression")
+				}
+
+				boundary, err := parseExpression(&child.Nodes[0])
+
+				if err != nil {
+					return nil, err
+				}
+
+				if child.XMLName.Local == "LowerBoundary" {
+					betweenFilter.LowerBoundary = boundary
+				} else {
+					betweenFilter.UpperBoundary = boundary
+				}
+			default:
+				expression, err := parseExpression(child)
+
+				if err != nil {
+					return nil, err
+				}
+
+				betweenFilter.Expression = expression
+			}
+		}
+
+		if betweenFilter.Expression == nil || betweenFilter.LowerBoundary == nil || betweenFilter.UpperBoundary == nil {
+			return nil, errors.New("PropertyIsBetween requires an expression and two boundaries")
+		}
+
+		return betweenFilter, nil
+
+	case name == "PropertyIsNull" || name == "PropertyIsNil":
+		if len(node.Nodes) != 1 {
+			return nil, errors.New(name + " requires exactly one expression")
+		}
+
+		expression, err := parseExpression(&node.Nodes[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NullFilter{name, expression}, nil
+
+	case name == "And" || name == "Or":
+		logicalFilter := LogicalFilter{name, make([]FilterNode, 0)}
+
+		for i := range node.Nodes {
+			child, err := parseFilterNode(&node.Nodes[i])
+
+			if err != nil {
+				return nil, err
+			}
+
+			logicalFilter.Children = append(logicalFilter.Children, child)
+		}
+
+		return logicalFilter, nil
+
+	case name == "Not":
+		if len(node.Nodes) != 1 {
+			return nil, errors.New("Not requires exactly one filter")
+		}
+
+		child, err := parseFilterNode(&node.Nodes[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NotFilter{child}, nil
+
+	case functions.StringInSlice(name, spatialOperators):
+		spatialFilter := SpatialFilter{Name: name}
+
+		for i := range node.Nodes {
+			switch {
+			case isPropertyElement(&node.Nodes[i]):
+				spatialFilter.PropertyName = nodeText(&node.Nodes[i])
+			case isFilterElement(&node.Nodes[i], "Distance"):
+				spatialFilter.Distance = nodeText(&node.Nodes[i])
+
+				for _, attr := range node.Nodes[i].Attrs {
+					if attr.Name.Local == "units" || attr.Name.Local == "uom" {
+						spatialFilter.Units = attr.Value
+					}
+				}
+			}
+		}
+
+		return spatialFilter, nil
+
+	case isIDElement(name):
+		return parseFeatureIDs([]recursiveNode{*node}), nil
+	}
+
+	return UnknownFilter{name}, nil
+}
+
+func parseExpression(node *recursiveNode) (Expression, error) {
+
+	name := node.XMLName.Local
+
+	if !inNamespace(node.XMLName, filterNamespaces) {
+		return nil, errors.New(`unknown expression "` + node.XMLName.Space + ":" + name + `"`)
+	}
+
+	switch {
+	case name == "PropertyName" || name == "ValueReference":
+		return PropertyNameExpression{nodeText(node)}, nil
+
+	case name == "Literal":
+		return LiteralExpression{nodeText(node)}, nil
+
+	case name == "Function":
+		function := FunctionExpression{}
+
+		for _, attr := range node.Attrs {
+			if attr.Name.Local == "name" {
+				function.Name = attr.Value
+			}
+		}
+
+		arguments, err := parseExpressionList(node.Nodes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		function.Arguments = arguments
+
+		return function, nil
+
+	case functions.StringInSlice(name, arithmeticOperators):
+		operands, err := parseExpressionList(node.Nodes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(operands) != 2 {
+			return nil, errors.New(name + " requires exactly two expressions")
+		}
+
+		return ArithmeticExpression{name, operands[0], operands[1]}, nil
+	}
+
+	return nil, errors.New(`unknown expression "` + name + `"`)
+}
+
+func parseExpressionList(nodes []recursiveNode) ([]Expression, error) {
+
+	expressions := make([]Expression, 0)
+
+	for i := range nodes {
+		expression, err := parseExpression(&nodes[i])
+
+		if err != nil {
+			return nil, err
+		}
+
+		expressions = append(expressions, expression)
+	}
+
+	return expressions, nil
+}
+
+func parseFeatureIDs(nodes []recursiveNode) FeatureIDFilter {
+
+	idFilter := FeatureIDFilter{make([]string, 0)}
+
+	for _, node := range nodes {
+		for _, attr := range node.Attrs {
+			if attr.Name.Local == "fid" || attr.Name.Local == "id" || attr.Name.Local == "rid" {
+				idFilter.IDs = append(idFilter.IDs, attr.Value)
+			}
+		}
+	}
+
+	return idFilter
+}
+
+func isIDElement(name string) bool {
+	return name == "FeatureId" || name == "GmlObjectId" || name == "ResourceId"
+}
+
+//matchCase reads the matchCase attribute of a comparison operator, default is true
+func matchCase(node *recursiveNode) bool {
+	for _, attr := range node.Attrs {
+		if attr.Name.Local == "matchCase" {
+			return strings.ToLower(attr.Value) != "false"
+		}
+	}
+
+	return true
+}
+
+//nodeText returns the unescaped character data of a node, CDATA sections included
+func nodeText(node *recursiveNode) string {
+
+	decoder := xml.NewDecoder(bytes.NewBuffer(node.Content))
+	text := ""
+
+	for {
+		token, err := decoder.Token()
+
+		if err != nil {
+			break
+		}
+
+		if charData, ok := token.(xml.CharData); ok {
+			text += string(charData)
+		}
+	}
+
+	if node.XMLName.Local == "Literal" {
+		return text
+	}
+
+	return strings.TrimSpace(text)
+}
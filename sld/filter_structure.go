@@ -0,0 +1,165 @@
+package sld
+
+//########### Filter expression tree ###########//
+
+//FilterNode is a single node of the parsed OGC filter expression tree of a rule.
+//Implemented by ComparisonFilter, LikeFilter, RegexpFilter, BetweenFilter, NullFilter, LogicalFilter,
+//NotFilter, SpatialFilter, FeatureIDFilter and UnknownFilter
+type FilterNode interface {
+	//Operator returns the element name of the filter operator, e.g. "PropertyIsEqualTo" or "And"
+	Operator() string
+}
+
+//Expression is a single node of an OGC expression (PropertyName, Literal, Function or an arithmetic operator)
+type Expression interface {
+	//PropertyNames returns all property names which are used in the expression
+	PropertyNames() []string
+}
+
+//ComparisonFilter contains a binary comparison operator
+//Name = PropertyIsEqualTo, PropertyIsNotEqualTo, PropertyIsLessThan, PropertyIsLessThanOrEqualTo, PropertyIsGreaterThan or PropertyIsGreaterThanOrEqualTo
+type ComparisonFilter struct {
+	Name      string
+	Left      Expression
+	Right     Expression
+	MatchCase bool
+}
+
+//LikeFilter contains a PropertyIsLike operator with its pattern and the special characters of the pattern
+type LikeFilter struct {
+	Expression Expression
+	Pattern    string
+	WildCard   string
+	SingleChar string
+	EscapeChar string
+	MatchCase  bool
+}
+
+//RegexpFilter contains a function, which matches the whole value against a regular expression (strMatches, strStartsWith, ...).
+//Pattern is an anchored regular expression, which is supported by Go
+type RegexpFilter struct {
+	Expression Expression
+	Pattern    string
+}
+
+//BetweenFilter contains a PropertyIsBetween operator
+type BetweenFilter struct {
+	Expression    Expression
+	LowerBoundary Expression
+	UpperBoundary Expression
+}
+
+//NullFilter contains a PropertyIsNull or PropertyIsNil operator
+type NullFilter struct {
+	Name       string
+	Expression Expression
+}
+
+//LogicalFilter contains an And or Or operator with all of its operands
+type LogicalFilter struct {
+	Name     string
+	Children []FilterNode
+}
+
+//NotFilter contains a negated filter
+type NotFilter struct {
+	Child FilterNode
+}
+
+//SpatialFilter contains a spatial operator (BBOX, Intersects, DWithin, ...).
+//The geometry operand is not parsed, only the used geometry column is stored
+type SpatialFilter struct {
+	Name         string
+	PropertyName string
+	Distance     string
+	Units        string
+}
+
+//FeatureIDFilter contains a list of FeatureId/GmlObjectId/ResourceId identifiers
+type FeatureIDFilter struct {
+	IDs []string
+}
+
+//UnknownFilter is used for filter elements which are not known by the parser
+type UnknownFilter struct {
+	Name string
+}
+
+//PropertyNameExpression references a table column
+type PropertyNameExpression struct {
+	Name string
+}
+
+//LiteralExpression contains a constant value
+type LiteralExpression struct {
+	Value string
+}
+
+//FunctionExpression contains a function call with its arguments
+type FunctionExpression struct {
+	Name      string
+	Arguments []Expression
+}
+
+//ArithmeticExpression contains an Add, Sub, Mul or Div operator
+type ArithmeticExpression struct {
+	Name  string
+	Left  Expression
+	Right Expression
+}
+
+//Operator implementations
+
+//Operator of a ComparisonFilter
+func (f ComparisonFilter) Operator() string { return f.Name }
+
+//Operator of a LikeFilter
+func (f LikeFilter) Operator() string { return "PropertyIsLike" }
+
+//Operator of a RegexpFilter
+func (f RegexpFilter) Operator() string { return "strMatches" }
+
+//Operator of a BetweenFilter
+func (f BetweenFilter) Operator() string { return "PropertyIsBetween" }
+
+//Operator of a NullFilter
+func (f NullFilter) Operator() string { return f.Name }
+
+//Operator of a LogicalFilter
+func (f LogicalFilter) Operator() string { return f.Name }
+
+//Operator of a NotFilter
+func (f NotFilter) Operator() string { return "Not" }
+
+//Operator of a SpatialFilter
+func (f SpatialFilter) Operator() string { return f.Name }
+
+//Operator of a FeatureIDFilter
+func (f FeatureIDFilter) Operator() string { return "FeatureId" }
+
+//Operator of an UnknownFilter
+func (f UnknownFilter) Operator() string { return f.Name }
+
+//PropertyNames implementations
+
+//PropertyNames of a PropertyNameExpression
+func (e PropertyNameExpression) PropertyNames() []string { return []string{e.Name} }
+
+//PropertyNames of a LiteralExpression
+func (e LiteralExpression) PropertyNames() []string { return []string{} }
+
+//PropertyNames of a FunctionExpression
+func (e FunctionExpression) PropertyNames() []string {
+	names := make([]string, 0)
+
+	for _, argument := range e.Arguments {
+		names = append(names, argument.PropertyNames()...)
+	}
+
+	return names
+}
+
+//PropertyNames of an ArithmeticExpression
+func (e ArithmeticExpression) PropertyNames() []string {
+	return append(e.Left.PropertyNames(), e.Right.PropertyNames()...)
+}
@@ -0,0 +1,102 @@
+package sld
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+//MatchesTable checks if a layer or feature type name references a table. Workspace prefixes like "osm:" are ignored
+//and the table prefix of imposm is added, so the layer "osm:osm_roads" matches the table "roads" with the prefix "osm_"
+func MatchesTable(layerName string, tableName string, tablePrefix string) bool {
+
+	if index := strings.LastIndex(layerName, ":"); index != -1 {
+		layerName = layerName[index+1:]
+	}
+
+	return layerName != "" && (strings.EqualFold(layerName, tableName) || strings.EqualFold(layerName, tablePrefix+tableName))
+}
+
+//SetTableNames restricts the extracted requirements to the layers and feature types, which match one of the tables.
+//The table names are tried in order (e.g. a generalized table followed by its source tables), the first table
+//matching at least one layer is used. A file with a single layer is used completely, if no layer matches.
+//Otherwise no layer is used
+func (s *Parser) SetTableNames(tableNames []string, tablePrefix string) {
+	s.tableNames = tableNames
+	s.tablePrefix = tablePrefix
+}
+
+//LayerNames returns the names of all NamedLayer and UserLayer elements and the FeatureTypeNames of all styles
+func (s *Parser) LayerNames() ([]string, error) {
+
+	if s.successfullPasing == false {
+		err := s.loadSLDFile()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var node recursiveNode
+	err := xml.NewDecoder(bytes.NewBuffer(s.fileByteArray)).Decode(&node)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return documentLayerNames(&node), nil
+}
+
+//UsedLayers returns the layer and feature type names, which were used for the requirements of the table.
+//The list is empty, if the whole file was used
+func (s *Parser) UsedLayers() []string {
+	return s.usedLayers
+}
+
+//documentLayerNames returns the layer and feature type names of a SLD document
+func documentLayerNames(root *recursiveNode) []string {
+
+	names := make([]string, 0)
+
+	walk([]recursiveNode{*root}, nil, func(node recursiveNode) bool {
+		if isStyleElement(&node, "NamedLayer", "UserLayer") {
+			names = appendUnique(names, childText(&node, "Name"))
+		} else if isStyleElement(&node, "FeatureTypeStyle") {
+			names = appendUnique(names, childText(&node, "FeatureTypeName"))
+			return false
+		}
+
+		return true
+	})
+
+	return subtractValues(names, []string{""})
+}
+
+//selectedTableName returns the first table name, which matches a layer of the document.
+//An empty name is returned, if no table matches
+func (s *Parser) selectedTableName(root *recursiveNode) string {
+
+	layerNames := documentLayerNames(root)
+
+	for _, tableName := range s.tableNames {
+		for _, layerName := range layerNames {
+			if MatchesTable(layerName, tableName, s.tablePrefix) {
+				return tableName
+			}
+		}
+	}
+
+	return ""
+}
+
+//childText returns the text of the first child element of the SLD/SE namespaces with the given name
+func childText(node *recursiveNode, name string) string {
+
+	for i := range node.Nodes {
+		if isStyleElement(&node.Nodes[i], name) {
+			return nodeText(&node.Nodes[i])
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,78 @@
+package sld
+
+import (
+	"regexp"
+	"strings"
+)
+
+//Regexp converts the pattern of a PropertyIsLike operator into an anchored regular expression.
+//The wildCard, singleChar and escapeChar attributes of the operator are honoured.
+//The expression only uses syntax, which is supported by Go (imposm3) and PostgreSQL
+func (f LikeFilter) Regexp() string {
+
+	expression := "^"
+	pattern := f.Pattern
+
+	for len(pattern) > 0 {
+		switch {
+		case f.EscapeChar != "" && strings.HasPrefix(pattern, f.EscapeChar):
+			pattern = pattern[len(f.EscapeChar):]
+
+			//the escaped character is used literally
+			if len(pattern) > 0 {
+				char := []rune(pattern)[0]
+				expression += regexp.QuoteMeta(string(char))
+				pattern = pattern[len(string(char)):]
+			}
+
+		case f.WildCard != "" && strings.HasPrefix(pattern, f.WildCard):
+			expression += ".*"
+			pattern = pattern[len(f.WildCard):]
+
+		case f.SingleChar != "" && strings.HasPrefix(pattern, f.SingleChar):
+			expression += "."
+			pattern = pattern[len(f.SingleChar):]
+
+		default:
+			char := []rune(pattern)[0]
+			expression += regexp.QuoteMeta(string(char))
+			pattern = pattern[len(string(char)):]
+		}
+	}
+
+	expression += "$"
+
+	if !f.MatchCase {
+		expression = "(?i)" + expression
+	}
+
+	return expression
+}
+
+//literalPattern returns the unescaped pattern, if it does not contain any wildcard.
+//Case insensitive patterns are never literal
+func (f LikeFilter) literalPattern() (string, bool) {
+
+	if !f.MatchCase {
+		return "", false
+	}
+
+	value := ""
+	escaped := false
+
+	for _, char := range f.Pattern {
+		switch {
+		case escaped:
+			value += string(char)
+			escaped = false
+		case string(char) == f.EscapeChar:
+			escaped = true
+		case string(char) == f.WildCard || string(char) == f.SingleChar:
+			return "", false
+		default:
+			value += string(char)
+		}
+	}
+
+	return value, true
+}
@@ -0,0 +1,52 @@
+package sld
+
+import "testing"
+
+func TestLikeFilterRegexp(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		filter   LikeFilter
+		expected string
+	}{
+		{"wildcard", LikeFilter{nil, "res%", "%", "_", "\\", true}, "^res.*$"},
+		{"single character", LikeFilter{nil, "a_c", "%", "_", "\\", true}, "^a.c$"},
+		{"escaped wildcard", LikeFilter{nil, `100\%`, "%", "_", "\\", true}, "^100%$"},
+		{"escaped single character", LikeFilter{nil, `a\_b`, "%", "_", "\\", true}, "^a_b$"},
+		{"other escape character", LikeFilter{nil, "50!%!!", "%", "_", "!", true}, "^50%!$"},
+		{"escaped regexp character", LikeFilter{nil, `a\*?`, "*", "?", "\\", true}, `^a\*.$`},
+		{"regexp character as wildcard", LikeFilter{nil, "a.b", ".", "_", "\\", true}, "^a.*b$"},
+		{"trailing escape character", LikeFilter{nil, `ab\`, "%", "_", "\\", true}, "^ab$"},
+		{"no escape character", LikeFilter{nil, `a\%`, "%", "_", "", true}, `^a\\.*$`},
+		{"regexp characters", LikeFilter{nil, "(a)+", "%", "_", "\\", true}, `^\(a\)\+$`},
+		{"unicode", LikeFilter{nil, "stra_e", "%", "_", "\\", true}, "^stra.e$"},
+		{"case insensitive", LikeFilter{nil, "Res%", "%", "_", "\\", false}, "(?i)^Res.*$"}}
+
+	for _, test := range tests {
+		if result := test.filter.Regexp(); result != test.expected {
+			t.Errorf("%s: %q, expected %q", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestLikeFilterLiteralPattern(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		filter   LikeFilter
+		expected string
+		literal  bool
+	}{
+		{"plain text", LikeFilter{nil, "service", "%", "_", "\\", true}, "service", true},
+		{"escaped wildcard", LikeFilter{nil, `100\%`, "%", "_", "\\", true}, "100%", true},
+		{"escaped escape character", LikeFilter{nil, "a!!b", "%", "_", "!", true}, "a!b", true},
+		{"wildcard", LikeFilter{nil, "a%", "%", "_", "\\", true}, "", false},
+		{"single character", LikeFilter{nil, "a_", "%", "_", "\\", true}, "", false},
+		{"case insensitive", LikeFilter{nil, "service", "%", "_", "\\", false}, "", false}}
+
+	for _, test := range tests {
+		if result, literal := test.filter.literalPattern(); result != test.expected || literal != test.literal {
+			t.Errorf("%s: %q %v, expected %q %v", test.name, result, literal, test.expected, test.literal)
+		}
+	}
+}
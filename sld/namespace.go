@@ -0,0 +1,104 @@
+package sld
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+//namespaces of SLD 1.0.0, SLD 1.1.0/Symbology Encoding 1.1, Filter 1.0/1.1 and Filter 2.0
+const (
+	sldNamespace = "http://www.opengis.net/sld"
+	seNamespace  = "http://www.opengis.net/se"
+	ogcNamespace = "http://www.opengis.net/ogc"
+	fesNamespace = "http://www.opengis.net/fes/2.0"
+)
+
+//supported SLD versions
+const (
+	Version100 = "1.0.0"
+	Version110 = "1.1.0"
+)
+
+//units of measure of Symbology Encoding 1.1, symbolizers without uom attribute use pixels
+const (
+	PixelUnit = "pixel"
+	MetreUnit = "metre"
+	FootUnit  = "foot"
+)
+
+//styleNamespaces are the namespaces of layer, style and symbolizer elements.
+//The empty namespace is accepted for documents without namespace declarations
+var styleNamespaces = []string{"", sldNamespace, seNamespace}
+
+//filterNamespaces are the namespaces of filter and expression elements
+var filterNamespaces = []string{"", ogcNamespace, fesNamespace}
+
+//isStyleElement checks if the node is one of the named SLD/SE elements
+func isStyleElement(node *recursiveNode, names ...string) bool {
+	return inNamespace(node.XMLName, styleNamespaces) && nameInList(node.XMLName.Local, names)
+}
+
+//isFilterElement checks if the node is one of the named filter elements
+func isFilterElement(node *recursiveNode, names ...string) bool {
+	return inNamespace(node.XMLName, filterNamespaces) && nameInList(node.XMLName.Local, names)
+}
+
+//isPropertyElement checks if the node references a column, Filter 2.0 uses ValueReference instead of PropertyName
+func isPropertyElement(node *recursiveNode) bool {
+	return isFilterElement(node, "PropertyName", "ValueReference")
+}
+
+func inNamespace(name xml.Name, namespaces []string) bool {
+	for _, namespace := range namespaces {
+		if name.Space == namespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nameInList(name string, names []string) bool {
+	for _, listName := range names {
+		if name == listName {
+			return true
+		}
+	}
+
+	return false
+}
+
+//documentVersion returns the version of a SLD document. Documents without version attribute
+//are SLD 1.1.0, if they contain Symbology Encoding elements
+func documentVersion(root *recursiveNode) string {
+
+	for _, attr := range root.Attrs {
+		if attr.Name.Local == "version" && attr.Value != "" {
+			return attr.Value
+		}
+	}
+
+	version := Version100
+
+	walk([]recursiveNode{*root}, nil, func(node recursiveNode) bool {
+		if node.XMLName.Space == seNamespace {
+			version = Version110
+			return false
+		}
+		return version == Version100
+	})
+
+	return version
+}
+
+//unitOfMeasure returns the unit of a uom attribute, e.g. "http://www.opengeospatial.org/se/units/metre"
+func unitOfMeasure(node *recursiveNode) string {
+
+	for _, attr := range node.Attrs {
+		if attr.Name.Local == "uom" && attr.Value != "" {
+			return attr.Value[strings.LastIndex(attr.Value, "/")+1:]
+		}
+	}
+
+	return PixelUnit
+}
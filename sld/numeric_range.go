@@ -0,0 +1,280 @@
+package sld
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+//NumericInterval is a range of numbers, a missing bound is represented by an infinite value
+type NumericInterval struct {
+	Min          float64
+	Max          float64
+	MinInclusive bool
+	MaxInclusive bool
+}
+
+//NumericRangeSet describes the numeric values of a single column for which a filter can be true.
+//Null = the filter can be true if the column is NULL
+//Exact = the filter is true if and only if the column value is in the set, otherwise the set is only an upper bound
+type NumericRangeSet struct {
+	Intervals []NumericInterval
+	Null      bool
+	Exact     bool
+}
+
+//NumericRangeRequirement contains the numeric values of the columns which are used by a single rule in its scale range.
+//Columns which are missing in Ranges are unconstrained
+type NumericRangeRequirement struct {
+	Scale  ScaleDenominator
+	Ranges map[string]NumericRangeSet
+}
+
+//UnconstrainedNumericRangeSet returns a set which contains every number and NULL
+func UnconstrainedNumericRangeSet() NumericRangeSet {
+	return NumericRangeSet{[]NumericInterval{{math.Inf(-1), math.Inf(1), false, false}}, true, false}
+}
+
+//IsUnconstrained checks if the set contains every number and NULL
+func (r NumericRangeSet) IsUnconstrained() bool {
+	return r.Null && len(r.Intervals) == 1 && math.IsInf(r.Intervals[0].Min, -1) && math.IsInf(r.Intervals[0].Max, 1)
+}
+
+//Union of two range sets (logical or)
+func (r NumericRangeSet) Union(other NumericRangeSet) NumericRangeSet {
+	intervals := append(append([]NumericInterval{}, r.Intervals...), other.Intervals...)
+	return NumericRangeSet{normalizeIntervals(intervals), r.Null || other.Null, r.Exact && other.Exact}
+}
+
+//Intersect two range sets (logical and)
+func (r NumericRangeSet) Intersect(other NumericRangeSet) NumericRangeSet {
+
+	intervals := make([]NumericInterval, 0)
+
+	for _, a := range r.Intervals {
+		for _, b := range other.Intervals {
+			interval := a
+
+			if b.Min > interval.Min || (b.Min == interval.Min && !b.MinInclusive) {
+				interval.Min, interval.MinInclusive = b.Min, b.MinInclusive
+			}
+
+			if b.Max < interval.Max || (b.Max == interval.Max && !b.MaxInclusive) {
+				interval.Max, interval.MaxInclusive = b.Max, b.MaxInclusive
+			}
+
+			if !interval.isEmpty() {
+				intervals = append(intervals, interval)
+			}
+		}
+	}
+
+	return NumericRangeSet{normalizeIntervals(intervals), r.Null && other.Null, r.Exact && other.Exact}
+}
+
+//Complement of a range set (logical not). Only an exact set can be complemented,
+//otherwise the result is unconstrained
+func (r NumericRangeSet) Complement() NumericRangeSet {
+
+	if !r.Exact {
+		return UnconstrainedNumericRangeSet()
+	}
+
+	intervals := make([]NumericInterval, 0)
+	lower, lowerInclusive := math.Inf(-1), false
+
+	for _, interval := range r.Intervals {
+		gap := NumericInterval{lower, interval.Min, lowerInclusive, !interval.MinInclusive}
+
+		if !gap.isEmpty() {
+			intervals = append(intervals, gap)
+		}
+
+		lower, lowerInclusive = interval.Max, !interval.MaxInclusive
+	}
+
+	gap := NumericInterval{lower, math.Inf(1), lowerInclusive, false}
+
+	if !gap.isEmpty() {
+		intervals = append(intervals, gap)
+	}
+
+	return NumericRangeSet{intervals, !r.Null, true}
+}
+
+//ColumnNumericRangeSet calculates the set of numbers of a column for which the filter can be true.
+//A nil filter matches every value
+func ColumnNumericRangeSet(filter FilterNode, column string) NumericRangeSet {
+
+	switch f := filter.(type) {
+	case ComparisonFilter:
+		operator := f.Name
+		literal, ok := comparedLiteral(f.Left, f.Right, column)
+
+		if !ok {
+			break
+		}
+
+		number, err := strconv.ParseFloat(literal, 64)
+
+		if err != nil {
+			break
+		}
+
+		//the literal is on the left side, the operator has to be mirrored
+		if isProperty(f.Right, column) {
+			operator = mirroredOperators[operator]
+		}
+
+		switch operator {
+		case "PropertyIsEqualTo":
+			return NumericRangeSet{[]NumericInterval{{number, number, true, true}}, false, true}
+		case "PropertyIsNotEqualTo":
+			return NumericRangeSet{[]NumericInterval{{math.Inf(-1), number, false, false}, {number, math.Inf(1), false, false}}, false, true}
+		case "PropertyIsLessThan":
+			return NumericRangeSet{[]NumericInterval{{math.Inf(-1), number, false, false}}, false, true}
+		case "PropertyIsLessThanOrEqualTo":
+			return NumericRangeSet{[]NumericInterval{{math.Inf(-1), number, false, true}}, false, true}
+		case "PropertyIsGreaterThan":
+			return NumericRangeSet{[]NumericInterval{{number, math.Inf(1), false, false}}, false, true}
+		case "PropertyIsGreaterThanOrEqualTo":
+			return NumericRangeSet{[]NumericInterval{{number, math.Inf(1), true, false}}, false, true}
+		}
+
+	case BetweenFilter:
+		if !isProperty(f.Expression, column) {
+			break
+		}
+
+		lower, lowerOk := numericLiteral(f.LowerBoundary)
+		upper, upperOk := numericLiteral(f.UpperBoundary)
+
+		if lowerOk && upperOk {
+			return NumericRangeSet{normalizeIntervals([]NumericInterval{{lower, upper, true, true}}), false, true}
+		}
+
+	case NullFilter:
+		if isProperty(f.Expression, column) {
+			return NumericRangeSet{[]NumericInterval{}, true, true}
+		}
+
+	case LogicalFilter:
+		if len(f.Children) == 0 {
+			break
+		}
+
+		result := ColumnNumericRangeSet(f.Children[0], column)
+
+		for _, child := range f.Children[1:] {
+			if f.Name == "And" {
+				result = result.Intersect(ColumnNumericRangeSet(child, column))
+			} else {
+				result = result.Union(ColumnNumericRangeSet(child, column))
+			}
+		}
+
+		return result
+
+	case NotFilter:
+		return ColumnNumericRangeSet(f.Child, column).Complement()
+	}
+
+	return UnconstrainedNumericRangeSet()
+}
+
+//CombineNumericRanges calculates the union of the numeric values of a column over all rules.
+//The second return value is false, if the column is unconstrained
+func CombineNumericRanges(requirements []NumericRangeRequirement, column string) (NumericRangeSet, bool) {
+
+	if len(requirements) == 0 {
+		return UnconstrainedNumericRangeSet(), false
+	}
+
+	combined := NumericRangeSet{[]NumericInterval{}, false, true}
+
+	for _, requirement := range requirements {
+		ranges, found := requirement.Ranges[column]
+
+		if !found {
+			return UnconstrainedNumericRangeSet(), false
+		}
+
+		combined = combined.Union(ranges)
+	}
+
+	return combined, !combined.IsUnconstrained()
+}
+
+//NumericFilterColumns returns all columns which are compared with a numeric literal inside of the filter
+func NumericFilterColumns(filter FilterNode) []string {
+
+	columns := make([]string, 0)
+
+	CollectComparedLiterals(filter, func(propertyName string, literal string) {
+		if _, err := strconv.ParseFloat(literal, 64); err == nil {
+			columns = appendUnique(columns, propertyName)
+		}
+	})
+
+	return columns
+}
+
+var mirroredOperators = map[string]string{
+	"PropertyIsEqualTo":              "PropertyIsEqualTo",
+	"PropertyIsNotEqualTo":           "PropertyIsNotEqualTo",
+	"PropertyIsLessThan":             "PropertyIsGreaterThan",
+	"PropertyIsLessThanOrEqualTo":    "PropertyIsGreaterThanOrEqualTo",
+	"PropertyIsGreaterThan":          "PropertyIsLessThan",
+	"PropertyIsGreaterThanOrEqualTo": "PropertyIsLessThanOrEqualTo"}
+
+func numericLiteral(expression Expression) (float64, bool) {
+
+	literal, ok := expression.(LiteralExpression)
+
+	if !ok {
+		return 0, false
+	}
+
+	number, err := strconv.ParseFloat(literal.Value, 64)
+
+	return number, err == nil
+}
+
+func (i NumericInterval) isEmpty() bool {
+	return i.Min > i.Max || (i.Min == i.Max && !(i.MinInclusive && i.MaxInclusive))
+}
+
+//normalizeIntervals sorts the intervals and merges overlapping and adjacent intervals
+func normalizeIntervals(intervals []NumericInterval) []NumericInterval {
+
+	sort.Slice(intervals, func(a, b int) bool {
+		if intervals[a].Min == intervals[b].Min {
+			return intervals[a].MinInclusive && !intervals[b].MinInclusive
+		}
+		return intervals[a].Min < intervals[b].Min
+	})
+
+	merged := make([]NumericInterval, 0)
+
+	for _, interval := range intervals {
+		if interval.isEmpty() {
+			continue
+		}
+
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+
+			//overlapping or touching intervals are combined
+			if interval.Min < last.Max || (interval.Min == last.Max && (interval.MinInclusive || last.MaxInclusive)) {
+				if interval.Max > last.Max || (interval.Max == last.Max && interval.MaxInclusive) {
+					last.Max, last.MaxInclusive = interval.Max, interval.MaxInclusive
+				}
+				continue
+			}
+		}
+
+		merged = append(merged, interval)
+	}
+
+	return merged
+}
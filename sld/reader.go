@@ -0,0 +1,16 @@
+package sld
+
+//StyleReader is implemented by all style parsers, which extract the requirements of a style file for a table
+type StyleReader interface {
+	//SetTableNames restricts the requirements to the layers of the tables, the first matching table is used.
+	//Without matching table a file with a single layer is used completely and a file with several layers is not used
+	SetTableNames(tableNames []string, tablePrefix string)
+	//ExtractRequirements returns the requirements of the style
+	ExtractRequirements(mappingColumns MappingColumnNames) (ParsedSLD, error)
+	//LayerNames returns the names of all layers of the style file
+	LayerNames() ([]string, error)
+	//UsedLayers returns the names of the layers, which were used for the requirements
+	UsedLayers() []string
+	//GetFilePath returns the path to the style file
+	GetFilePath() string
+}
@@ -0,0 +1,162 @@
+package sld
+
+import functions "Imposm_Optimizer/std_functions"
+
+//NewTableRequirements returns empty requirements of a style for the mapping columns of a table
+func NewTableRequirements(mappingColumns MappingColumnNames) TableRequirements {
+	return TableRequirements{
+		MappingColumns:          mappingColumns,
+		RequiredColumnList:      make([]RequiredColumn, 0),
+		RequiredMappingValues:   make([]string, 0),
+		RequiredMappingPatterns: make([]string, 0),
+		ImplicitFilteredValues:  make([]string, 0),
+		ColumnValueSets:         make(map[string]ValueSet),
+		NumericRanges:           make([]NumericRangeRequirement, 0),
+		MappingValueScales:      make(map[string][]ScaleDenominator),
+		MappingPatternScales:    make(map[string][]ScaleDenominator),
+		UnfilteredScales:        make([]ScaleDenominator, 0),
+		RuleScales:              make([]ScaleDenominator, 0),
+		MappingValueKinds:       make(map[string][]string),
+		MappingPatternKinds:     make(map[string][]string),
+		UnfilteredKinds:         make([]string, 0),
+		SymbolizerKinds:         make([]string, 0),
+		SymbolizerUnits:         make([]string, 0)}
+}
+
+//AddRequiredColumn adds a column with its literals, the scale range and the usage to the requirements
+func AddRequiredColumn(requirements *TableRequirements, columnName string, literals []string, scale ScaleDenominator, usage string) {
+	addRequiredColumn(&requirements.RequiredColumnList, columnName, literals, scale, usage)
+}
+
+//CompleteRequirements calculates the mapping values, value sets, numeric ranges, scales and symbolizer kinds of the rules.
+//Columns, which are used outside of the filters, have to be added with AddRequiredColumn before.
+//It returns the scale range of all rules and false, if only the listed mapping values reach a symbolizer
+func CompleteRequirements(ruleList []Rule, requirements *TableRequirements) (ScaleDenominator, bool) {
+	columnList := &requirements.RequiredColumnList
+	mappingValueColumnName := requirements.MappingColumns.MappingValueColumnName
+	scaleDenominator := &ScaleDenominator{-1, -1}
+	useAllMappingTypes := true
+
+	//an ElseFilter is true for all features, which are not matched by the other rules of the style
+	ruleList = resolveElseFilters(ruleList)
+
+	//calculate the minimum and maximum scale denominator of all mapping values used
+	//and combine the mapping values of all rules, which can reach a symbolizer
+	foundRule := (len(ruleList) > 0)
+	var combinedValues *ValueSet
+
+	for _, rule := range ruleList {
+
+		if rule.MaxScale == 0 {
+			scaleDenominator.MaxScaleDenominator = -2
+		} else if rule.MaxScale > scaleDenominator.MaxScaleDenominator && scaleDenominator.MaxScaleDenominator != -2 {
+			scaleDenominator.MaxScaleDenominator = rule.MaxScale
+		}
+
+		if rule.MinScale < scaleDenominator.MinScaleDenominator || scaleDenominator.MinScaleDenominator == -1 {
+			scaleDenominator.MinScaleDenominator = rule.MinScale
+		}
+
+		requirements.SymbolizerKinds = appendUnique(requirements.SymbolizerKinds, SymbolizerKinds(&rule)...)
+		requirements.SymbolizerUnits = appendUnique(requirements.SymbolizerUnits, SymbolizerUnits(&rule)...)
+
+		if !scaleInList(ruleScale(&rule), requirements.RuleScales) {
+			requirements.RuleScales = append(requirements.RuleScales, ruleScale(&rule))
+		}
+
+		//add all literals compared with a column, they are used to calculate the data type
+		CollectComparedLiterals(rule.FilterTree, func(propertyName string, literal string) {
+			addRequiredColumn(columnList, propertyName, []string{literal}, ruleScale(&rule), FilterUsage)
+		})
+
+		ruleValues, filtersMappingType := ruleMappingValueSet(&rule, mappingValueColumnName)
+
+		if !filtersMappingType {
+			continue
+		}
+
+		//values of explicit filtering rules are required, even if other rules use all mapping values
+		if !ruleValues.Negated {
+			for _, value := range ruleValues.Values {
+				if value == NullValue {
+					continue
+				}
+
+				if !functions.StringInSlice(value, requirements.RequiredMappingValues) {
+					requirements.RequiredMappingValues = append(requirements.RequiredMappingValues, value)
+				}
+
+				requirements.MappingValueScales[value] = MergeScale(requirements.MappingValueScales[value], ruleScale(&rule))
+				requirements.MappingValueKinds[value] = appendUnique(requirements.MappingValueKinds[value], SymbolizerKinds(&rule)...)
+			}
+
+			for _, pattern := range ruleValues.Patterns {
+				requirements.RequiredMappingPatterns = appendUnique(requirements.RequiredMappingPatterns, pattern)
+				requirements.MappingPatternScales[pattern] = MergeScale(requirements.MappingPatternScales[pattern], ruleScale(&rule))
+				requirements.MappingPatternKinds[pattern] = appendUnique(requirements.MappingPatternKinds[pattern], SymbolizerKinds(&rule)...)
+			}
+		} else {
+			requirements.UnfilteredScales = MergeScale(requirements.UnfilteredScales, ruleScale(&rule))
+			requirements.UnfilteredKinds = appendUnique(requirements.UnfilteredKinds, SymbolizerKinds(&rule)...)
+		}
+
+		if combinedValues == nil {
+			combinedValues = &ruleValues
+		} else {
+			union := combinedValues.Union(ruleValues)
+			combinedValues = &union
+		}
+	}
+
+	//calculate the values of each filtered column, which can reach a symbolizer of any rule
+	filteredColumns := make([]string, 0)
+
+	for _, rule := range ruleList {
+		filteredColumns = appendUnique(filteredColumns, FilterPropertyNames(rule.FilterTree)...)
+	}
+
+	for _, column := range filteredColumns {
+		columnValues := ColumnValueSet(ruleList[0].FilterTree, column)
+
+		for _, rule := range ruleList[1:] {
+			columnValues = columnValues.Union(ColumnValueSet(rule.FilterTree, column))
+		}
+
+		if !columnValues.IsUnconstrained() {
+			requirements.ColumnValueSets[column] = columnValues
+		}
+	}
+
+	//calculate the numeric values of each numerically compared column for every rule and its scale range
+	for _, rule := range ruleList {
+		ranges := make(map[string]NumericRangeSet)
+
+		for _, column := range NumericFilterColumns(rule.FilterTree) {
+			columnRanges := ColumnNumericRangeSet(rule.FilterTree, column)
+
+			if !columnRanges.IsUnconstrained() {
+				ranges[column] = columnRanges
+			}
+		}
+
+		requirements.NumericRanges = append(requirements.NumericRanges, NumericRangeRequirement{ruleScale(&rule), ranges})
+	}
+
+	if combinedValues == nil || !combinedValues.Negated {
+		//set sld filter status, only the listed mapping values reach a symbolizer
+		if foundRule {
+			useAllMappingTypes = false
+		}
+	} else {
+		//all mapping values except the listed ones reach a symbolizer
+		for _, value := range combinedValues.Values {
+			implicitValue := mappingValueColumnName + ":" + value
+
+			if value != NullValue && !functions.StringInSlice(implicitValue, requirements.ImplicitFilteredValues) {
+				requirements.ImplicitFilteredValues = append(requirements.ImplicitFilteredValues, implicitValue)
+			}
+		}
+	}
+
+	return *scaleDenominator, useAllMappingTypes
+}
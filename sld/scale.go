@@ -0,0 +1,135 @@
+package sld
+
+import (
+	"math"
+	"sort"
+)
+
+//AllScales is a scale range which contains every scale denominator
+var AllScales = ScaleDenominator{0, -2}
+
+//Overlaps checks if two scale ranges have at least one common scale denominator.
+//The minimum is inclusive, the maximum exclusive and -2 is an infinite maximum
+func (s ScaleDenominator) Overlaps(other ScaleDenominator) bool {
+	return other.MinScaleDenominator < s.max() && s.MinScaleDenominator < other.max()
+}
+
+//Covers checks if every scale denominator of the other scale range is part of this scale range
+func (s ScaleDenominator) Covers(other ScaleDenominator) bool {
+	return s.MinScaleDenominator <= other.MinScaleDenominator && other.max() <= s.max()
+}
+
+//OverlapsAny checks if at least one scale range of the list overlaps the scale range
+func (s ScaleDenominator) OverlapsAny(scales []ScaleDenominator) bool {
+	for _, scale := range scales {
+		if s.Overlaps(scale) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//scaleInList checks if the scale range is part of the list
+func scaleInList(scale ScaleDenominator, scales []ScaleDenominator) bool {
+	for _, listScale := range scales {
+		if listScale == scale {
+			return true
+		}
+	}
+
+	return false
+}
+
+//max returns the maximum scale denominator, an infinite maximum is returned as math.MaxInt
+func (s ScaleDenominator) max() int {
+	if s.MaxScaleDenominator == -2 {
+		return math.MaxInt
+	}
+
+	return s.MaxScaleDenominator
+}
+
+//MergeScale adds a scale range to a list of scale ranges, overlapping and adjacent ranges are combined
+func MergeScale(scales []ScaleDenominator, scale ScaleDenominator) []ScaleDenominator {
+
+	list := append(append([]ScaleDenominator{}, scales...), scale)
+
+	sort.Slice(list, func(a, b int) bool {
+		return list[a].MinScaleDenominator < list[b].MinScaleDenominator
+	})
+
+	merged := make([]ScaleDenominator, 0)
+
+	for _, current := range list {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+
+			if current.MinScaleDenominator <= last.max() {
+				if current.max() > last.max() {
+					last.MaxScaleDenominator = current.MaxScaleDenominator
+				}
+				continue
+			}
+		}
+
+		merged = append(merged, current)
+	}
+
+	return merged
+}
+
+//InScale returns a copy of the parsed SLD, which only contains the requirements of the rules visible in the scale band
+func (p ParsedSLD) InScale(band ScaleDenominator) ParsedSLD {
+
+	requirements := p.Requirements
+
+	requirements.RequiredColumnList = make([]RequiredColumn, 0)
+
+	for _, column := range p.Requirements.RequiredColumnList {
+		if band.OverlapsAny(column.Scales) {
+			requirements.RequiredColumnList = append(requirements.RequiredColumnList, column)
+		}
+	}
+
+	requirements.RequiredMappingValues = make([]string, 0)
+
+	for _, value := range p.Requirements.RequiredMappingValues {
+		if band.OverlapsAny(p.Requirements.MappingValueScales[value]) {
+			requirements.RequiredMappingValues = append(requirements.RequiredMappingValues, value)
+		}
+	}
+
+	requirements.RequiredMappingPatterns = make([]string, 0)
+
+	for _, pattern := range p.Requirements.RequiredMappingPatterns {
+		if band.OverlapsAny(p.Requirements.MappingPatternScales[pattern]) {
+			requirements.RequiredMappingPatterns = append(requirements.RequiredMappingPatterns, pattern)
+		}
+	}
+
+	requirements.NumericRanges = make([]NumericRangeRequirement, 0)
+
+	for _, ranges := range p.Requirements.NumericRanges {
+		if band.Overlaps(ranges.Scale) {
+			requirements.NumericRanges = append(requirements.NumericRanges, ranges)
+		}
+	}
+
+	requirements.RuleScales = make([]ScaleDenominator, 0)
+
+	for _, scale := range p.Requirements.RuleScales {
+		if band.Overlaps(scale) {
+			requirements.RuleScales = append(requirements.RuleScales, scale)
+		}
+	}
+
+	if !band.OverlapsAny(requirements.UnfilteredScales) {
+		requirements.UnfilteredKinds = make([]string, 0)
+	}
+
+	//a style without rules never filters the mapping values
+	useAllMappingTypes := p.UseAllMappingTypes && (p.Scale.MinScaleDenominator == -1 || band.OverlapsAny(requirements.UnfilteredScales))
+
+	return ParsedSLD{p.FileName, requirements, p.Scale, useAllMappingTypes, p.Version}
+}
@@ -7,39 +7,26 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"path/filepath"
+	"strconv"
 )
 
-var nonExplicitFilteringOperators = []string{
-	"PropertyIsNotEqualTo",
-	"PropertyIsLike",
-	"PropertyIsBetween",
-	"PropertyIsLessThan",
-	"PropertyIsLessThanOrEqualTo",
-	"PropertyIsGreaterThan",
-	"PropertyIsGreaterThanOrEqualTo"}
-
-var nonExplicitFilteringFunctions = []string{
-	"between",
-	"greaterEqualThan",
-	"greaterThan",
-	"isLike",
-	"lessThan",
-	"lessEqualThan",
-	"not",
-	"notEqual"}
-
 //Parser class
 type Parser struct {
 	filePath           string
 	successfullPasing  bool
 	fileByteArray      []byte
 	useAllMappingTypes bool
+	version            string
+	tableNames         []string
+	tablePrefix        string
+	usedLayers         []string
 }
 
 //New sldParser instance
 func New(filePath string) Parser {
-	s := Parser{filePath, false, []byte{}, true}
+	s := Parser{filePath, false, []byte{}, true, "", nil, "", make([]string, 0)}
 	return s
 }
 
@@ -76,22 +63,21 @@ func (s *Parser) ExtractRequirements(mappingColums MappingColumnNames) (ParsedSL
 		}
 	}
 
-	columnList := make([]RequiredColumn, 0)
-	mappingTypeList := make([]string, 0)
-	implicitFilteredValueList := make([]string, 0)
-
+	requirements := NewTableRequirements(mappingColums)
 	scaleDenominator := ScaleDenominator{-1, -1}
 
-	err := s.searchSLDRecursiv(s.fileByteArray, &columnList, &mappingTypeList, &implicitFilteredValueList, &scaleDenominator, mappingColums.MappingValueColumnName)
+	err := s.searchSLDRecursiv(s.fileByteArray, &requirements, &scaleDenominator)
 
 	if err != nil {
 		return ParsedSLD{}, err
 	}
 
-	return ParsedSLD{s.filePath, TableRequirements{mappingColums, columnList, mappingTypeList, implicitFilteredValueList}, scaleDenominator, s.useAllMappingTypes}, nil
+	return ParsedSLD{s.filePath, requirements, scaleDenominator, s.useAllMappingTypes, s.version}, nil
 }
 
-func (s *Parser) searchSLDRecursiv(mappingFileData []byte, columnList *[]RequiredColumn, mappingTypeList *[]string, implicitFilteredValueList *[]string, scaleDenominator *ScaleDenominator, mappingValueColumnName string) error {
+func (s *Parser) searchSLDRecursiv(mappingFileData []byte, requirements *TableRequirements, scaleDenominator *ScaleDenominator) error {
+	columnList := &requirements.RequiredColumnList
+
 	//init buffer and decoder for unmarshal recursiv xml
 	sldBuffer := bytes.NewBuffer(mappingFileData)
 	decoder := xml.NewDecoder(sldBuffer)
@@ -103,201 +89,261 @@ func (s *Parser) searchSLDRecursiv(mappingFileData []byte, columnList *[]Require
 		return err
 	}
 
-	//Rule array for all found rule tags
-	//For the following calculation of the min/max scale dominators of the filtered mapping values
-	ruleList := make([]Rule, 0)
-
-	//walk through nodes
-	walk([]recursiveNode{node}, &node, func(node recursiveNode) bool {
-
-		//search for PropertyName Element
-		if node.XMLName.Local == "PropertyName" {
-
-			newColumnName := string(node.Content)
-			newColumn := RequiredColumn{newColumnName, nil}
+	s.version = documentVersion(&node)
 
-			literalList := make([]string, 0)
+	//multi-layer files only use the layers and feature types of the table
+	tableName := s.selectedTableName(&node)
+	s.usedLayers = make([]string, 0)
 
-			//search all Literals that belongs to the PropertyName
-			if node.ParentNode != nil {
+	//without matching table only a file with a single layer is used
+	skipLayers := tableName == "" && len(documentLayerNames(&node)) > 1
 
-				//search the parentnode for "Literal"
-				for _, adjacentNode := range node.ParentNode.Nodes {
-					if adjacentNode.XMLName.Local == "Literal" {
+	if skipLayers {
+		fmt.Println(`WARNING: no layer of the style file "`+s.filePath+`" matches the tables`, s.tableNames)
+	}
 
-						//add Literal to literalList, is used to calculate the data type
-						newLiteralName := string(adjacentNode.Content)
+	//Rule array for all found rule tags
+	//For the following calculation of the min/max scale dominators of the filtered mapping values
+	ruleList := make([]Rule, 0)
 
-						if !functions.StringInSlice(newLiteralName, literalList) {
-							literalList = append(literalList, newLiteralName)
-						}
+	//columnWalker returns a walk function, which adds all used columns with the scale range they are used in
+	columnWalker := func(scale ScaleDenominator) func(recursiveNode) bool {
+		return func(node recursiveNode) bool {
 
-						//add Literal to mappingTypeList if columnname matches the mapping value columnname
-						if newColumnName == mappingValueColumnName {
+			//search for PropertyName Element, the literals are added from the filter tree of the rules
+			if isPropertyElement(&node) {
 
-							if !functions.StringInSlice(newLiteralName, *mappingTypeList) {
-								*mappingTypeList = append(*mappingTypeList, newLiteralName)
-							}
+				addRequiredColumn(columnList, nodeText(&node), nil, scale, columnUsage(&node))
 
+				//search for VendorOption "sortBy" and add all sorting columns to columnList
+			} else if isStyleElement(&node, "VendorOption") {
+				for _, attr := range node.Attrs {
+					if attr.Name.Local == "name" && attr.Value == "sortBy" {
+						for _, column := range SortByColumns(nodeText(&node)) {
+							addRequiredColumn(columnList, column, nil, scale, SortUsage)
 						}
 					}
 				}
-			}
-
-			//check if PropertyName Element is not already in list
-			found, i := ColumnInColumnlist(newColumnName, *columnList)
 
-			if !found {
+				return true
 
-				newColumn.Literals = literalList
-				*columnList = append(*columnList, newColumn)
-			} else {
-				//if PropertyName Element is already in list, add missing literals
-				for _, literal := range literalList {
-					if !functions.StringInSlice(literal, (*columnList)[i].Literals) {
-						(*columnList)[i].Literals = append((*columnList)[i].Literals, literal)
-					}
+				//search for functions like recode, their keys are used to calculate the data type of the column
+			} else if isFilterElement(&node, "Function") {
+				if expression, err := parseExpression(&node); err == nil {
+					CollectFunctionLiterals(expression, func(propertyName string, literal string) {
+						addRequiredColumn(columnList, propertyName, []string{literal}, scale, columnUsage(&node))
+					})
 				}
-			}
-
-			//search for VendorOption "name" and "sortby" and add attribut to columnList
-		} else if node.XMLName.Local == "VendorOption" {
-			for _, attr := range node.Attrs {
-				if attr.Name.Local == "name" && attr.Value == "sortBy" {
-					newColumnName := string(node.Content)
-
-					//check if PropertyName Element is not already in list
-					found, _ := ColumnInColumnlist(newColumnName, *columnList)
 
-					if !found {
-						*columnList = append(*columnList, RequiredColumn{newColumnName, nil})
-					}
+				//Symbology Encoding 1.1 defines the functions as elements
+			} else if isStyleElement(&node, "Recode", "Categorize", "Interpolate") {
+				collectSEFunctionLiterals(&node, func(propertyName string, literal string) {
+					addRequiredColumn(columnList, propertyName, []string{literal}, scale, columnUsage(&node))
+				})
+			}
 
-				}
+			//search for embedded CQL expressions like <Label>${name}</Label> or <CssParameter name="fill">${color}</CssParameter>
+			for _, column := range EmbeddedExpressionColumns(ownText(&node)) {
+				addRequiredColumn(columnList, column, nil, scale, columnUsage(&node))
 			}
 
-		} else if node.XMLName.Local == "Rule" { //extract all rule tags
+			//Continue the walk through function
+			return true
+		}
+	}
+
+	//columns outside of rules are used in all scales
+	addColumns := columnWalker(AllScales)
 
-			copyByteStream := node.Content
+	//index of the current FeatureTypeStyle, the rules of a style are siblings of an ElseFilter rule
+	featureTypeStyle := -1
 
-			//add beginning and end tag to the rule content, for correct decoding the rule
-			copyByteStream = append([]byte("<Rule>"), copyByteStream...)
-			copyByteStream = append(copyByteStream, "</Rule>"...)
+	//walk through nodes
+	walk([]recursiveNode{node}, &node, func(node recursiveNode) bool {
 
-			newRule := Rule{}
-			err := xml.Unmarshal(copyByteStream, &newRule)
+		if skipLayers && isStyleElement(&node, "NamedLayer", "UserLayer") {
+			return false
+		}
 
-			if err != nil {
-				fmt.Println("Parsing Error: " + err.Error())
-			} else {
-				ruleList = append(ruleList, newRule)
+		if tableName != "" && isStyleElement(&node, "NamedLayer", "UserLayer") {
+			if layerName := childText(&node, "Name"); MatchesTable(layerName, tableName, s.tablePrefix) {
+				s.usedLayers = appendUnique(s.usedLayers, layerName)
 			}
 		}
 
-		//Continue the walk through function
-		return true
-	})
+		if isStyleElement(&node, "FeatureTypeStyle", "CoverageStyle") {
+			featureTypeStyle++
+
+			//the FeatureTypeName of a style overrides the layer name
+			if tableName != "" {
+				featureTypeName := childText(&node, "FeatureTypeName")
 
-	//search after rule tag to check if the rule filter uses the mapping value collumn
-	//calculate the minimum and maximum scale denominator of all mapping values used
-	foundRule := (len(ruleList) > 0)
-	ruleFiltersMappingType := true
+				if featureTypeName != "" && MatchesTable(featureTypeName, tableName, s.tablePrefix) {
+					s.usedLayers = appendUnique(s.usedLayers, featureTypeName)
+				}
 
-	for _, rule := range ruleList {
+				if !s.inScope(&node, tableName) {
+					return false
+				}
+			}
+		}
 
-		if rule.MaxScale == 0 {
-			scaleDenominator.MaxScaleDenominator = -2
-		} else if rule.MaxScale > scaleDenominator.MaxScaleDenominator && scaleDenominator.MaxScaleDenominator != -2 {
-			scaleDenominator.MaxScaleDenominator = rule.MaxScale
+		//elements of other layers are skipped
+		if tableName != "" && !s.inScope(&node, tableName) {
+			return !isStyleElement(&node, "Rule")
 		}
 
-		if rule.MinScale < scaleDenominator.MinScaleDenominator || scaleDenominator.MinScaleDenominator == -1 {
-			scaleDenominator.MinScaleDenominator = rule.MinScale
+		if !isStyleElement(&node, "Rule") {
+			return addColumns(node)
 		}
 
-		mappingTypeFound, err := checkIfRuleFiltersMappingTypes(&rule, mappingValueColumnName, implicitFilteredValueList)
+		//build the rule directly from the node, so that no namespace information is lost
+		newRule, err := parseRule(&node, featureTypeStyle)
 
 		if err != nil {
 			fmt.Println("Parsing Error: " + err.Error())
-			ruleFiltersMappingType = false
+			return true
 		}
 
-		if !mappingTypeFound {
-			ruleFiltersMappingType = false
-		}
-	}
+		//all columns of the rule are only used in the scale range of the rule
+		walk(node.Nodes, &node, columnWalker(ruleScale(&newRule)))
 
-	//set sld filter status
-	if foundRule && ruleFiltersMappingType {
-		s.useAllMappingTypes = false
-	}
+		ruleList = append(ruleList, newRule)
+
+		return false
+	})
+
+	*scaleDenominator, s.useAllMappingTypes = CompleteRequirements(ruleList, requirements)
 
 	return nil
 }
 
-func checkIfRuleFiltersMappingTypes(rule *Rule, mappingValueColumnName string, implicitFilteredValueList *[]string) (bool, error) {
+//parseRule builds a rule from a Rule element of SLD 1.0.0 or Symbology Encoding 1.1
+func parseRule(node *recursiveNode, featureTypeStyle int) (Rule, error) {
 
-	if rule.Filter.XMLContent == nil {
+	rule := Rule{FeatureTypeStyle: featureTypeStyle}
 
-		if rule.TextSymbolizer == nil {
-			return false, nil
+	for i := range node.Nodes {
+		child := &node.Nodes[i]
+		symbolizer := Symbolizer{child.Content, unitOfMeasure(child)}
+
+		switch {
+		case isStyleElement(child, "Name"):
+			rule.Name = nodeText(child)
+		case isStyleElement(child, "Title"):
+			rule.Title = nodeText(child)
+		case isStyleElement(child, "Abstract"):
+			rule.Abstract = nodeText(child)
+		case isStyleElement(child, "MinScaleDenominator", "MaxScaleDenominator"):
+			//scale denominators are doubles, e.g. 50000.0
+			scale, err := strconv.ParseFloat(nodeText(child), 64)
+
+			if err != nil {
+				return Rule{}, errors.New(`invalid ` + child.XMLName.Local + ` "` + nodeText(child) + `"`)
+			}
+
+			if child.XMLName.Local == "MinScaleDenominator" {
+				rule.MinScale = int(math.Round(scale))
+			} else {
+				rule.MaxScale = int(math.Round(scale))
+			}
+		case isFilterElement(child, "Filter"):
+			rule.Filter = Filter{child.Content}
+			filterTree, err := parseFilter(child)
+
+			if err != nil {
+				fmt.Println("Parsing Error: " + err.Error())
+				filterTree = UnknownFilter{"Filter"}
+			}
+
+			rule.FilterTree = filterTree
+		case isStyleElement(child, "ElseFilter"):
+			rule.ElseFilter = &Filter{child.Content}
+		case isStyleElement(child, "PointSymbolizer"):
+			rule.PointSymbolizer = append(rule.PointSymbolizer, symbolizer)
+		case isStyleElement(child, "LineSymbolizer"):
+			rule.LineSymbolizer = append(rule.LineSymbolizer, symbolizer)
+		case isStyleElement(child, "PolygonSymbolizer"):
+			rule.PolygonSymbolizer = append(rule.PolygonSymbolizer, symbolizer)
+		case isStyleElement(child, "TextSymbolizer"):
+			rule.TextSymbolizer = append(rule.TextSymbolizer, symbolizer)
+		case isStyleElement(child, "RasterSymbolizer"):
+			rule.RasterSymbolizer = append(rule.RasterSymbolizer, symbolizer)
+		}
+	}
+
+	return rule, nil
+}
+
+//inScope checks if an element belongs to the table. The scope is defined by the FeatureTypeName of the enclosing
+//FeatureTypeStyle or by the name of the enclosing layer, elements outside of layers belong to every table
+func (s *Parser) inScope(node *recursiveNode, tableName string) bool {
+
+	for ancestor := node; ancestor != nil; ancestor = ancestor.ParentNode {
+		if isStyleElement(ancestor, "FeatureTypeStyle", "CoverageStyle") {
+			if featureTypeName := childText(ancestor, "FeatureTypeName"); featureTypeName != "" {
+				return MatchesTable(featureTypeName, tableName, s.tablePrefix)
+			}
 		}
 
-		return true, nil
+		if isStyleElement(ancestor, "NamedLayer", "UserLayer") {
+			return MatchesTable(childText(ancestor, "Name"), tableName, s.tablePrefix)
+		}
 	}
 
-	//init buffer and decoder for unmarshal recursiv xml
-	ruleBuffer := bytes.NewBuffer(rule.Filter.XMLContent) //check only the filter tag/content
-	dececoder := xml.NewDecoder(ruleBuffer)
+	return true
+}
 
-	//start node
-	var node recursiveNode
-	err := dececoder.Decode(&node)
-	if err != nil {
-		return false, err
+//ruleMappingValueSet calculates the mapping values, which can reach a symbolizer of the rule.
+//The second return value is false, if the rule does not take part in the filtering
+func ruleMappingValueSet(rule *Rule, mappingValueColumnName string) (ValueSet, bool) {
+
+	//rules without symbolizers never draw a feature
+	if len(SymbolizerKinds(rule)) == 0 {
+		return ValueSet{}, false
 	}
 
-	foundMappingFilter := true
+	//rules without filter draw every feature, a rule which only labels the features as well
+	if rule.FilterTree == nil {
+		return UnconstrainedValueSet(), true
+	}
 
-	walk([]recursiveNode{node}, &node, func(node recursiveNode) bool {
-		if node.XMLName.Local == "PropertyName" {
-			if string(node.Content) == mappingValueColumnName {
+	return ColumnValueSet(rule.FilterTree, mappingValueColumnName), true
+}
 
-				if node.ParentNode != nil {
-					if functions.StringInSlice(node.ParentNode.XMLName.Local, nonExplicitFilteringOperators) {
+//ruleScale returns the scale denominators of a rule, a missing maximum is represented by -2
+func ruleScale(rule *Rule) ScaleDenominator {
 
-						if node.ParentNode.XMLName.Local == "PropertyIsNotEqualTo" {
+	if rule.MaxScale == 0 {
+		return ScaleDenominator{rule.MinScale, -2}
+	}
 
-							for _, adjacentNode := range node.ParentNode.Nodes {
-								if adjacentNode.XMLName.Local == "Literal" {
-									newLiteralName := string(adjacentNode.Content)
-									*implicitFilteredValueList = append(*implicitFilteredValueList, string(node.Content)+":"+newLiteralName)
-								}
-							}
-						}
+	return ScaleDenominator{rule.MinScale, rule.MaxScale}
+}
 
-						foundMappingFilter = false
-						return false
-					} else if node.ParentNode.XMLName.Local == "Function" {
-						for _, attr := range node.ParentNode.Attrs {
-							if attr.Name.Local == "name" {
-								if functions.StringInSlice(attr.Value, nonExplicitFilteringFunctions) {
-									foundMappingFilter = false
-									return false
-								}
-							}
-						}
-					}
-				}
+//addRequiredColumn adds a column with its literals, the scale range and the usage to the column list
+func addRequiredColumn(columnList *[]RequiredColumn, columnName string, literals []string, scale ScaleDenominator, usage string) {
 
-				return true
-			}
-		}
+	if columnName == "" {
+		return
+	}
 
-		return true
-	})
+	found, i := ColumnInColumnlist(columnName, *columnList)
+
+	if !found {
+		*columnList = append(*columnList, RequiredColumn{columnName, appendUnique(nil, literals...), []ScaleDenominator{scale}, []string{usage}})
+		return
+	}
 
-	return foundMappingFilter, nil
+	//if PropertyName Element is already in list, add missing literals
+	for _, literal := range literals {
+		if !functions.StringInSlice(literal, (*columnList)[i].Literals) {
+			(*columnList)[i].Literals = append((*columnList)[i].Literals, literal)
+		}
+	}
+
+	(*columnList)[i].Scales = MergeScale((*columnList)[i].Scales, scale)
+	(*columnList)[i].Usages = appendUnique((*columnList)[i].Usages, usage)
 }
 
 //Node Structure
@@ -352,6 +398,11 @@ func (s *Parser) GetFilePath() string {
 	return s.filePath
 }
 
+//Version returns the version of the parsed SLD file (1.0.0 or 1.1.0)
+func (s *Parser) Version() string {
+	return s.version
+}
+
 /*UseAllMappingTypes indicates whether it is obvious which mapping values should be used.
 true = mapping values can be filtered,
 false = it is not obvious which mapping values can be used. There is no filtering*/
@@ -8,11 +8,14 @@ type Filter struct {
 }
 
 //Symbolizer contains raw XML data from a symbolic tag of a SLD
+//UnitOfMeasure = the unit of the sizes of the symbolizer (pixel, metre or foot)
 type Symbolizer struct {
-	XMLContent []byte `xml:",innerxml"`
+	XMLContent    []byte `xml:",innerxml"`
+	UnitOfMeasure string `xml:"uom,attr,omitempty"`
 }
 
 //Rule describes the structure of a rule in an SLD
+//FeatureTypeStyle = the index of the FeatureTypeStyle, which contains the rule
 type Rule struct {
 	Name              string       `xml:"Name,omitempty"`
 	Title             string       `xml:"Title,omitempty"`
@@ -20,44 +23,75 @@ type Rule struct {
 	MinScale          int          `xml:"MinScaleDenominator,omitempty"`
 	MaxScale          int          `xml:"MaxScaleDenominator,omitempty"`
 	Filter            Filter       `xml:"Filter,omitempty"`
+	ElseFilter        *Filter      `xml:"ElseFilter"`
 	PointSymbolizer   []Symbolizer `xml:"PointSymbolizer,omitempty"`
 	LineSymbolizer    []Symbolizer `xml:"LineSymbolizer,omitempty"`
 	PolygonSymbolizer []Symbolizer `xml:"PolygonSymbolizer,omitempty"`
 	TextSymbolizer    []Symbolizer `xml:"TextSymbolizer,omitempty"`
 	RasterSymbolizer  []Symbolizer `xml:"RasterSymbolizer,omitempty"`
+	FilterTree        FilterNode   `xml:"-"`
+	FeatureTypeStyle  int          `xml:"-"`
 }
 
 //########### Parser structures ###########//
 
-//ScaleDenominator contains information of the scale denominator of a specific sld file
+//ScaleDenominator contains information of the scale denominator of a specific sld file or rule, -2 is an infinite maximum
 type ScaleDenominator struct {
-	MinScaleDenominator int
-	MaxScaleDenominator int
+	MinScaleDenominator int `json:"min_scale_denominator"`
+	MaxScaleDenominator int `json:"max_scale_denominator"`
 }
 
 //RequiredColumn contains the key name and key values of a mapping class
+//Scales = the scale ranges in which the column is used by a rule
+//Usages = what the column is used for (filter, label, style or sort)
 type RequiredColumn struct {
 	PropertyName string
 	Literals     []string
+	Scales       []ScaleDenominator
+	Usages       []string
 }
 
 //TableRequirements combine all required table columns and mapping values
+//RequiredMappingPatterns = regular expressions of PropertyIsLike operators, matching mapping values are required as well
+//ColumnValueSets = for each filtered column the values which can reach a symbolizer, unconstrained columns are missing
+//NumericRanges = the numeric values of the numerically compared columns for every rule
+//MappingValueScales/MappingPatternScales = the scale ranges in which a required mapping value/pattern is used by a rule
+//UnfilteredScales = the scale ranges of all rules which use all mapping values
+//RuleScales = the distinct scale ranges of all rules
+//MappingValueKinds/MappingPatternKinds = the symbolizer kinds of the rules, which use a required mapping value/pattern
+//UnfilteredKinds = the symbolizer kinds of all rules which use all mapping values
+//SymbolizerKinds = the symbolizer kinds of all rules
+//SymbolizerUnits = the units of measure of all symbolizers
 type TableRequirements struct {
-	MappingColumns         MappingColumnNames
-	RequiredColumnList     []RequiredColumn
-	RequiredMappingValues  []string
-	ImplicitFilteredValues []string
+	MappingColumns          MappingColumnNames
+	RequiredColumnList      []RequiredColumn
+	RequiredMappingValues   []string
+	RequiredMappingPatterns []string
+	ImplicitFilteredValues  []string
+	ColumnValueSets         map[string]ValueSet
+	NumericRanges           []NumericRangeRequirement
+	MappingValueScales      map[string][]ScaleDenominator
+	MappingPatternScales    map[string][]ScaleDenominator
+	UnfilteredScales        []ScaleDenominator
+	RuleScales              []ScaleDenominator
+	MappingValueKinds       map[string][]string
+	MappingPatternKinds     map[string][]string
+	UnfilteredKinds         []string
+	SymbolizerKinds         []string
+	SymbolizerUnits         []string
 }
 
 //ParsedSLD contains necessary information about the parsed SLD file
 //FileName = the path to the parsed SLD file
 //Requirements = List of the required table columns/mapping values
 //UseAllMappingTypes = If all mapping values are to be used, is caused by missing filtering of the mapping column
+//Version = the version of the style file, e.g. 1.0.0 or 1.1.0 for SLD files
 type ParsedSLD struct {
 	FileName           string
 	Requirements       TableRequirements
 	Scale              ScaleDenominator
 	UseAllMappingTypes bool
+	Version            string
 }
 
 //MappingColumnNames stores the column names, which have the value "mapping_value" or "mapping_key" as type
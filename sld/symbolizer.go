@@ -0,0 +1,73 @@
+package sld
+
+//symbolizer kinds of a rule
+const (
+	PointKind   = "point"
+	LineKind    = "line"
+	PolygonKind = "polygon"
+	TextKind    = "text"
+	RasterKind  = "raster"
+)
+
+//drawingKinds contains the symbolizer kinds, which draw something for a geometry type.
+//Point and text symbolizers are drawn for every geometry, line symbolizers also draw the outline of polygons
+var drawingKinds = map[string][]string{
+	"point":      {PointKind, TextKind},
+	"linestring": {LineKind, PointKind, TextKind},
+	"polygon":    {PolygonKind, LineKind, PointKind, TextKind}}
+
+//SymbolizerKinds returns the kinds of all symbolizers of a rule
+func SymbolizerKinds(rule *Rule) []string {
+
+	kinds := make([]string, 0)
+
+	if len(rule.PointSymbolizer) > 0 {
+		kinds = append(kinds, PointKind)
+	}
+
+	if len(rule.LineSymbolizer) > 0 {
+		kinds = append(kinds, LineKind)
+	}
+
+	if len(rule.PolygonSymbolizer) > 0 {
+		kinds = append(kinds, PolygonKind)
+	}
+
+	if len(rule.TextSymbolizer) > 0 {
+		kinds = append(kinds, TextKind)
+	}
+
+	if len(rule.RasterSymbolizer) > 0 {
+		kinds = append(kinds, RasterKind)
+	}
+
+	return kinds
+}
+
+//SymbolizerUnits returns the units of measure of all symbolizers of a rule
+func SymbolizerUnits(rule *Rule) []string {
+
+	units := make([]string, 0)
+
+	for _, symbolizers := range [][]Symbolizer{rule.PointSymbolizer, rule.LineSymbolizer, rule.PolygonSymbolizer, rule.TextSymbolizer, rule.RasterSymbolizer} {
+		for _, symbolizer := range symbolizers {
+			units = appendUnique(units, symbolizer.UnitOfMeasure)
+		}
+	}
+
+	return units
+}
+
+//DrawsGeometry checks if at least one of the symbolizer kinds draws the geometry type (point, linestring or polygon)
+func DrawsGeometry(kinds []string, geometryType string) bool {
+
+	for _, kind := range kinds {
+		for _, drawingKind := range drawingKinds[geometryType] {
+			if kind == drawingKind {
+				return true
+			}
+		}
+	}
+
+	return false
+}
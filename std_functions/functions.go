@@ -44,3 +44,11 @@ func DirExists(dirName string) bool {
 	}
 	return info.IsDir()
 }
+
+//AppendUnique Appends a string to a Slice, if it is not empty and not yet part of the Slice
+func AppendUnique(list []string, a string) []string {
+	if a == "" || StringInSlice(a, list) {
+		return list
+	}
+	return append(list, a)
+}
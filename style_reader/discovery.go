@@ -0,0 +1,158 @@
+package styles
+
+import (
+	"Imposm_Optimizer/sld"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//tablePlaceholder is replaced by the table name in the file name pattern of the style files
+const tablePlaceholder = "{table}"
+
+//Discovery contains the style files of the tables, which were found in a style directory.
+//UnmatchedStyles = style files without table, UnstyledTables = tables and generalized tables without style file,
+//UnreadableDirectories = directories, which could not be read and could contain further style files
+type Discovery struct {
+	Tables                map[string][]string
+	GeneralizedTables     map[string][]string
+	UnmatchedStyles       []string
+	UnstyledTables        []string
+	UnreadableDirectories []string
+}
+
+//Discover scans a directory tree for style files and assigns every file to the tables, which match its file name pattern
+//like "osm_{table}.sld" or one of its layer and feature type names. Without pattern only the layer names are used.
+//A generalized table without own style file is unstyled, even if its source table has style files.
+//The scan continues after a directory, which cannot be read
+func Discover(directory string, fileNamePattern string, tableNames []string, genTableNames []string, tablePrefix string) (Discovery, error) {
+
+	var patternRegexp *regexp.Regexp
+
+	if fileNamePattern != "" {
+		if !strings.Contains(fileNamePattern, tablePlaceholder) {
+			return Discovery{}, errors.New(`the file name pattern "` + fileNamePattern + `" does not contain "` + tablePlaceholder + `"`)
+		}
+
+		quotedParts := make([]string, 0)
+
+		for _, part := range strings.Split(fileNamePattern, tablePlaceholder) {
+			quotedParts = append(quotedParts, regexp.QuoteMeta(part))
+		}
+
+		patternRegexp = regexp.MustCompile(`(?i)^` + strings.Join(quotedParts, `(.+)`) + `$`)
+	}
+
+	discovery := Discovery{make(map[string][]string), make(map[string][]string), make([]string, 0), make([]string, 0), make([]string, 0)}
+
+	err := filepath.Walk(directory, func(filePath string, info os.FileInfo, err error) error {
+
+		//a missing style directory is an error, other directories, which cannot be read, are reported
+		if err != nil {
+			if filePath == directory && info == nil {
+				return err
+			}
+
+			fmt.Println(`WARNING: directory "` + filePath + `" could not be read: ` + err.Error())
+			discovery.UnreadableDirectories = append(discovery.UnreadableDirectories, filePath)
+			return nil
+		}
+
+		//hidden directories like ".git" are skipped
+		if info.IsDir() {
+			if filePath != directory && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		isStyleFile, err := HasStyleContent(filePath)
+
+		if err != nil {
+			fmt.Println(`WARNING: style file "` + filePath + `" could not be read: ` + err.Error())
+			discovery.UnmatchedStyles = append(discovery.UnmatchedStyles, filePath)
+			return nil
+		}
+
+		if !isStyleFile {
+			return nil
+		}
+
+		layerNames, err := styleLayerNames(filePath, patternRegexp)
+
+		if err != nil {
+			fmt.Println(`WARNING: style file "` + filePath + `" could not be read: ` + err.Error())
+			discovery.UnmatchedStyles = append(discovery.UnmatchedStyles, filePath)
+			return nil
+		}
+
+		matched := false
+
+		for _, tableName := range tableNames {
+			if matchesLayers(layerNames, tableName, tablePrefix) {
+				discovery.Tables[tableName] = append(discovery.Tables[tableName], filePath)
+				matched = true
+			}
+		}
+
+		for _, genTableName := range genTableNames {
+			if matchesLayers(layerNames, genTableName, tablePrefix) {
+				discovery.GeneralizedTables[genTableName] = append(discovery.GeneralizedTables[genTableName], filePath)
+				matched = true
+			}
+		}
+
+		if !matched {
+			discovery.UnmatchedStyles = append(discovery.UnmatchedStyles, filePath)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return Discovery{}, err
+	}
+
+	for _, tableName := range tableNames {
+		if len(discovery.Tables[tableName]) == 0 {
+			discovery.UnstyledTables = append(discovery.UnstyledTables, tableName)
+		}
+	}
+
+	for _, genTableName := range genTableNames {
+		if len(discovery.GeneralizedTables[genTableName]) == 0 {
+			discovery.UnstyledTables = append(discovery.UnstyledTables, genTableName)
+		}
+	}
+
+	sort.Strings(discovery.UnstyledTables)
+
+	return discovery, nil
+}
+
+//styleLayerNames returns the table name of the file name pattern or the layer and feature type names of a style file
+func styleLayerNames(filePath string, patternRegexp *regexp.Regexp) ([]string, error) {
+
+	if patternRegexp != nil {
+		if match := patternRegexp.FindStringSubmatch(filepath.Base(filePath)); match != nil {
+			return []string{match[1]}, nil
+		}
+	}
+
+	return New(filePath).LayerNames()
+}
+
+func matchesLayers(layerNames []string, tableName string, tablePrefix string) bool {
+	for _, layerName := range layerNames {
+		if sld.MatchesTable(layerName, tableName, tablePrefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,121 @@
+package styles
+
+import (
+	carto "Imposm_Optimizer/carto_style"
+	css "Imposm_Optimizer/css_style"
+	maplibre "Imposm_Optimizer/maplibre_style"
+	mapnik "Imposm_Optimizer/mapnik_style"
+	qgis "Imposm_Optimizer/qgis_style"
+	"Imposm_Optimizer/sld"
+	ysld "Imposm_Optimizer/ysld_style"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//styleExtensions are the file extensions of all readable style files
+var styleExtensions = []string{".sld", ".xml", ".mml", ".mss", ".json", ".qml", ".qgs", ".qgz", ".css", ".ysld"}
+
+//New returns the parser of a style file, Mapnik stylesheets are read from .xml files, CartoCSS projects
+//from .mml or .mss files, MapLibre styles from .json files, QGIS styles and projects from .qml, .qgs or .qgz files,
+//GeoServer CSS styles from .css files, YSLD styles from .ysld files, all other files are SLD files
+func New(filePath string) sld.StyleReader {
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		maplibreParser := maplibre.New(filePath)
+		return &maplibreParser
+	case ".mml", ".mss":
+		cartoParser := carto.New(filePath)
+		return &cartoParser
+	case ".xml":
+		mapnikParser := mapnik.New(filePath)
+		return &mapnikParser
+	case ".qml", ".qgs", ".qgz":
+		qgisParser := qgis.New(filePath)
+		return &qgisParser
+	case ".css":
+		cssParser := css.New(filePath)
+		return &cssParser
+	case ".ysld":
+		ysldParser := ysld.New(filePath)
+		return &ysldParser
+	default:
+		sldParser := sld.New(filePath)
+		return &sldParser
+	}
+}
+
+//IsStyleFile checks if a file has the extension of a readable style file
+func IsStyleFile(filePath string) bool {
+
+	extension := strings.ToLower(filepath.Ext(filePath))
+
+	for _, styleExtension := range styleExtensions {
+		if extension == styleExtension {
+			return true
+		}
+	}
+
+	return false
+}
+
+//HasStyleContent checks if a file found in a directory is a readable style file. Other programs use .json and .xml files
+//as well, so only MapLibre styles with "version" and "layers" and Mapnik stylesheets with a <Map> root are accepted.
+//An error is returned, if the content of the file cannot be read
+func HasStyleContent(filePath string) (bool, error) {
+
+	if !IsStyleFile(filePath) {
+		return false, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		content, err := ioutil.ReadFile(filePath)
+
+		if err != nil {
+			return false, err
+		}
+
+		style := struct {
+			Version json.RawMessage   `json:"version"`
+			Layers  []json.RawMessage `json:"layers"`
+		}{}
+
+		return json.Unmarshal(content, &style) == nil && style.Version != nil && style.Layers != nil, nil
+
+	case ".xml":
+		file, err := os.Open(filePath)
+
+		if err != nil {
+			return false, err
+		}
+
+		defer file.Close()
+
+		decoder := xml.NewDecoder(file)
+
+		for {
+			token, err := decoder.Token()
+
+			//a file, which is no XML document, is no style file
+			if _, isSyntaxError := err.(*xml.SyntaxError); isSyntaxError || err == io.EOF {
+				return false, nil
+			}
+
+			if err != nil {
+				return false, err
+			}
+
+			if element, ok := token.(xml.StartElement); ok {
+				return element.Name.Local == "Map", nil
+			}
+		}
+	}
+
+	return true, nil
+}
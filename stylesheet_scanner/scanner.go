@@ -0,0 +1,189 @@
+package scanner
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+//Block is a rule of a stylesheet like a CartoCSS ruleset or a GeoServer CSS rule
+//Selectors = text in front of "{", empty for the root block
+//Statements = declarations and directives like "line-color: #fff" or "@water: #aad3df"
+//Children = nested blocks
+type Block struct {
+	Selectors  string
+	Statements []string
+	Children   []Block
+}
+
+//stylesheet is a scanner for the text of a stylesheet
+type stylesheet struct {
+	text     []rune
+	position int
+}
+
+//Parse returns the blocks of a stylesheet. Line comments like "// comment" are only removed, if they are part of the syntax
+func Parse(text string, lineComments bool) ([]Block, error) {
+
+	scanner := stylesheet{[]rune(RemoveComments(text, lineComments)), 0}
+	root, err := scanner.parseBlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if scanner.position < len(scanner.text) {
+		return nil, errors.New(`unexpected "}" in stylesheet`)
+	}
+
+	return root.Children, nil
+}
+
+//RemoveComments removes the block comments and optionally the line comments of a stylesheet, strings and URLs are kept
+func RemoveComments(text string, lineComments bool) string {
+
+	runes := []rune(text)
+	result := make([]rune, 0, len(runes))
+
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '"' || runes[i] == '\'':
+			end := ClosingQuote(runes, i)
+			result = append(result, runes[i:end]...)
+			i = end - 1
+		case runes[i] == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := strings.Index(string(runes[i+2:]), "*/")
+
+			if end == -1 {
+				return string(result)
+			}
+
+			i += len([]rune(string(runes[i+2:])[:end])) + 3
+			result = append(result, ' ')
+		case lineComments && runes[i] == '/' && i+1 < len(runes) && runes[i+1] == '/' && (i == 0 || runes[i-1] != ':'):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = append(result, '\n')
+		default:
+			result = append(result, runes[i])
+		}
+	}
+
+	return string(result)
+}
+
+//ClosingQuote returns the position after the string, which starts at the given position
+func ClosingQuote(runes []rune, start int) int {
+
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] == '\\' {
+			i++
+		} else if runes[i] == runes[start] {
+			return i + 1
+		}
+	}
+
+	return len(runes)
+}
+
+//parseBlock parses the statements and nested blocks until the end of the current block
+func (s *stylesheet) parseBlock() (Block, error) {
+
+	current := Block{"", make([]string, 0), make([]Block, 0)}
+
+	for {
+		statement, end := s.readStatement()
+
+		switch end {
+		case '{':
+			child, err := s.parseBlock()
+
+			if err != nil {
+				return Block{}, err
+			}
+
+			if s.position > len(s.text) {
+				return Block{}, errors.New(`missing "}" after "` + statement + `"`)
+			}
+
+			child.Selectors = statement
+			current.Children = append(current.Children, child)
+
+		default:
+			if statement != "" {
+				current.Statements = append(current.Statements, statement)
+			}
+
+			if end == '}' || end == 0 {
+				return current, nil
+			}
+		}
+	}
+}
+
+//readStatement reads the text until the next "{", ";" or "}" outside of strings and brackets
+func (s *stylesheet) readStatement() (string, rune) {
+
+	start := s.position
+	depth := 0
+
+	for s.position < len(s.text) {
+		r := s.text[s.position]
+
+		switch {
+		case r == '"' || r == '\'':
+			s.position = ClosingQuote(s.text, s.position)
+			continue
+		case r == '[' || r == '(':
+			depth++
+		case r == ']' || r == ')':
+			depth--
+		case depth <= 0 && (r == '{' || r == ';' || r == '}'):
+			s.position++
+			return strings.TrimSpace(string(s.text[start : s.position-1])), r
+		}
+
+		s.position++
+	}
+
+	//the end of the stylesheet is reached, a block without "}" is closed behind the text
+	s.position++
+	return strings.TrimSpace(string(s.text[start:])), 0
+}
+
+//ReadName returns the name like "osm_roads" or "nth-mark", which starts at the given position, and the position after the name
+func ReadName(runes []rune, start int) (string, int) {
+
+	end := start
+	for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '-') {
+		end++
+	}
+
+	return string(runes[start:end]), end
+}
+
+//SplitOutside splits a text at the separator, which is not inside of strings or brackets
+func SplitOutside(text string, separator rune) []string {
+
+	parts := make([]string, 0)
+	runes := []rune(text)
+	start := 0
+	depth := 0
+
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '"' || runes[i] == '\'':
+			i = ClosingQuote(runes, i) - 1
+		case runes[i] == '[' || runes[i] == '(':
+			depth++
+		case runes[i] == ']' || runes[i] == ')':
+			depth--
+		case runes[i] == separator && depth == 0:
+			parts = append(parts, string(runes[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(parts, string(runes[start:]))
+}
@@ -0,0 +1,314 @@
+package ysld
+
+import (
+	"Imposm_Optimizer/sld"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+//zoomZeroScale is the scale denominator of zoom level 0 of the WebMercator grid set of GeoServer
+const zoomZeroScale = 559082264.029
+
+//symbolizerKinds maps the symbolizer names to the symbolizer kinds of the sld package
+var symbolizerKinds = map[string]string{
+	"point":   sld.PointKind,
+	"line":    sld.LineKind,
+	"polygon": sld.PolygonKind,
+	"text":    sld.TextKind,
+	"raster":  sld.RasterKind}
+
+//Parser class
+type Parser struct {
+	filePath          string
+	successfullPasing bool
+	style             Style
+	tableNames        []string
+	tablePrefix       string
+	usedLayers        []string
+}
+
+//New YSLD parser instance
+func New(filePath string) Parser {
+	s := Parser{filePath, false, Style{}, nil, "", make([]string, 0)}
+	return s
+}
+
+func (s *Parser) loadStyleFile() error {
+
+	if s.successfullPasing {
+		return nil
+	}
+
+	if filepath.Ext(s.filePath) != ".ysld" {
+		return errors.New(`"` + s.filePath + `" must be a .ysld file`)
+	}
+
+	styleFile, err := ioutil.ReadFile(s.filePath)
+
+	if err != nil {
+		return err
+	}
+
+	err = yaml.Unmarshal(styleFile, &s.style)
+
+	if err != nil {
+		return errors.New(`"` + s.filePath + `" is no valid YSLD file: ` + err.Error())
+	}
+
+	//the rules and symbolizers at the root belong to a single feature style
+	if len(s.style.Symbolizers) > 0 {
+		s.style.Rules = append(s.style.Rules, Rule{Symbolizers: s.style.Symbolizers})
+	}
+
+	if len(s.style.Rules) > 0 {
+		s.style.FeatureStyles = append(s.style.FeatureStyles, FeatureStyle{Rules: s.style.Rules})
+	}
+
+	s.successfullPasing = true
+
+	return nil
+}
+
+//SetTableNames stores the tables of the requirements. A YSLD file is a single layer, which is always used
+func (s *Parser) SetTableNames(tableNames []string, tablePrefix string) {
+	s.tableNames = tableNames
+	s.tablePrefix = tablePrefix
+}
+
+//LayerNames returns the name of the style
+func (s *Parser) LayerNames() ([]string, error) {
+
+	err := s.loadStyleFile()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.style.Name == "" {
+		return []string{}, nil
+	}
+
+	return []string{s.style.Name}, nil
+}
+
+//UsedLayers returns the name of the style, if it matches the table of the requirements.
+//The list is empty, if the whole file was used without matching name
+func (s *Parser) UsedLayers() []string {
+	return s.usedLayers
+}
+
+//GetFilePath returns the path to the style file
+func (s *Parser) GetFilePath() string {
+	return s.filePath
+}
+
+//ExtractRequirements ( mappingColumns ) will return all required values of the YSLD rules in form of a ParsedSLD structure.
+//The rules are handled like SLD rules, the zoom levels are converted into scale denominators
+func (s *Parser) ExtractRequirements(mappingColumns sld.MappingColumnNames) (sld.ParsedSLD, error) {
+
+	err := s.loadStyleFile()
+
+	if err != nil {
+		return sld.ParsedSLD{}, err
+	}
+
+	s.usedLayers = make([]string, 0)
+
+	for _, tableName := range s.tableNames {
+		if sld.MatchesTable(s.style.Name, tableName, s.tablePrefix) {
+			s.usedLayers = append(s.usedLayers, s.style.Name)
+			break
+		}
+	}
+
+	requirements := sld.NewTableRequirements(mappingColumns)
+	ruleList := make([]sld.Rule, 0)
+
+	for featureStyleIndex, featureStyle := range s.style.FeatureStyles {
+		for _, ysldRule := range featureStyle.Rules {
+			if rule, ok := s.convertRule(ysldRule, featureStyleIndex, &requirements); ok {
+				ruleList = append(ruleList, rule)
+
+				//the features are sorted in all scales of the rules
+				for _, column := range sld.SortByColumns(featureStyle.SortBy) {
+					sld.AddRequiredColumn(&requirements, column, []string{}, ruleScale(rule), sld.SortUsage)
+				}
+			}
+		}
+	}
+
+	scaleDenominator, useAllMappingTypes := sld.CompleteRequirements(ruleList, &requirements)
+
+	return sld.ParsedSLD{
+		FileName:           s.filePath,
+		Requirements:       requirements,
+		Scale:              scaleDenominator,
+		UseAllMappingTypes: useAllMappingTypes,
+		Version:            "YSLD"}, nil
+}
+
+//convertRule converts a YSLD rule into a SLD rule and adds the columns of its filter and symbolizers to the requirements.
+//False is returned, if the rule is never drawn
+func (s *Parser) convertRule(ysldRule Rule, featureStyleIndex int, requirements *sld.TableRequirements) (sld.Rule, bool) {
+
+	rule := sld.Rule{Name: ysldRule.Name, FeatureTypeStyle: featureStyleIndex}
+	minScale, maxScale := 0.0, math.Inf(1)
+
+	if len(ysldRule.Scale) == 2 {
+		minScale = math.Max(minScale, rangeValue(ysldRule.Scale[0], 0))
+		maxScale = math.Min(maxScale, rangeValue(ysldRule.Scale[1], math.Inf(1)))
+	}
+
+	//the zoom levels are separated in the middle between their scale denominators
+	if len(ysldRule.Zoom) == 2 {
+		if s.style.Grid.Name != "" && s.style.Grid.Name != "WebMercator" {
+			fmt.Println(`- zoom levels of the grid set "` + s.style.Grid.Name + `" are ignored`)
+		} else {
+			maxScale = math.Min(maxScale, zoomScale(rangeValue(ysldRule.Zoom[0], math.Inf(-1))-0.5))
+			minScale = math.Max(minScale, zoomScale(rangeValue(ysldRule.Zoom[1], math.Inf(1))+0.5))
+		}
+	}
+
+	if minScale >= maxScale {
+		return sld.Rule{}, false
+	}
+
+	rule.MinScale = int(math.Round(minScale))
+
+	if !math.IsInf(maxScale, 1) {
+		rule.MaxScale = int(math.Round(maxScale))
+	}
+
+	scale := ruleScale(rule)
+
+	if ysldRule.Else {
+		rule.ElseFilter = &sld.Filter{}
+	} else if filterText := embeddedExpression(ysldRule.Filter); filterText != "" {
+		rule.Filter = sld.Filter{XMLContent: []byte(filterText)}
+		filter, err := sld.ParseCQLFilter(filterText)
+
+		if err != nil {
+			fmt.Println("Parsing Error: " + err.Error())
+			filter = sld.UnknownFilter{Name: "Filter"}
+		}
+
+		rule.FilterTree = filter
+
+		for _, column := range sld.CQLPropertyNames(filterText) {
+			sld.AddRequiredColumn(requirements, column, []string{}, scale, sld.FilterUsage)
+		}
+	}
+
+	for _, symbolizers := range ysldRule.Symbolizers {
+		for name, properties := range symbolizers {
+			symbolizer := []sld.Symbolizer{{XMLContent: nil, UnitOfMeasure: symbolizerUnit(properties)}}
+
+			switch symbolizerKinds[name] {
+			case sld.PointKind:
+				rule.PointSymbolizer = symbolizer
+			case sld.LineKind:
+				rule.LineSymbolizer = symbolizer
+			case sld.PolygonKind:
+				rule.PolygonSymbolizer = symbolizer
+			case sld.TextKind:
+				rule.TextSymbolizer = symbolizer
+			case sld.RasterKind:
+				rule.RasterSymbolizer = symbolizer
+			}
+
+			addPropertyColumns(properties, sld.StyleUsage, scale, requirements)
+		}
+	}
+
+	return rule, true
+}
+
+//addPropertyColumns adds the columns of the embedded expressions like "${name}" of the symbolizer properties to the requirements
+func addPropertyColumns(value interface{}, usage string, scale sld.ScaleDenominator, requirements *sld.TableRequirements) {
+
+	switch v := value.(type) {
+	case string:
+		for _, column := range sld.EmbeddedExpressionColumns(v) {
+			sld.AddRequiredColumn(requirements, column, []string{}, scale, usage)
+		}
+
+	case map[interface{}]interface{}:
+		for key, child := range v {
+			childUsage := usage
+
+			if key == "label" {
+				childUsage = sld.LabelUsage
+			}
+
+			addPropertyColumns(child, childUsage, scale, requirements)
+		}
+
+	case []interface{}:
+		for _, child := range v {
+			addPropertyColumns(child, usage, scale, requirements)
+		}
+	}
+}
+
+//symbolizerUnit returns the unit of measure of a symbolizer like "uom: metre"
+func symbolizerUnit(properties interface{}) string {
+
+	if values, ok := properties.(map[interface{}]interface{}); ok {
+		if unit, ok := values["uom"].(string); ok && unit != "" {
+			return unit[strings.LastIndex(unit, "/")+1:]
+		}
+	}
+
+	return sld.PixelUnit
+}
+
+//embeddedExpression returns the CQL text of an embedded expression like "${type = 'primary'}"
+func embeddedExpression(text string) string {
+
+	text = strings.TrimSpace(text)
+
+	if strings.HasPrefix(text, "${") && strings.HasSuffix(text, "}") {
+		return strings.TrimSpace(text[2 : len(text)-1])
+	}
+
+	return text
+}
+
+//rangeValue returns the number of a scale or zoom range, "min", "max" and invalid values are unlimited
+func rangeValue(value interface{}, unlimited float64) float64 {
+
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		if number, err := strconv.ParseFloat(v, 64); err == nil {
+			return number
+		}
+	}
+
+	return unlimited
+}
+
+//ruleScale returns the scale range of a rule, -2 is an infinite maximum
+func ruleScale(rule sld.Rule) sld.ScaleDenominator {
+
+	if rule.MaxScale == 0 {
+		return sld.ScaleDenominator{MinScaleDenominator: rule.MinScale, MaxScaleDenominator: -2}
+	}
+
+	return sld.ScaleDenominator{MinScaleDenominator: rule.MinScale, MaxScaleDenominator: rule.MaxScale}
+}
+
+//zoomScale converts a zoom level of the WebMercator grid set into a scale denominator
+func zoomScale(zoom float64) float64 {
+	return zoomZeroScale / math.Pow(2, zoom)
+}
@@ -0,0 +1,42 @@
+package ysld
+
+//########### YSLD structures ###########//
+
+//Style is the root object of a YSLD file. A style with a single feature style can list its rules at the root,
+//a style with a single rule its symbolizers
+//Grid = the grid set of the zoom levels, GeoServer uses WebMercator by default
+type Style struct {
+	Name          string                   `yaml:"name"`
+	Title         string                   `yaml:"title"`
+	Grid          Grid                     `yaml:"grid"`
+	FeatureStyles []FeatureStyle           `yaml:"feature-styles"`
+	Rules         []Rule                   `yaml:"rules"`
+	Symbolizers   []map[string]interface{} `yaml:"symbolizers"`
+}
+
+//Grid references the grid set of the zoom levels by its name
+type Grid struct {
+	Name string `yaml:"name"`
+}
+
+//FeatureStyle contains the rules, which are drawn in one pass
+//SortBy = the sortBy vendor option like "z_order D"
+type FeatureStyle struct {
+	Name   string `yaml:"name"`
+	SortBy string `yaml:"x-sortBy"`
+	Rules  []Rule `yaml:"rules"`
+}
+
+//Rule describes the structure of a rule in a YSLD style
+//Filter = CQL filter like "${type = 'primary'}"
+//Else = matches all features, which are not matched by another rule of the feature style
+//Scale/Zoom = the minimum and maximum scale denominator or zoom level, "min" and "max" are unlimited
+//Symbolizers = list of single entry maps like {"line": {"stroke-width": "${lanes}"}}
+type Rule struct {
+	Name        string                   `yaml:"name"`
+	Filter      string                   `yaml:"filter"`
+	Else        bool                     `yaml:"else"`
+	Scale       []interface{}            `yaml:"scale"`
+	Zoom        []interface{}            `yaml:"zoom"`
+	Symbolizers []map[string]interface{} `yaml:"symbolizers"`
+}